@@ -1,11 +1,9 @@
 package main
 
 import (
-	"fmt"
 	"github.com/yohamta/donburi"
 	"github.com/yohamta/donburi/ecs"
 	"github.com/yohamta/donburi/filter"
-	"math/rand"
 )
 
 // ActionExecutionSystem は選択されたアクションの実行を担当します。
@@ -42,7 +40,7 @@ func (sys *ActionExecutionSystem) Update(ecs *ecs.ECS) {
 
 		// 選択されたパーツが壊れている場合はアクション失敗
 		if selectedPart == nil || selectedPart.IsBroken {
-			handleActionFailure(ecs, entry, actionComp, statusComp, identityComp.Name+": パーツが壊れていて失敗")
+			handleActionFailure(ecs, entry, actionComp, statusComp, T("battle.part_broken", "name", identityComp.Name))
 			return
 		}
 
@@ -51,8 +49,17 @@ func (sys *ActionExecutionSystem) Update(ecs *ecs.ECS) {
 
 		// アクション実行のメッセージをまず表示し、コールバックで実際の処理を行う
 		initialMessage := sys.createInitialMessage(identityComp, selectedPart, targetEntry)
-		showGameMessage(ecs, initialMessage, func() {
-			sys.executeAction(ecs, entry, targetEntry, targetIsValid, balanceConfig)
+		enqueueGameMessage(ecs, GameMessage{
+			Text: initialMessage,
+			// 先に処理された行動で自身が破壊された場合は、このメッセージごと破棄する
+			Guard: func() bool { return entry.Valid() && !StatusComponentType.Get(entry).IsBroken() },
+			Callback: func() {
+				// 待機中に狙っていた相手が破壊された場合は、ターゲットを決め直す
+				if targetEntry != nil && StatusComponentType.Get(targetEntry).IsBroken() {
+					targetEntry, targetIsValid = sys.determineTarget(ecs, entry, actionComp.TargetedMedarot, selectedPart.Category)
+				}
+				sys.executeAction(ecs, entry, targetEntry, targetIsValid, balanceConfig)
+			},
 		})
 	})
 }
@@ -98,7 +105,7 @@ func (sys *ActionExecutionSystem) findRandomOpponent(ecs *ecs.ECS, attackerEntry
 	})
 
 	if len(candidates) > 0 {
-		return candidates[rand.Intn(len(candidates))], true
+		return candidates[battleRand.Intn(len(candidates))], true
 	}
 	return nil, false
 }
@@ -109,49 +116,70 @@ func (sys *ActionExecutionSystem) executeAction(ecs *ecs.ECS, attackerEntry, tar
 	selectedPart := PartsComponentType.Get(attackerEntry).Parts[actionComp.SelectedPartKey]
 
 	logMsg := ""
+	ev := CombatEvent{
+		Type:           EventActionUsed,
+		AttackerID:     entityDisplayID(attackerEntry),
+		AttackerPartID: selectedPart.ID,
+		Category:       selectedPart.Category,
+	}
 
 	if !targetIsValid {
-		logMsg = fmt.Sprintf("%sは失敗した", selectedPart.PartName)
+		logMsg = T("battle.action_failed", "part", selectedPart.DisplayName())
+		ev.Type = EventActionFailed
 	} else if selectedPart.Category == CategoryShoot || selectedPart.Category == CategoryFight {
 		// 攻撃アクション
 		if targetEntry == nil {
-			logMsg = fmt.Sprintf("%sのターゲットが見つからない", selectedPart.PartName)
+			logMsg = T("battle.no_target", "part", selectedPart.DisplayName())
+			ev.Type = EventActionFailed
 		} else {
-			logMsg = sys.performAttack(attackerEntry, targetEntry, balanceConfig)
+			ev.Type = EventAttack
+			logMsg = sys.performAttack(attackerEntry, targetEntry, balanceConfig, &ev)
 		}
 	} else {
 		// 補助など、その他のアクション
-		logMsg = fmt.Sprintf("%sは%sを使用した", IdentityComponentType.Get(attackerEntry).Name, selectedPart.PartName)
+		logMsg = T("battle.used_part", "name", IdentityComponentType.Get(attackerEntry).Name, "part", selectedPart.DisplayName())
 	}
 
-	actionComp.LastActionLog = logMsg
+	actionComp.RecordAction(logMsg)
 	ActionComponentType.Set(attackerEntry, actionComp)
 
 	// アクション後の状態遷移と最終メッセージ表示
+	ev.Transitions = append(ev.Transitions, StateTransition{
+		EntityID: ev.AttackerID,
+		From:     StatusComponentType.Get(attackerEntry).State,
+		To:       StateActionCooldown,
+	})
+	ev.Message = logMsg
+	publishCombatEvent(ecs, ev)
 	transitionToCooldown(ecs, attackerEntry, actionComp, logMsg)
 }
 
-// performAttack は一連の攻撃処理を行います。
-func (sys *ActionExecutionSystem) performAttack(attackerEntry, targetEntry *donburi.Entry, cfg BalanceConfig) string {
+// performAttack は一連の攻撃処理を行い、その内訳をevに記録します。
+func (sys *ActionExecutionSystem) performAttack(attackerEntry, targetEntry *donburi.Entry, cfg BalanceConfig, ev *CombatEvent) string {
 	// ... コンポーネント取得 ...
 	_, attackerMedal, attackerPart := getAttackerData(attackerEntry)
 	targetID, targetStatus, targetParts := getTargetData(targetEntry)
-
-	// isHit, isCritical := calculateHit(attackerID, attackerMedal, attackerPart, targetID, targetStatus, targetParts.Parts[PartSlotLegs], cfg)
-	isHit, isCritical := calculateHit(attackerMedal, attackerPart, targetStatus, targetParts.Parts[PartSlotLegs], cfg)
-	if !isHit {
-		return fmt.Sprintf("%sへの攻撃は回避された！", targetID.Name)
+	ev.TargetID = targetID.ID
+
+	hit := calculateHit(attackerMedal, attackerPart, targetStatus, targetParts.Parts[PartSlotLegs], cfg)
+	ev.HitChance, ev.HitRoll = hit.HitChance, hit.HitRoll
+	ev.CritChance, ev.CritRoll = hit.CritChance, hit.CritRoll
+	ev.Hit, ev.Critical = hit.IsHit, hit.IsCritical
+	if !hit.IsHit {
+		return T("battle.evaded", "target", targetID.Name)
 	}
 
 	partToDamage := selectRandomPartToDamage(targetParts)
 	if partToDamage == nil {
-		return fmt.Sprintf("%sには攻撃できる部位がない！", targetID.Name)
+		return T("battle.no_part_to_attack", "target", targetID.Name)
 	}
 
-	damage := calculateDamage(attackerEntry, attackerMedal, attackerPart, partToDamage, targetParts.Parts[PartSlotLegs], isCritical, cfg, targetStatus.IsDefenseDisabled)
+	dmg := calculateDamage(attackerEntry, attackerMedal, attackerPart, partToDamage, targetParts.Parts[PartSlotLegs], hit.IsCritical, cfg, targetStatus.IsDefenseDisabled)
+	damage := dmg.Damage
 
 	// ... ダメージ適用とログ生成 ...
 	origArmor := partToDamage.Armor
+	targetStateBefore := targetStatus.State
 	partToDamage.Armor -= damage
 	if partToDamage.Armor <= 0 {
 		partToDamage.Armor = 0
@@ -164,12 +192,21 @@ func (sys *ActionExecutionSystem) performAttack(attackerEntry, targetEntry *donb
 	}
 	PartsComponentType.Set(targetEntry, targetParts)
 
-	logMsg := fmt.Sprintf("%sの%sに%dダメージ！ (%d -> %d)", targetID.Name, partToDamage.PartName, damage, origArmor, partToDamage.Armor)
-	if isCritical {
-		logMsg = "クリティカル！ " + logMsg
+	ev.TargetPartID = partToDamage.ID
+	ev.Defense = dmg.Defense
+	ev.Damage = damage
+	ev.ArmorBefore, ev.ArmorAfter = origArmor, partToDamage.Armor
+	ev.PartBroken = partToDamage.IsBroken && origArmor > 0
+	if targetStateAfter := StatusComponentType.Get(targetEntry).State; targetStateAfter != targetStateBefore {
+		ev.Transitions = append(ev.Transitions, StateTransition{EntityID: targetID.ID, From: targetStateBefore, To: targetStateAfter})
+	}
+
+	logMsg := T("battle.damage", "target", targetID.Name, "part", partToDamage.DisplayName(), "damage", damage, "before", origArmor, "after", partToDamage.Armor)
+	if hit.IsCritical {
+		logMsg = T("battle.critical", "message", logMsg)
 	}
 	if partToDamage.IsBroken && origArmor > 0 {
-		logMsg += " [破壊！]"
+		logMsg = T("battle.destroyed", "message", logMsg)
 	}
 
 	return logMsg
@@ -178,29 +215,34 @@ func (sys *ActionExecutionSystem) performAttack(attackerEntry, targetEntry *donb
 // --- Helper functions ---
 
 func (sys *ActionExecutionSystem) createInitialMessage(attackerID *IdentityComponent, part *Part, targetEntry *donburi.Entry) string {
-	targetInfo := ""
 	if (part.Category == CategoryShoot || part.Category == CategoryFight) && targetEntry != nil {
-		targetInfo = fmt.Sprintf(" -> %s", IdentityComponentType.Get(targetEntry).Name)
+		return T("battle.announce_target", "name", attackerID.Name, "part", part.DisplayName(), "target", IdentityComponentType.Get(targetEntry).Name)
 	}
-	return fmt.Sprintf("%s: %s%s！", attackerID.Name, part.PartName, targetInfo)
+	return T("battle.announce", "name", attackerID.Name, "part", part.DisplayName())
 }
 
 func handleActionFailure(ecs *ecs.ECS, entry *donburi.Entry, action *ActionComponent, status *StatusComponent, logMsg string) {
+	ev := CombatEvent{
+		Type:        EventActionFailed,
+		AttackerID:  entityDisplayID(entry),
+		Transitions: []StateTransition{{EntityID: entityDisplayID(entry), From: status.State, To: StateReadyToSelectAction}},
+		Message:     logMsg,
+	}
+	if part, ok := PartsComponentType.Get(entry).Parts[action.SelectedPartKey]; ok && part != nil {
+		ev.AttackerPartID = part.ID
+	}
+	publishCombatEvent(ecs, ev)
+
 	status.State = StateReadyToSelectAction
 	status.Gauge = 100
 	entry.RemoveComponent(ReadyToExecuteActionTag)
 	StatusComponentType.Set(entry, status)
 
-	action.LastActionLog = logMsg
+	action.RecordAction(logMsg)
 	ActionComponentType.Set(entry, action)
 
-	showGameMessage(ecs, logMsg, func() {
-		// メッセージを閉じたら即座に次の行動選択に移る
-		gsEntry, _ := GameStateComponentType.First(ecs.World)
-		gs := GameStateComponentType.Get(gsEntry)
-		gs.CurrentState = StatePlaying
-		GameStateComponentType.Set(gsEntry, gs)
-	})
+	// キューが空になれば MessageSystem が Playing に戻すため、コールバックは不要
+	showGameMessage(ecs, logMsg, nil)
 }
 
 func transitionToCooldown(ecs *ecs.ECS, entry *donburi.Entry, action *ActionComponent, logMsg string) {
@@ -211,14 +253,7 @@ func transitionToCooldown(ecs *ecs.ECS, entry *donburi.Entry, action *ActionComp
 	entry.AddComponent(ActionCooldownTag)
 	StatusComponentType.Set(entry, status)
 
-	showGameMessage(ecs, logMsg, func() {
-		gsEntry, _ := GameStateComponentType.First(ecs.World)
-		gs := GameStateComponentType.Get(gsEntry)
-		if gs.CurrentState == GameStateMessage { // 他の要因で状態が変わっていなければ
-			gs.CurrentState = StatePlaying
-			GameStateComponentType.Set(gsEntry, gs)
-		}
-	})
+	showGameMessage(ecs, logMsg, nil)
 }
 
 func handleHeadDestruction(targetEntry *donburi.Entry) {
@@ -2,31 +2,63 @@ package main
 
 import (
 	"math/rand"
+	"time"
 
 	"github.com/yohamta/donburi"
 	"github.com/yohamta/donburi/ecs"
 )
 
-// showGameMessage はメッセージ表示状態に移行し、コールバックを設定します。
+// battleRand は戦闘の結果（編成、命中、被弾部位、AIの行動など）を決める乱数です。
+// ネット対戦では両方の端末で同じシードを使い、同じ戦闘になるようにします。
+// 演出やUIの初期値など、戦闘の結果に関わらない乱数には使いません。
+var battleRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// SeedBattle は戦闘の乱数のシードを設定します。
+func SeedBattle(seed int64) {
+	battleRand.Seed(seed)
+}
+
+// showGameMessage はメッセージをキューに追加し、メッセージ表示状態に移行します。
 // action_execution_system など、複数のシステムから利用されるユーティリティです。
 func showGameMessage(ecs *ecs.ECS, msg string, callback func()) {
+	enqueueGameMessage(ecs, GameMessage{Text: msg, Callback: callback})
+}
+
+// enqueueGameMessage は自動送りなどの設定を含むメッセージをキューの末尾に追加します。
+// 他のメッセージのコールバック内で追加されたメッセージは、残りのキューより先に表示されます。
+func enqueueGameMessage(ecs *ecs.ECS, msg GameMessage) {
 	entry, ok := GameStateComponentType.First(ecs.World)
 	if !ok {
 		// ゲーム状態が取得できない場合でも、コールバックは実行を試みる
-		if callback != nil {
-			callback()
+		if msg.Callback != nil {
+			msg.Callback()
 		}
 		return
 	}
 	gs := GameStateComponentType.Get(entry)
-	gs.Message = msg
-	gs.PostMessageCallback = callback
-	gs.CurrentState = GameStateMessage
+	if len(gs.MessageQueue) == 0 {
+		gs.MessageTicks = 0
+	}
+	gs.MessageQueue = append(gs.MessageQueue, msg)
+	if gs.CurrentState != GameStateOver {
+		gs.CurrentState = GameStateMessage
+	}
 	GameStateComponentType.Set(entry, gs)
 }
 
-// calculateHit は命中判定とクリティカル判定を行います。
-func calculateHit(attackerMedal *MedalComponent, attackerPart *Part, targetStatus *StatusComponent, targetLegs *Part, cfg BalanceConfig) (bool, bool) {
+// HitResult は命中判定の結果と、その判定に使われた数値を保持します。
+type HitResult struct {
+	HitChance  int // 最終的な命中率（100を超えることがある）
+	HitRoll    int // 命中判定で振られた値 (0-99)
+	CritChance int // クリティカル率（命中率が100を超えた分）
+	CritRoll   int // クリティカル判定で振られた値 (0-99)。判定していない場合は-1
+	IsHit      bool
+	IsCritical bool
+}
+
+// computeHitChance は命中率を計算します。乱数は使わないため、行動のプレビューにも使えます。
+// 戻り値は100を超えることがあり、超えた分がクリティカル率になります。
+func computeHitChance(attackerMedal *MedalComponent, attackerPart *Part, targetStatus *StatusComponent, targetLegs *Part, cfg BalanceConfig) int {
 	skillValue := 0
 	if attackerPart.Category == CategoryShoot {
 		skillValue = attackerMedal.Medal.SkillShoot
@@ -48,7 +80,7 @@ func calculateHit(attackerMedal *MedalComponent, attackerPart *Part, targetStatu
 
 	targetMobility := 0
 	if targetLegs != nil && !targetLegs.IsBroken {
-		targetMobility = targetLegs.Mobility
+		targetMobility = targetLegs.Mobility.Int()
 	}
 	// 回避不可状態なら機動力を0にする
 	if targetStatus.IsEvasionDisabled {
@@ -59,17 +91,44 @@ func calculateHit(attackerMedal *MedalComponent, attackerPart *Part, targetStatu
 	if hitChance < 0 {
 		hitChance = 0
 	}
+	return hitChance
+}
+
+// hitProbabilities は命中率から、命中する確率とクリティカルになる確率 (0.0-1.0) を求めます。
+// calculateHitの判定手順（命中判定の後、命中率が100を超えた分でクリティカル判定）と一致します。
+func hitProbabilities(hitChance int) (hitProb, critProb float64) {
+	hitProb = clampProbability(float64(hitChance) / 100)
+	critProb = hitProb * clampProbability(float64(hitChance-100)/100)
+	return hitProb, critProb
+}
+
+func clampProbability(p float64) float64 {
+	if p < 0 {
+		return 0
+	}
+	if p > 1 {
+		return 1
+	}
+	return p
+}
+
+// calculateHit は命中判定とクリティカル判定を行います。
+func calculateHit(attackerMedal *MedalComponent, attackerPart *Part, targetStatus *StatusComponent, targetLegs *Part, cfg BalanceConfig) HitResult {
+	hitChance := computeHitChance(attackerMedal, attackerPart, targetStatus, targetLegs, cfg)
 
-	isHit := rand.Intn(100) < hitChance
-	isCritical := false
+	result := HitResult{HitChance: hitChance, CritRoll: -1}
+	result.HitRoll = battleRand.Intn(100)
+	result.IsHit = result.HitRoll < hitChance
 	// 命中率が100を超えた分がクリティカル率になる
-	if isHit && hitChance > 100 {
-		if rand.Intn(100) < (hitChance - 100) {
-			isCritical = true
-		}
+	if hitChance > 100 {
+		result.CritChance = hitChance - 100
+	}
+	if result.IsHit && result.CritChance > 0 {
+		result.CritRoll = battleRand.Intn(100)
+		result.IsCritical = result.CritRoll < result.CritChance
 	}
 
-	return isHit, isCritical
+	return result
 }
 
 // selectRandomPartToDamage は攻撃対象のパーツをランダムに1つ選択します。
@@ -84,16 +143,23 @@ func selectRandomPartToDamage(targetParts *PartsComponent) *Part {
 	if len(vulnerable) == 0 {
 		return nil
 	}
-	return vulnerable[rand.Intn(len(vulnerable))]
+	return vulnerable[battleRand.Intn(len(vulnerable))]
 }
 
-// calculateDamage は最終的なダメージ量を計算します。
+// DamageResult はダメージ計算の結果と内訳を保持します。
+type DamageResult struct {
+	Power   float64 // 補正後の威力
+	Defense float64 // 実際に適用された防御力
+	Damage  int     // 最終ダメージ
+}
+
+// calculateDamage は最終的なダメージ量を計算します。乱数は使いません。
 func calculateDamage(attackerEntry *donburi.Entry, attackerMedal *MedalComponent, attackingPart *Part,
 	targetPart *Part, targetLegs *Part,
-	isCritical bool, cfg BalanceConfig, isTargetDefenseDisabled bool) int {
+	isCritical bool, cfg BalanceConfig, isTargetDefenseDisabled bool) DamageResult {
 
 	// 威力計算
-	basePower := float64(attackingPart.Power)
+	basePower := float64(attackingPart.Power.Int())
 	if attackingPart.Category == CategoryFight {
 		basePower += float64(attackerMedal.Medal.SkillFight * cfg.Damage.MedalSkillFactor)
 	} else if attackingPart.Category == CategoryShoot {
@@ -104,7 +170,7 @@ func calculateDamage(attackerEntry *donburi.Entry, attackerMedal *MedalComponent
 		if attackerEntry.HasComponent(PartsComponentType) { // ←存在チェック
 			attackerPartsComp := PartsComponentType.Get(attackerEntry) // ←取得
 			if attackerLegs, legsOk := attackerPartsComp.Parts[PartSlotLegs]; legsOk && !attackerLegs.IsBroken {
-				basePower += float64(attackerLegs.Propulsion)
+				basePower += float64(attackerLegs.Propulsion.Int())
 			}
 		}
 	}
@@ -129,5 +195,64 @@ func calculateDamage(attackerEntry *donburi.Entry, attackerMedal *MedalComponent
 		rawDamage *= cfg.Damage.CriticalMultiplier
 	}
 
-	return int(rawDamage)
+	return DamageResult{Power: basePower, Defense: defenseValue, Damage: int(rawDamage)}
+}
+
+// ActionPreview は、行動を確定する前に表示する命中率とダメージの見込みです。
+type ActionPreview struct {
+	HitChance       int     // 命中率（100を超えた分がクリティカル率）
+	HitProbability  float64 // 命中する確率 (0.0-1.0)
+	CritProbability float64 // クリティカルになる確率 (0.0-1.0)
+	MinDamage       int     // 通常命中時の最小ダメージ（攻撃されうる部位の中で）
+	MaxDamage       int     // 通常命中時の最大ダメージ
+	MaxCritDamage   int     // クリティカル時の最大ダメージ
+	ExpectedDamage  float64 // 命中・クリティカル・部位の選ばれ方を考慮した期待ダメージ
+}
+
+// previewAction は攻撃パーツとターゲットから、calculateHit/calculateDamageと同じ式で見込みを計算します。
+// 被弾部位はselectRandomPartToDamageと同様に、壊れていない部位から等確率で選ばれるものとします。
+// 攻撃できる部位がない場合はfalseを返します。
+func previewAction(attackerEntry *donburi.Entry, attackerPart *Part, targetEntry *donburi.Entry, cfg BalanceConfig) (ActionPreview, bool) {
+	return previewActionWithStatus(attackerEntry, attackerPart, targetEntry, StatusComponentType.Get(targetEntry), cfg)
+}
+
+// previewActionWithStatus はターゲットの状態を指定してpreviewActionと同じ見込みを計算します。
+// 画面に出せない状態（ホットシート対戦で隠している相手の特性など）を除いて計算するために使います。
+func previewActionWithStatus(attackerEntry *donburi.Entry, attackerPart *Part, targetEntry *donburi.Entry, targetStatus *StatusComponent, cfg BalanceConfig) (ActionPreview, bool) {
+	attackerMedal := CMedal.Get(attackerEntry)
+	targetParts := PartsComponentType.Get(targetEntry)
+	targetLegs := targetParts.Parts[PartSlotLegs]
+
+	preview := ActionPreview{HitChance: computeHitChance(attackerMedal, attackerPart, targetStatus, targetLegs, cfg)}
+	preview.HitProbability, preview.CritProbability = hitProbabilities(preview.HitChance)
+
+	vulnerable := 0
+	var normalSum, critSum float64
+	for _, slot := range []PartSlotKey{PartSlotHead, PartSlotRightArm, PartSlotLeftArm, PartSlotLegs} {
+		part, ok := targetParts.Parts[slot]
+		if !ok || part.IsBroken {
+			continue
+		}
+		normal := calculateDamage(attackerEntry, attackerMedal, attackerPart, part, targetLegs, false, cfg, targetStatus.IsDefenseDisabled).Damage
+		crit := calculateDamage(attackerEntry, attackerMedal, attackerPart, part, targetLegs, true, cfg, targetStatus.IsDefenseDisabled).Damage
+		if vulnerable == 0 || normal < preview.MinDamage {
+			preview.MinDamage = normal
+		}
+		if normal > preview.MaxDamage {
+			preview.MaxDamage = normal
+		}
+		if crit > preview.MaxCritDamage {
+			preview.MaxCritDamage = crit
+		}
+		normalSum += float64(normal)
+		critSum += float64(crit)
+		vulnerable++
+	}
+	if vulnerable == 0 {
+		return preview, false
+	}
+
+	normalProb := preview.HitProbability - preview.CritProbability
+	preview.ExpectedDamage = (normalProb*normalSum + preview.CritProbability*critSum) / float64(vulnerable)
+	return preview, true
 }
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/yohamta/donburi"
+)
+
+// testBalance は計算を追いやすい値にしたゲームバランスです。
+func testBalance() BalanceConfig {
+	cfg := LoadConfig().Balance
+	cfg.Hit.BaseChance = 50
+	cfg.Hit.TraitAimBonus = 20
+	cfg.Hit.TraitStrikeBonus = 10
+	cfg.Hit.TraitBerserkDebuff = -10
+	cfg.Damage.CriticalMultiplier = 1.5
+	cfg.Damage.MedalSkillFactor = 2
+	return cfg
+}
+
+// newTestMedarot はメダルとパーツだけを持つ機体を作ります。
+func newTestMedarot(world donburi.World, medal *Medal, parts map[PartSlotKey]*Part) *donburi.Entry {
+	entry := world.Entry(world.Create(CMedal, PartsComponentType, StatusComponentType))
+	CMedal.SetValue(entry, MedalComponent{Medal: medal})
+	PartsComponentType.SetValue(entry, PartsComponent{Parts: parts})
+	StatusComponentType.SetValue(entry, StatusComponent{State: StateReadyToSelectAction})
+	return entry
+}
+
+func TestComputeHitChance(t *testing.T) {
+	medal := &MedalComponent{Medal: &Medal{SkillShoot: 10, SkillFight: 5}}
+	legs := &Part{Mobility: SomeInt(40)}
+	brokenLegs := &Part{Mobility: SomeInt(40), IsBroken: true}
+	tests := []struct {
+		name     string
+		part     Part
+		status   StatusComponent
+		legs     *Part
+		expected int
+	}{
+		{"shoot uses the shooting skill", Part{Category: CategoryShoot, Trait: TraitNormal, Accuracy: 30}, StatusComponent{}, legs, 50 + 30 + 10 - 40},
+		{"fight uses the fighting skill", Part{Category: CategoryFight, Trait: TraitNormal, Accuracy: 30}, StatusComponent{}, legs, 50 + 30 + 5 - 40},
+		{"aim bonus", Part{Category: CategoryShoot, Trait: TraitAim, Accuracy: 30}, StatusComponent{}, legs, 50 + 30 + 10 + 20 - 40},
+		{"strike bonus", Part{Category: CategoryFight, Trait: TraitStrike, Accuracy: 30}, StatusComponent{}, legs, 50 + 30 + 5 + 10 - 40},
+		{"evasion disabled", Part{Category: CategoryShoot, Trait: TraitNormal, Accuracy: 30}, StatusComponent{IsEvasionDisabled: true}, legs, 50 + 30 + 10},
+		{"broken legs", Part{Category: CategoryShoot, Trait: TraitNormal, Accuracy: 30}, StatusComponent{}, brokenLegs, 50 + 30 + 10},
+		{"no legs", Part{Category: CategoryShoot, Trait: TraitNormal, Accuracy: 30}, StatusComponent{}, nil, 50 + 30 + 10},
+		{"over 100", Part{Category: CategoryShoot, Trait: TraitAim, Accuracy: 90}, StatusComponent{IsEvasionDisabled: true}, legs, 50 + 90 + 10 + 20},
+		{"never negative", Part{Category: CategoryFight, Trait: TraitBerserk}, StatusComponent{}, &Part{Mobility: SomeInt(80)}, 0},
+	}
+	for _, tt := range tests {
+		if got := computeHitChance(medal, &tt.part, &tt.status, tt.legs, testBalance()); got != tt.expected {
+			t.Errorf("%s: computeHitChance = %d, want %d", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestHitProbabilities(t *testing.T) {
+	tests := []struct {
+		hitChance         int
+		wantHit, wantCrit float64
+	}{
+		{-5, 0, 0},
+		{0, 0, 0},
+		{50, 0.5, 0},
+		{100, 1, 0},
+		{130, 1, 0.3},
+		{250, 1, 1},
+	}
+	for _, tt := range tests {
+		hit, crit := hitProbabilities(tt.hitChance)
+		if math.Abs(hit-tt.wantHit) > 1e-9 || math.Abs(crit-tt.wantCrit) > 1e-9 {
+			t.Errorf("hitProbabilities(%d) = %g, %g; want %g, %g", tt.hitChance, hit, crit, tt.wantHit, tt.wantCrit)
+		}
+	}
+}
+
+func TestHitProbabilitiesMatchCalculateHit(t *testing.T) {
+	saved := battleRand
+	t.Cleanup(func() { battleRand = saved })
+	battleRand = rand.New(rand.NewSource(1))
+
+	medal := &MedalComponent{Medal: &Medal{}}
+	for _, accuracy := range []int{-20, 20, 70} {
+		part := &Part{Category: CategoryShoot, Trait: TraitNormal, Accuracy: accuracy}
+		const trials = 20000
+		hits, crits := 0, 0
+		for range trials {
+			result := calculateHit(medal, part, &StatusComponent{}, nil, testBalance())
+			if result.IsHit {
+				hits++
+			}
+			if result.IsCritical {
+				crits++
+			}
+		}
+		wantHit, wantCrit := hitProbabilities(50 + accuracy)
+		if got := float64(hits) / trials; math.Abs(got-wantHit) > 0.02 {
+			t.Errorf("accuracy %d: hit rate %g, hitProbabilities says %g", accuracy, got, wantHit)
+		}
+		if got := float64(crits) / trials; math.Abs(got-wantCrit) > 0.02 {
+			t.Errorf("accuracy %d: critical rate %g, hitProbabilities says %g", accuracy, got, wantCrit)
+		}
+	}
+}
+
+func TestPreviewAction(t *testing.T) {
+	world := donburi.NewWorld()
+	gun := &Part{Category: CategoryShoot, Trait: TraitNormal, Power: SomeInt(40), Accuracy: 90}
+	attacker := newTestMedarot(world, &Medal{SkillShoot: 10}, map[PartSlotKey]*Part{PartSlotRightArm: gun})
+	target := newTestMedarot(world, &Medal{}, map[PartSlotKey]*Part{
+		PartSlotHead:     {Defense: 5},
+		PartSlotRightArm: {Defense: 10},
+		PartSlotLeftArm:  {Defense: 0, IsBroken: true},
+		PartSlotLegs:     {Defense: 5, Mobility: SomeInt(40)},
+	})
+
+	// 威力 40+10*2=60。防御は部位+脚部で、頭10・右腕15・脚部10。命中率 50+90+10-40=110
+	preview, ok := previewAction(attacker, gun, target, testBalance())
+	if !ok {
+		t.Fatal("previewAction found no part to hit")
+	}
+	want := ActionPreview{
+		HitChance:       110,
+		HitProbability:  1,
+		CritProbability: 0.1,
+		MinDamage:       45,
+		MaxDamage:       50,
+		MaxCritDamage:   75,
+		ExpectedDamage:  (0.9*(50+45+50) + 0.1*(75+67+75)) / 3,
+	}
+	if math.Abs(preview.ExpectedDamage-want.ExpectedDamage) > 1e-9 || math.Abs(preview.CritProbability-want.CritProbability) > 1e-9 {
+		t.Errorf("previewAction = %+v, want %+v", preview, want)
+	}
+	preview.ExpectedDamage, preview.CritProbability = want.ExpectedDamage, want.CritProbability
+	if preview != want {
+		t.Errorf("previewAction = %+v, want %+v", preview, want)
+	}
+
+	// 防御不可なら、どの部位にも威力がそのまま通る
+	status := *StatusComponentType.Get(target)
+	status.IsDefenseDisabled = true
+	preview, _ = previewActionWithStatus(attacker, gun, target, &status, testBalance())
+	if preview.MinDamage != 60 || preview.MaxDamage != 60 || preview.MaxCritDamage != 90 {
+		t.Errorf("defense disabled: damage %d-%d (critical %d), want 60-60 (90)", preview.MinDamage, preview.MaxDamage, preview.MaxCritDamage)
+	}
+
+	for _, part := range PartsComponentType.Get(target).Parts {
+		part.IsBroken = true
+	}
+	if _, ok := previewAction(attacker, gun, target, testBalance()); ok {
+		t.Error("previewAction reported damage against a target with every part broken")
+	}
+}
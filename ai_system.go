@@ -5,7 +5,6 @@ import (
 	"github.com/yohamta/donburi"
 	"github.com/yohamta/donburi/ecs"
 	"github.com/yohamta/donburi/filter"
-	"math/rand"
 )
 
 // AISystem はAIメダロットの行動を決定します。
@@ -20,6 +19,7 @@ func NewAISystem() *AISystem {
 			filter.Contains(AIControlledComponentType), filter.Contains(StatusComponentType),
 			filter.Contains(PartsComponentType), filter.Contains(ActionComponentType),
 			filter.Contains(IdentityComponentType), filter.Not(filter.Contains(BrokenTag)),
+			filter.Not(filter.Contains(BotControlledComponentType)), // 外部ボットの機体はBotSystemが担当する
 		)),
 		targetableQuery: donburi.NewQuery(filter.And(
 			filter.Contains(IdentityComponentType), filter.Contains(StatusComponentType),
@@ -35,66 +35,68 @@ func (sys *AISystem) Update(ecs *ecs.ECS) {
 	}
 
 	sys.aiQuery.Each(ecs.World, func(entry *donburi.Entry) {
-		status := StatusComponentType.Get(entry)
-		if status.State != StateReadyToSelectAction {
+		if StatusComponentType.Get(entry).State != StateReadyToSelectAction {
 			return
 		}
-
-		partsComp := PartsComponentType.Get(entry)
-		actionComp := ActionComponentType.Get(entry)
-		aiIdentity := IdentityComponentType.Get(entry)
-
-		// 1. 使用可能なパーツを選ぶ
-		availablePartSlots := []PartSlotKey{}
-		slots := []PartSlotKey{PartSlotHead, PartSlotRightArm, PartSlotLeftArm}
-		rand.Shuffle(len(slots), func(i, j int) { slots[i], slots[j] = slots[j], slots[i] })
-		for _, slotKey := range slots {
-			part, exists := partsComp.Parts[slotKey]
-			if exists && !part.IsBroken && part.Charge > 0 {
-				availablePartSlots = append(availablePartSlots, slotKey)
-			}
-		}
-		if len(availablePartSlots) == 0 {
-			return
+		if slotKey, target, ok := chooseAIAction(ecs.World, entry, sys.targetableQuery); ok {
+			applyActionChoice(entry, slotKey, target)
 		}
-		selectedSlotKey := availablePartSlots[0]
-		selectedPart := partsComp.Parts[selectedSlotKey]
+	})
+}
 
-		// 2. ターゲットを選ぶ
-		var opponentTeam TeamID = Team1
-		if aiIdentity.Team == Team1 {
-			opponentTeam = Team2
+// chooseAIAction は組み込みのAIとして、機体が使うパーツとターゲットを選びます。
+// チームのプロファイル（設定の ai.team1_profile・ai.team2_profile）が "random" ならランダムに、それ以外は効用AI（ai_utility.go）で選びます。
+// 外部ボット（bot_system.go）が応答しなかった場合の代わりにも使います。行動できない場合はfalseを返します。
+func chooseAIAction(w donburi.World, entry *donburi.Entry, targetableQuery *donburi.Query) (PartSlotKey, donburi.Entity, bool) {
+	if configEntry, ok := ConfigComponentType.First(w); ok {
+		config := ConfigComponentType.Get(configEntry).GameConfig
+		if profile, ok := config.AI.Profile(IdentityComponentType.Get(entry).Team); ok {
+			return chooseUtilityAction(w, entry, targetableQuery, profile, config.Balance)
 		}
-		candidates := []donburi.Entity{}
-		sys.targetableQuery.Each(ecs.World, func(targetEntry *donburi.Entry) {
-			// ★★★ 修正箇所: IsBroken() メソッドを使用 ★★★
-			if IdentityComponentType.Get(targetEntry).Team == opponentTeam && !StatusComponentType.Get(targetEntry).IsBroken() {
-				candidates = append(candidates, targetEntry.Entity())
-			}
-		})
+	}
+	return chooseRandomAction(w, entry, targetableQuery)
+}
 
-		if selectedPart.Category == CategoryShoot || selectedPart.Category == CategoryFight {
-			if len(candidates) == 0 {
-				return
-			} // 攻撃対象がいなければ行動しない
-			actionComp.TargetedMedarot = candidates[rand.Intn(len(candidates))]
-		}
+// chooseRandomAction は使えるパーツと生きている敵から、ランダムに行動を選びます。
+func chooseRandomAction(w donburi.World, entry *donburi.Entry, targetableQuery *donburi.Query) (PartSlotKey, donburi.Entity, bool) {
+	partsComp := PartsComponentType.Get(entry)
+	aiIdentity := IdentityComponentType.Get(entry)
 
-		// 3. アクションを確定
-		actionComp.SelectedPartKey = selectedSlotKey
-		status.State = StateActionCharging
-		status.Gauge = 0
-		switch selectedPart.Trait {
-		case TraitAim:
-			status.IsEvasionDisabled = true
-		case TraitStrike:
-			status.IsDefenseDisabled = true
-		case TraitBerserk:
-			status.IsEvasionDisabled, status.IsDefenseDisabled = true, true
+	// 1. 使用可能なパーツを選ぶ
+	availablePartSlots := []PartSlotKey{}
+	slots := []PartSlotKey{PartSlotHead, PartSlotRightArm, PartSlotLeftArm}
+	battleRand.Shuffle(len(slots), func(i, j int) { slots[i], slots[j] = slots[j], slots[i] })
+	for _, slotKey := range slots {
+		part, exists := partsComp.Parts[slotKey]
+		if exists && !part.IsBroken && part.Charge.Int() > 0 {
+			availablePartSlots = append(availablePartSlots, slotKey)
 		}
+	}
+	if len(availablePartSlots) == 0 {
+		return "", 0, false
+	}
+	selectedSlotKey := availablePartSlots[0]
+	selectedPart := partsComp.Parts[selectedSlotKey]
 
-		entry.AddComponent(ActionChargingTag)
-		StatusComponentType.Set(entry, status)
-		ActionComponentType.Set(entry, actionComp)
+	// 2. ターゲットを選ぶ
+	var opponentTeam TeamID = Team1
+	if aiIdentity.Team == Team1 {
+		opponentTeam = Team2
+	}
+	candidates := []donburi.Entity{}
+	targetableQuery.Each(w, func(targetEntry *donburi.Entry) {
+		// ★★★ 修正箇所: IsBroken() メソッドを使用 ★★★
+		if IdentityComponentType.Get(targetEntry).Team == opponentTeam && !StatusComponentType.Get(targetEntry).IsBroken() {
+			candidates = append(candidates, targetEntry.Entity())
+		}
 	})
+
+	target := donburi.Entity(0)
+	if selectedPart.Category == CategoryShoot || selectedPart.Category == CategoryFight {
+		if len(candidates) == 0 {
+			return "", 0, false
+		} // 攻撃対象がいなければ行動しない
+		target = candidates[battleRand.Intn(len(candidates))]
+	}
+	return selectedSlotKey, target, true
 }
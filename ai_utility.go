@@ -0,0 +1,132 @@
+package main
+
+import (
+	"github.com/yohamta/donburi"
+)
+
+// 効用AI: 使えるパーツと攻撃できる敵の組み合わせすべてに点数を付け、最も点数の高い行動を選びます。
+// 命中率とダメージはcalculateHit・calculateDamageと同じ式で見積もり、点数の重みはAIのプロファイル（AIProfile）で変えられます。
+//
+//	点数 = (ダメージの期待値 + 部位を破壊できる確率 + 頭部を破壊できる確率) × リーダーへの割増し
+//	     − チャージ時間 − 特性で回避・防御ができなくなる危険
+
+// chooseUtilityAction は点数が最も高いパーツとターゲットを返します。行動できない場合はfalseを返します。
+func chooseUtilityAction(w donburi.World, entry *donburi.Entry, targetableQuery *donburi.Query, profile AIProfile, cfg BalanceConfig) (PartSlotKey, donburi.Entity, bool) {
+	parts := PartsComponentType.Get(entry)
+	identity := IdentityComponentType.Get(entry)
+
+	var targets []*donburi.Entry
+	targetableQuery.Each(w, func(targetEntry *donburi.Entry) {
+		if IdentityComponentType.Get(targetEntry).Team != identity.Team && !StatusComponentType.Get(targetEntry).IsBroken() {
+			targets = append(targets, targetEntry)
+		}
+	})
+
+	var bestSlot PartSlotKey
+	bestTarget := donburi.Entity(0)
+	bestScore, found := 0.0, false
+	consider := func(slot PartSlotKey, target donburi.Entity, score float64) {
+		if profile.Randomness > 0 {
+			score += battleRand.Float64() * profile.Randomness
+		}
+		if !found || score > bestScore {
+			bestSlot, bestTarget, bestScore, found = slot, target, score, true
+		}
+	}
+
+	for _, slot := range availableActionSlots(parts) {
+		part := parts.Parts[slot]
+		chargeTicks := aiGaugeTicks(parts, part.Charge.Int(), cfg)
+		cost := profile.ChargeWeight*chargeTicks/100 + aiSelfRisk(entry, part, len(targets), chargeTicks+aiGaugeTicks(parts, part.Cooldown.Int(), cfg), profile)
+		if part.Category != CategoryShoot && part.Category != CategoryFight {
+			consider(slot, 0, -cost) // 攻撃以外はターゲット不要
+			continue
+		}
+		for _, target := range targets {
+			if value, ok := aiAttackValue(entry, part, target, profile, cfg); ok {
+				consider(slot, target.Entity(), value-cost)
+			}
+		}
+	}
+	return bestSlot, bestTarget, found
+}
+
+// aiAttackValue はターゲットを攻撃したときの見込みの点数を計算します。
+// 被弾部位はselectRandomPartToDamageと同様に、壊れていない部位から等確率で選ばれるものとします。
+// 残りの装甲を超えるダメージは数えないため、壊れかけの部位が多い敵ほど、破壊の確率で点数が上がります。
+func aiAttackValue(attackerEntry *donburi.Entry, attackerPart *Part, targetEntry *donburi.Entry, profile AIProfile, cfg BalanceConfig) (float64, bool) {
+	attackerMedal := CMedal.Get(attackerEntry)
+	targetParts := PartsComponentType.Get(targetEntry)
+	targetLegs := targetParts.Parts[PartSlotLegs]
+	targetStatus := StatusComponentType.Get(targetEntry)
+
+	hitProb, critProb := hitProbabilities(computeHitChance(attackerMedal, attackerPart, targetStatus, targetLegs, cfg))
+	normalProb := hitProb - critProb
+
+	vulnerable := 0
+	var damage, breakProb, headBreakProb float64
+	for _, slot := range []PartSlotKey{PartSlotHead, PartSlotRightArm, PartSlotLeftArm, PartSlotLegs} {
+		part, ok := targetParts.Parts[slot]
+		if !ok || part.IsBroken {
+			continue
+		}
+		normal := calculateDamage(attackerEntry, attackerMedal, attackerPart, part, targetLegs, false, cfg, targetStatus.IsDefenseDisabled).Damage
+		crit := calculateDamage(attackerEntry, attackerMedal, attackerPart, part, targetLegs, true, cfg, targetStatus.IsDefenseDisabled).Damage
+		damage += normalProb*float64(min(normal, part.Armor)) + critProb*float64(min(crit, part.Armor))
+
+		partBreak := 0.0
+		if normal >= part.Armor {
+			partBreak += normalProb
+		}
+		if crit >= part.Armor {
+			partBreak += critProb
+		}
+		breakProb += partBreak
+		if slot == PartSlotHead {
+			headBreakProb += partBreak // 頭部の破壊は機体の機能停止
+		}
+		vulnerable++
+	}
+	if vulnerable == 0 {
+		return 0, false
+	}
+	n := float64(vulnerable)
+	value := profile.DamageWeight*damage/n + profile.BreakWeight*breakProb/n + profile.HeadBreakWeight*headBreakProb/n
+	if IdentityComponentType.Get(targetEntry).IsLeader {
+		value *= 1 + profile.LeaderWeight // リーダーの機能停止で勝敗が決まる
+	}
+	return value, true
+}
+
+// aiSelfRisk は特性（狙い撃ち・力溜め・がむしゃら）で自分の回避・防御ができなくなる危険を見積もります。
+// 無防備になる時間（チャージと冷却のティック数）と、その間に攻撃してくる可能性のある敵の数に比例させます。
+// 自分がリーダーなら、失うと負けるため割増しします。
+func aiSelfRisk(entry *donburi.Entry, part *Part, threats int, exposedTicks float64, profile AIProfile) float64 {
+	exposure := 0.0
+	switch part.Trait {
+	case TraitAim: // 回避不可
+		exposure = 1
+	case TraitStrike: // 防御不可
+		exposure = 1
+	case TraitBerserk: // 回避・防御とも不可
+		exposure = 2
+	}
+	risk := profile.SelfRiskWeight * exposure * float64(threats) * exposedTicks / 100
+	if IdentityComponentType.Get(entry).IsLeader {
+		risk *= 1 + profile.LeaderWeight
+	}
+	return risk
+}
+
+// aiGaugeTicks はゲージが0から100まで溜まるのにかかるティック数を、戦闘速度x1として見積もります。
+func aiGaugeTicks(parts *PartsComponent, baseStat int, cfg BalanceConfig) float64 {
+	legPropulsion := 0
+	if legs, ok := parts.Parts[PartSlotLegs]; ok && !legs.IsBroken {
+		legPropulsion = legs.Propulsion.Int()
+	}
+	step := gaugeStepPerTick(baseStat, legPropulsion, cfg)
+	if step <= 0 {
+		return 0
+	}
+	return 100 / step
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/yohamta/donburi"
+)
+
+func TestAIAttackValueCountsDamageUpToRemainingArmor(t *testing.T) {
+	world := donburi.NewWorld()
+	gun := &Part{Category: CategoryShoot, Trait: TraitNormal, Power: SomeInt(40), Accuracy: 90}
+	attacker := newTestMedarot(world, &Medal{SkillShoot: 10}, map[PartSlotKey]*Part{PartSlotRightArm: gun})
+	target := newTestMedarot(world, &Medal{}, map[PartSlotKey]*Part{
+		PartSlotHead:     {Armor: 40, Defense: 5},
+		PartSlotRightArm: {Armor: 100, Defense: 10},
+		PartSlotLeftArm:  {Armor: 10, IsBroken: true},
+		PartSlotLegs:     {Armor: 20, Defense: 5, Mobility: SomeInt(40)},
+	})
+	target.AddComponent(IdentityComponentType)
+
+	// 命中率110: 通常命中0.9・クリティカル0.1。ダメージは通常/クリティカルで 頭50/75・右腕45/67・脚部50/75
+	// 残りの装甲で頭は40、脚部は20までしか数えず、右腕はどちらでも壊れない
+	const (
+		damage    = (0.9*40 + 0.1*40 + 0.9*45 + 0.1*67 + 0.9*20 + 0.1*20) / 3
+		breakProb = (1.0 + 0 + 1.0) / 3
+		headBreak = 1.0 / 3
+	)
+	tests := []struct {
+		name    string
+		profile AIProfile
+		leader  bool
+		want    float64
+	}{
+		{"damage only", AIProfile{DamageWeight: 1}, false, damage},
+		{"breaking parts", AIProfile{BreakWeight: 30}, false, 30 * breakProb},
+		{"breaking the head", AIProfile{HeadBreakWeight: 60}, false, 60 * headBreak},
+		{"all weights", AIProfile{DamageWeight: 1, BreakWeight: 30, HeadBreakWeight: 60, LeaderWeight: 0.5}, false, damage + 30*breakProb + 60*headBreak},
+		{"leader bonus", AIProfile{DamageWeight: 1, BreakWeight: 30, HeadBreakWeight: 60, LeaderWeight: 0.5}, true, (damage + 30*breakProb + 60*headBreak) * 1.5},
+	}
+	for _, tt := range tests {
+		IdentityComponentType.Get(target).IsLeader = tt.leader
+		got, ok := aiAttackValue(attacker, gun, target, tt.profile, testBalance())
+		if !ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: aiAttackValue = %g, %v; want %g", tt.name, got, ok, tt.want)
+		}
+	}
+
+	// 装甲で頭打ちにするため、プレビューの期待ダメージより小さくなる
+	preview, _ := previewAction(attacker, gun, target, testBalance())
+	if value, _ := aiAttackValue(attacker, gun, target, AIProfile{DamageWeight: 1}, testBalance()); value >= preview.ExpectedDamage {
+		t.Errorf("capped damage %g is not below the preview's %g", value, preview.ExpectedDamage)
+	}
+}
+
+func TestAIAttackValueWithoutVulnerableParts(t *testing.T) {
+	world := donburi.NewWorld()
+	gun := &Part{Category: CategoryShoot, Trait: TraitNormal, Power: SomeInt(40), Accuracy: 90}
+	attacker := newTestMedarot(world, &Medal{}, map[PartSlotKey]*Part{PartSlotRightArm: gun})
+	target := newTestMedarot(world, &Medal{}, map[PartSlotKey]*Part{
+		PartSlotHead: {Armor: 40, IsBroken: true},
+		PartSlotLegs: {Armor: 20, IsBroken: true},
+	})
+	target.AddComponent(IdentityComponentType)
+	if value, ok := aiAttackValue(attacker, gun, target, AIProfile{DamageWeight: 1}, testBalance()); ok {
+		t.Errorf("aiAttackValue = %g for a target with every part broken, want false", value)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	stdmath "math"
+
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/ecs"
+	"github.com/yohamta/donburi/features/math"
+	"github.com/yohamta/donburi/filter"
+)
+
+// AnimationKind は演出の種類です。
+type AnimationKind int
+
+const (
+	AnimProjectile  AnimationKind = iota // 射撃の弾が攻撃者からターゲットへ飛ぶ
+	AnimLunge                            // 格闘で攻撃者がターゲットへ突進して戻る
+	AnimDamagePopup                      // ダメージ数値（またはMISS）が浮かび上がる
+	AnimCritFlash                        // クリティカル時の画面フラッシュ
+	AnimDodge                            // 回避したメダロットが跳ねる
+	AnimSparks                           // パーツ破壊時の火花
+)
+
+// 演出の長さ（ティック数、戦闘速度x1の場合）
+const (
+	projectileDuration = 18
+	lungeDuration      = 20
+	popupDuration      = 45
+	critFlashDuration  = 12
+	dodgeDuration      = 18
+	sparksDuration     = 30
+	shakeDuration      = 20
+	armorDrainRate     = 0.15 // 1ティックで表示上の装甲が実際の値に近づく割合
+)
+
+// AnimationComponent は再生中の演出1つ分を表します。演出ごとに一時的なエンティティが作られ、再生後に削除されます。
+type AnimationComponent struct {
+	Kind     AnimationKind
+	Subject  donburi.Entity // 突進・回避で動かすメダロット
+	From, To math.Vec2
+	Text     string
+	Critical bool
+	Delay    float64 // 再生開始までの待ちティック数
+	Elapsed  float64
+	Duration float64
+}
+
+var AnimationComponentType = donburi.NewComponentType[AnimationComponent]()
+
+// Progress は演出の進行度 (0.0-1.0) を返します。
+func (a *AnimationComponent) Progress() float64 {
+	if a.Duration <= 0 {
+		return 1
+	}
+	return stdmath.Min(a.Elapsed/a.Duration, 1)
+}
+
+// Active は演出が再生開始済みかを返します。
+func (a *AnimationComponent) Active() bool {
+	return a.Delay <= 0
+}
+
+// ArmorDisplayComponent は情報パネルに表示する装甲値を保持し、実際の装甲値に向けて滑らかに減らします。
+type ArmorDisplayComponent struct {
+	Values map[PartSlotKey]float64
+}
+
+var ArmorDisplayComponentType = donburi.NewComponentType[ArmorDisplayComponent]()
+
+// ScreenShakeComponent は画面の揺れの残り時間と強さを保持するシングルトンコンポーネントです。
+type ScreenShakeComponent struct {
+	Remaining float64
+	Duration  float64
+	Intensity float64
+}
+
+var ScreenShakeComponentType = donburi.NewComponentType[ScreenShakeComponent]()
+
+// AnimationSystem は戦闘イベントを受け取って演出用のエンティティを生成し、毎ティック進めます。
+// 演出は戦闘速度に合わせて速くなり、全スキップ操作や即時メッセージモードでは省略されます。
+type AnimationSystem struct {
+	pending           []CombatEvent
+	animationQuery    *donburi.Query
+	medarotQuery      *donburi.Query
+	armorDisplayQuery *donburi.Query
+}
+
+func NewAnimationSystem() *AnimationSystem {
+	return &AnimationSystem{
+		animationQuery: donburi.NewQuery(filter.Contains(AnimationComponentType)),
+		medarotQuery: donburi.NewQuery(filter.And(
+			filter.Contains(IdentityComponentType), filter.Contains(StatusComponentType), filter.Contains(RenderComponentType),
+		)),
+		armorDisplayQuery: donburi.NewQuery(filter.And(
+			filter.Contains(ArmorDisplayComponentType), filter.Contains(PartsComponentType),
+		)),
+	}
+}
+
+// OnCombatEvent はイベントを保留し、次のUpdateで演出を生成します。
+func (sys *AnimationSystem) OnCombatEvent(ev CombatEvent) {
+	if ev.Type == EventAttack {
+		sys.pending = append(sys.pending, ev)
+	}
+}
+
+func (sys *AnimationSystem) Update(ecs *ecs.ECS) {
+	configEntry, ok := ConfigComponentType.First(ecs.World)
+	if !ok {
+		return
+	}
+	configComp := ConfigComponentType.Get(configEntry)
+	speed := 1.0
+	skip := isInputJustPressed(InputSkipAll)
+	if settings := configComp.Settings; settings != nil {
+		if settings.GaugeSpeed > 0 {
+			speed = float64(settings.GaugeSpeed)
+		}
+		skip = skip || settings.MessageMode == MessageModeInstant
+	}
+
+	for _, ev := range sys.pending {
+		sys.spawnForEvent(ecs, configComp.GameConfig, ev)
+	}
+	sys.pending = sys.pending[:0]
+
+	if skip {
+		sys.finishAll(ecs)
+		return
+	}
+
+	// 演出を進め、終わったものを削除する
+	var finished []*donburi.Entry
+	sys.animationQuery.Each(ecs.World, func(entry *donburi.Entry) {
+		anim := AnimationComponentType.Get(entry)
+		if anim.Delay > 0 {
+			anim.Delay -= speed
+			return
+		}
+		anim.Elapsed += speed
+		if anim.Elapsed >= anim.Duration {
+			finished = append(finished, entry)
+		}
+	})
+	for _, entry := range finished {
+		ecs.World.Remove(entry.Entity())
+	}
+
+	if shakeEntry, ok := ScreenShakeComponentType.First(ecs.World); ok {
+		shake := ScreenShakeComponentType.Get(shakeEntry)
+		if shake.Remaining > 0 {
+			shake.Remaining = stdmath.Max(shake.Remaining-speed, 0)
+		}
+	}
+
+	// 装甲バーを実際の値に向けて滑らかに減らす
+	rate := stdmath.Min(armorDrainRate*speed, 1)
+	sys.armorDisplayQuery.Each(ecs.World, func(entry *donburi.Entry) {
+		display := ArmorDisplayComponentType.Get(entry)
+		for slot, part := range PartsComponentType.Get(entry).Parts {
+			current, ok := display.Values[slot]
+			target := float64(part.Armor)
+			if !ok || stdmath.Abs(current-target) < 0.5 {
+				display.Values[slot] = target
+				continue
+			}
+			display.Values[slot] = current + (target-current)*rate
+		}
+	})
+}
+
+// finishAll は再生中の演出をすべて終了し、装甲バーを実際の値にそろえます。
+func (sys *AnimationSystem) finishAll(ecs *ecs.ECS) {
+	var entries []*donburi.Entry
+	sys.animationQuery.Each(ecs.World, func(entry *donburi.Entry) {
+		entries = append(entries, entry)
+	})
+	for _, entry := range entries {
+		ecs.World.Remove(entry.Entity())
+	}
+	if shakeEntry, ok := ScreenShakeComponentType.First(ecs.World); ok {
+		ScreenShakeComponentType.Get(shakeEntry).Remaining = 0
+	}
+	sys.armorDisplayQuery.Each(ecs.World, func(entry *donburi.Entry) {
+		display := ArmorDisplayComponentType.Get(entry)
+		for slot, part := range PartsComponentType.Get(entry).Parts {
+			display.Values[slot] = float64(part.Armor)
+		}
+	})
+}
+
+// findMedarotByID はIdentityComponentのIDからメダロットのエントリを探します。
+func (sys *AnimationSystem) findMedarotByID(ecs *ecs.ECS, id string) *donburi.Entry {
+	var found *donburi.Entry
+	sys.medarotQuery.Each(ecs.World, func(entry *donburi.Entry) {
+		if found == nil && IdentityComponentType.Get(entry).ID == id {
+			found = entry
+		}
+	})
+	return found
+}
+
+// iconPositionOf はメダロットのアイコンの現在位置を返します。
+func iconPositionOf(config *Config, entry *donburi.Entry) math.Vec2 {
+	x, y := medarotIconPosition(config, IdentityComponentType.Get(entry), StatusComponentType.Get(entry), RenderComponentType.Get(entry))
+	return math.NewVec2(float64(x), float64(y))
+}
+
+// spawnForEvent は攻撃イベント1件分の演出を生成します。
+func (sys *AnimationSystem) spawnForEvent(ecs *ecs.ECS, config *Config, ev CombatEvent) {
+	attacker := sys.findMedarotByID(ecs, ev.AttackerID)
+	target := sys.findMedarotByID(ecs, ev.TargetID)
+	if attacker == nil || target == nil {
+		return
+	}
+	from, to := iconPositionOf(config, attacker), iconPositionOf(config, target)
+
+	// 攻撃の動き
+	approach := AnimationComponent{Kind: AnimProjectile, From: from, To: to, Duration: projectileDuration}
+	if ev.Category == CategoryFight {
+		approach = AnimationComponent{Kind: AnimLunge, Subject: attacker.Entity(), From: from, To: to, Duration: lungeDuration}
+	}
+	spawnAnimation(ecs, approach)
+	impactDelay := approach.Duration
+	if approach.Kind == AnimLunge {
+		impactDelay = approach.Duration / 2 // 突進の折り返し地点で当たる
+	}
+
+	// 結果の演出
+	popupPos := math.NewVec2(to.X, to.Y-float64(config.UI.Battlefield.IconRadius)-4)
+	if !ev.Hit {
+		spawnAnimation(ecs, AnimationComponent{Kind: AnimDodge, Subject: target.Entity(), Delay: impactDelay, Duration: dodgeDuration})
+		spawnAnimation(ecs, AnimationComponent{Kind: AnimDamagePopup, From: popupPos, Text: "MISS", Delay: impactDelay, Duration: popupDuration})
+		return
+	}
+	spawnAnimation(ecs, AnimationComponent{Kind: AnimDamagePopup, From: popupPos, Text: fmt.Sprintf("%d", ev.Damage), Critical: ev.Critical, Delay: impactDelay, Duration: popupDuration})
+	if ev.Critical {
+		spawnAnimation(ecs, AnimationComponent{Kind: AnimCritFlash, Delay: impactDelay, Duration: critFlashDuration})
+	}
+	if ev.PartBroken {
+		spawnAnimation(ecs, AnimationComponent{Kind: AnimSparks, From: to, Delay: impactDelay, Duration: sparksDuration})
+	}
+	if ev.Critical || ev.PartBroken {
+		if shakeEntry, ok := ScreenShakeComponentType.First(ecs.World); ok {
+			shake := ScreenShakeComponentType.Get(shakeEntry)
+			intensity := 4.0
+			if ev.PartBroken {
+				intensity = 7.0
+			}
+			if shake.Remaining <= 0 || intensity > shake.Intensity {
+				shake.Intensity = intensity
+			}
+			// 揺れは着弾から始めたいが、シングルトンなので開始を遅らせる代わりに長さを延ばす
+			shake.Duration = shakeDuration + impactDelay
+			shake.Remaining = shake.Duration
+		}
+	}
+}
+
+// spawnAnimation は演出用のエンティティを作成します。
+func spawnAnimation(ecs *ecs.ECS, anim AnimationComponent) {
+	entity := ecs.World.Create(AnimationComponentType)
+	AnimationComponentType.SetValue(ecs.World.Entry(entity), anim)
+}
+
+// animationsPlaying は再生中または再生待ちの演出があるかを返します。
+func animationsPlaying(world donburi.World) bool {
+	_, ok := AnimationComponentType.First(world)
+	return ok
+}
+
+// animationIconOffset は突進・回避の演出によるアイコンのずれを返します。
+func animationIconOffset(world donburi.World, query *donburi.Query, subject donburi.Entity) (float64, float64) {
+	var dx, dy float64
+	query.Each(world, func(entry *donburi.Entry) {
+		anim := AnimationComponentType.Get(entry)
+		if anim.Subject != subject || !anim.Active() {
+			return
+		}
+		wave := stdmath.Sin(stdmath.Pi * anim.Progress())
+		switch anim.Kind {
+		case AnimLunge:
+			dx += (anim.To.X - anim.From.X) * 0.8 * wave
+			dy += (anim.To.Y - anim.From.Y) * 0.8 * wave
+		case AnimDodge:
+			dy -= 18 * wave
+		}
+	})
+	return dx, dy
+}
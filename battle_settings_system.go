@@ -0,0 +1,133 @@
+package main
+
+import (
+	"image"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/ecs"
+	"github.com/yohamta/donburi/filter"
+)
+
+const (
+	gaugeSpeedKey  = ebiten.KeyF   // ゲージ速度の切り替え
+	messageModeKey = ebiten.KeyM   // メッセージモードの切り替え
+	fullscreenKey  = ebiten.KeyF11 // フルスクリーンの切り替え（Alt+Enterでも可）
+	themeKey       = ebiten.KeyT   // テーマの切り替え
+	languageKey    = ebiten.KeyL   // 表示言語の切り替え
+)
+
+// BattleSettingsSystem はHUDボタンとホットキーで戦闘速度やメッセージモード、テーマ、表示言語、フルスクリーン表示を切り替え、設定を保存します。
+type BattleSettingsSystem struct{}
+
+func NewBattleSettingsSystem() *BattleSettingsSystem { return &BattleSettingsSystem{} }
+
+func (sys *BattleSettingsSystem) Update(ecs *ecs.ECS) {
+	configEntry, ok := ConfigComponentType.First(ecs.World)
+	if !ok {
+		return
+	}
+	configComp := ConfigComponentType.Get(configEntry)
+	settings := configComp.Settings
+	if settings == nil {
+		return
+	}
+
+	changed := false
+	if inpututil.IsKeyJustPressed(gaugeSpeedKey) {
+		settings.CycleGaugeSpeed()
+		changed = true
+	}
+	if inpututil.IsKeyJustPressed(messageModeKey) {
+		settings.CycleMessageMode()
+		changed = true
+	}
+	altEnter := inpututil.IsKeyJustPressed(ebiten.KeyEnter) && isFullscreenShortcut(ebiten.KeyEnter)
+	if inpututil.IsKeyJustPressed(fullscreenKey) || altEnter {
+		settings.Fullscreen = !settings.Fullscreen
+		ebiten.SetFullscreen(settings.Fullscreen)
+		changed = true
+	}
+	cycleTheme := inpututil.IsKeyJustPressed(themeKey)
+	cycleLanguage := inpututil.IsKeyJustPressed(languageKey)
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		speedRect, modeRect, themeRect, languageRect := hudButtonRects(configComp.GameConfig)
+		cursor := cursorPosition()
+		if cursor.In(speedRect) {
+			settings.CycleGaugeSpeed()
+			changed = true
+		} else if cursor.In(modeRect) {
+			settings.CycleMessageMode()
+			changed = true
+		} else if cursor.In(themeRect) {
+			cycleTheme = true
+		} else if cursor.In(languageRect) {
+			cycleLanguage = true
+		}
+	}
+	if cycleTheme && configComp.Themes != nil {
+		settings.Theme = configComp.Themes.NextThemeName(settings.Theme)
+		if err := configComp.Themes.Apply(&configComp.GameConfig.UI, settings.Theme); err != nil {
+			log.Printf("Failed to apply theme %s: %v", settings.Theme, err)
+		}
+		changed = true
+	}
+	if cycleLanguage {
+		settings.Language = NextLanguage(CurrentLanguage())
+		if err := applyLanguage(ecs.World, settings.Language); err != nil {
+			log.Printf("Failed to switch language: %v", err)
+		}
+		changed = true
+	}
+
+	if changed {
+		if err := settings.Save(); err != nil {
+			log.Printf("Failed to save settings: %v", err)
+		}
+	}
+}
+
+// hudButtonRects は戦場右上に並べる速度・メッセージモード・テーマ・言語の各ボタンの矩形を返します。
+func hudButtonRects(config *Config) (speedRect, modeRect, themeRect, languageRect image.Rectangle) {
+	hud := config.UI.HUD
+	w, h, m := int(hud.ButtonWidth), int(hud.ButtonHeight), int(hud.Margin)
+	right := config.UI.Screen.Width - m
+	languageRect = image.Rect(right-w, m, right, m+h)
+	themeRect = image.Rect(right-w*2-m, m, right-w-m, m+h)
+	modeRect = image.Rect(right-w*3-m*2, m, right-w*2-m*2, m+h)
+	speedRect = image.Rect(right-w*4-m*3, m, right-w*3-m*3, m+h)
+	return speedRect, modeRect, themeRect, languageRect
+}
+
+// isCursorOnHUD はカーソルがHUDボタン上にあるかを返します。
+// HUDへのクリックが他の入力（メッセージ送りなど）として扱われないようにするために使います。
+func isCursorOnHUD(config *Config) bool {
+	speedRect, modeRect, themeRect, languageRect := hudButtonRects(config)
+	cursor := cursorPosition()
+	return cursor.In(speedRect) || cursor.In(modeRect) || cursor.In(themeRect) || cursor.In(languageRect)
+}
+
+// messageAutoAdvanceTicks は自動送りモードで1件のメッセージを表示しておくティック数を返します。
+func messageAutoAdvanceTicks(settings *Settings) int {
+	ticks := settings.MessageAutoMs * ebiten.TPS() / 1000
+	if ticks < 1 {
+		ticks = 1
+	}
+	return ticks
+}
+
+// applyLanguage は表示言語を切り替え、言語に依存する機体名とウィンドウのタイトルを作り直します。
+// 既に表示したメッセージや行動履歴は切り替え前の言語のままです。
+func applyLanguage(w donburi.World, lang string) error {
+	if err := SetLanguage(lang); err != nil {
+		return err
+	}
+	donburi.NewQuery(filter.Contains(IdentityComponentType)).Each(w, func(entry *donburi.Entry) {
+		identity := IdentityComponentType.Get(entry)
+		identity.Name = T("medarot.name", "number", identity.Number)
+	})
+	ebiten.SetWindowTitle(T("window.title"))
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/filter"
+)
+
+// 戦闘の状態のスナップショット: 観戦配信（spectator.go）と外部ボットへの観測（bot_system.go）で共通のJSONの形です。
+
+// BattleSnapshot はある時点の戦闘の状態です。
+type BattleSnapshot struct {
+	Battle   int               `json:"battle,omitempty"` // 観戦配信で、リスタートのたびに増える戦闘の番号
+	Tick     int               `json:"tick"`
+	State    string            `json:"state"`
+	Message  string            `json:"message,omitempty"` // 表示中のメッセージ、または決着時のメッセージ
+	Winner   *TeamID           `json:"winner,omitempty"`
+	Medarots []MedarotSnapshot `json:"medarots"`
+}
+
+// MedarotSnapshot は1機分の状態です。
+type MedarotSnapshot struct {
+	ID              string          `json:"id"`
+	Name            string          `json:"name"`
+	Team            TeamID          `json:"team"`
+	Leader          bool            `json:"leader"`
+	Medal           string          `json:"medal,omitempty"`
+	State           MedarotState    `json:"state"`
+	Gauge           float64         `json:"gauge"`
+	EvasionDisabled bool            `json:"evasion_disabled"`
+	DefenseDisabled bool            `json:"defense_disabled"`
+	Action          *ActionSnapshot `json:"action,omitempty"` // 充填中・実行待ちの行動。行動を隠す機体では省略する
+	Parts           []PartSnapshot  `json:"parts"`
+}
+
+// ActionSnapshot は機体が選んだ行動です。
+type ActionSnapshot struct {
+	Slot     PartSlotKey `json:"slot"`
+	PartID   string      `json:"part_id,omitempty"`
+	TargetID string      `json:"target_id,omitempty"`
+}
+
+// PartSnapshot はパーツ1つ分の状態と性能です。NONEの性能は省略します。
+type PartSnapshot struct {
+	Slot       PartSlotKey    `json:"slot"`
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Category   ActionCategory `json:"category,omitempty"`
+	Trait      ActionTrait    `json:"trait,omitempty"`
+	Armor      int            `json:"armor"`
+	MaxArmor   int            `json:"max_armor"`
+	Broken     bool           `json:"broken"`
+	Power      int            `json:"power,omitempty"`
+	Charge     int            `json:"charge,omitempty"`
+	Cooldown   int            `json:"cooldown,omitempty"`
+	Accuracy   int            `json:"accuracy,omitempty"`
+	Defense    int            `json:"defense,omitempty"`
+	Mobility   int            `json:"mobility,omitempty"`
+	Propulsion int            `json:"propulsion,omitempty"`
+}
+
+// snapshotPartOrder はスナップショットでのパーツの並び順です。
+var snapshotPartOrder = []PartSlotKey{PartSlotHead, PartSlotRightArm, PartSlotLeftArm, PartSlotLegs}
+
+// snapshotStateNames はゲームの状態をスナップショット用の名前に変換します。
+var snapshotStateNames = map[GameState]string{
+	StatePlaying:            "playing",
+	StatePlayerActionSelect: "action_select",
+	GameStateMessage:        "message",
+	GameStateOver:           "over",
+	StatePaused:             "paused",
+	StateMedarotDetail:      "detail",
+}
+
+// BuildBattleSnapshot はRenderSystemと同じく、IdentityComponent・StatusComponent・PartsComponentを持つ機体を集めて
+// 戦闘の状態をまとめます。ホットシート対戦で画面に出さない行動は含めません。
+func BuildBattleSnapshot(w donburi.World) BattleSnapshot {
+	gs := GameStateComponentType.Get(GameStateComponentType.MustFirst(w))
+	snap := BattleSnapshot{
+		Tick:     gs.TickCount,
+		State:    snapshotStateNames[gs.CurrentState],
+		Medarots: []MedarotSnapshot{},
+	}
+	switch gs.CurrentState {
+	case GameStateMessage:
+		if len(gs.MessageQueue) > 0 {
+			snap.Message = gs.MessageQueue[0].Text
+		}
+	case GameStateOver:
+		winner := gs.Winner
+		snap.Winner = &winner
+		snap.Message = gs.Message
+	}
+
+	type drawOrder struct {
+		team  TeamID
+		index int
+	}
+	orders := map[string]drawOrder{}
+	donburi.NewQuery(filter.And(
+		filter.Contains(IdentityComponentType), filter.Contains(StatusComponentType), filter.Contains(PartsComponentType),
+	)).Each(w, func(entry *donburi.Entry) {
+		identity := IdentityComponentType.Get(entry)
+		status := visibleStatus(w, entry)
+		m := MedarotSnapshot{
+			ID:              identity.ID,
+			Name:            identity.Name,
+			Team:            identity.Team,
+			Leader:          identity.IsLeader,
+			State:           status.State,
+			Gauge:           status.Gauge,
+			EvasionDisabled: status.IsEvasionDisabled,
+			DefenseDisabled: status.IsDefenseDisabled,
+			Action:          actionSnapshotOf(w, entry, status.State),
+		}
+		if entry.HasComponent(CMedal) && CMedal.Get(entry).Medal != nil {
+			m.Medal = CMedal.Get(entry).Medal.DisplayName()
+		}
+		parts := PartsComponentType.Get(entry).Parts
+		for _, slot := range snapshotPartOrder {
+			if part, ok := parts[slot]; ok && part != nil {
+				m.Parts = append(m.Parts, partSnapshotOf(slot, part))
+			}
+		}
+		orders[identity.ID] = drawOrder{team: identity.Team, index: drawIndexOf(entry)}
+		snap.Medarots = append(snap.Medarots, m)
+	})
+	// 画面の情報パネルと同じく、チームと描画順で並べる
+	sort.Slice(snap.Medarots, func(i, j int) bool {
+		a, b := orders[snap.Medarots[i].ID], orders[snap.Medarots[j].ID]
+		if a.team != b.team {
+			return a.team < b.team
+		}
+		return a.index < b.index
+	})
+	return snap
+}
+
+// partSnapshotOf はパーツの状態と性能をまとめます。
+func partSnapshotOf(slot PartSlotKey, part *Part) PartSnapshot {
+	return PartSnapshot{
+		Slot: slot, ID: part.ID, Name: part.DisplayName(), Category: part.Category, Trait: part.Trait,
+		Armor: part.Armor, MaxArmor: part.MaxArmor, Broken: part.IsBroken,
+		Power: part.Power.Int(), Charge: part.Charge.Int(), Cooldown: part.Cooldown.Int(),
+		Accuracy: part.Accuracy, Defense: part.Defense, Mobility: part.Mobility.Int(), Propulsion: part.Propulsion.Int(),
+	}
+}
+
+// actionSnapshotOf は充填中・実行待ちの機体が選んだ行動を返します。行動がないか隠す場合はnilです。
+func actionSnapshotOf(w donburi.World, entry *donburi.Entry, state MedarotState) *ActionSnapshot {
+	if state != StateActionCharging && state != StateReadyToExecuteAction {
+		return nil
+	}
+	if !entry.HasComponent(ActionComponentType) || choicesHidden(w, entry) {
+		return nil
+	}
+	action := ActionComponentType.Get(entry)
+	snap := &ActionSnapshot{Slot: action.SelectedPartKey}
+	if part, ok := PartsComponentType.Get(entry).Parts[action.SelectedPartKey]; ok && part != nil {
+		snap.PartID = part.ID
+	}
+	if w.Valid(action.TargetedMedarot) {
+		snap.TargetID = entityDisplayID(w.Entry(action.TargetedMedarot))
+	}
+	return snap
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// 外部ボットとの通信部分。ボットは別のプロセスとして起動し、標準入力で観測を受け取り、標準出力で行動を返します。
+// どちらも1行1オブジェクトのJSONです。ボットの標準エラー出力はゲームの標準エラー出力にそのまま流します。
+//
+//	ゲーム → ボット: {"type":"observation","id":1,"tick":120,"self":"p4","team":1,"slots":["head","leftArm"],"targets":["p1","p2"],"battle":{...}}
+//	ボット → ゲーム: {"id":1,"slot":"leftArm","target":"p2"}
+
+const (
+	botSendQueue    = 8               // ボットが読み取っていない観測をいくつまで溜めるか。あふれたら観測を送らずに組み込みのAIで代わる
+	botCloseTimeout = 2 * time.Second // 終了時に、標準入力を閉じてからボットの終了を待つ時間
+)
+
+// errBotExited はボットのプロセスが終了していることを表します。
+var errBotExited = errors.New("bot process has exited")
+
+// BotObservation は行動を選ぶ機体1機ごとにボットへ送る観測です。
+type BotObservation struct {
+	Type    string         `json:"type"` // 常に "observation"
+	ID      int            `json:"id"`   // 応答で返してもらう通し番号
+	Tick    int            `json:"tick"`
+	Self    string         `json:"self"`    // 行動を選ぶ機体のID
+	Team    TeamID         `json:"team"`    // 行動を選ぶ機体のチーム
+	Slots   []PartSlotKey  `json:"slots"`   // 使えるパーツのスロット
+	Targets []string       `json:"targets"` // 射撃・格闘のターゲットにできる敵機体のID
+	Battle  BattleSnapshot `json:"battle"`
+}
+
+// BotReply はボットが選んだ行動です。射撃・格闘以外のパーツではTargetは使いません。
+type BotReply struct {
+	ID     int         `json:"id"`
+	Slot   PartSlotKey `json:"slot"`
+	Target string      `json:"target,omitempty"`
+}
+
+// BotProcess は起動した外部ボットのプロセスです。
+type BotProcess struct {
+	Name    string // ログ用の表示名（起動したコマンド）
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	send    chan BotObservation
+	replies chan BotReply // ボットが終了すると閉じられる
+	nextID  int
+}
+
+// StartBotProcess はコマンドを起動します。commandは空白で区切ったプログラムと引数です。
+func StartBotProcess(command string) (*BotProcess, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("bot command is empty")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin of bot %q: %w", command, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdout of bot %q: %w", command, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start bot %q: %w", command, err)
+	}
+	p := &BotProcess{
+		Name:    command,
+		cmd:     cmd,
+		stdin:   stdin,
+		send:    make(chan BotObservation, botSendQueue),
+		replies: make(chan BotReply, botSendQueue),
+	}
+	go p.writeLoop()
+	go p.readLoop(stdout)
+	return p, nil
+}
+
+// writeLoop は観測を順にボットの標準入力へ書き込みます。ボットが読み取らなくてもゲームが止まらないよう、別のゴルーチンで書きます。
+func (p *BotProcess) writeLoop() {
+	defer p.stdin.Close() // 終了時にsendが閉じられたら、ボットにEOFを伝える
+	enc := json.NewEncoder(p.stdin)
+	for obs := range p.send {
+		if err := enc.Encode(obs); err != nil {
+			log.Printf("Failed to send observation to bot %s: %v", p.Name, err)
+			return
+		}
+	}
+}
+
+// readLoop はボットの応答を読み取ります。標準出力が閉じられたら（ボットが終了したら）repliesを閉じます。
+func (p *BotProcess) readLoop(stdout io.Reader) {
+	defer close(p.replies)
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var reply BotReply
+		if err := json.Unmarshal([]byte(line), &reply); err != nil {
+			log.Printf("Bot %s sent an invalid reply %q: %v", p.Name, line, err)
+			continue
+		}
+		p.replies <- reply
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrClosed) { // Closeで閉じた場合は記録しない
+		log.Printf("Failed to read from bot %s: %v", p.Name, err)
+	}
+}
+
+// Request は観測に通し番号を付けてボットへ送り、その番号を返します。応答はPollで受け取ります。
+// 時間切れになった以前の観測への応答を見分けるため、応答には観測のidを入れてもらいます。
+func (p *BotProcess) Request(obs BotObservation) (int, error) {
+	p.nextID++
+	obs.Type = "observation"
+	obs.ID = p.nextID
+	select {
+	case p.send <- obs:
+		return obs.ID, nil
+	default:
+		return 0, errors.New("not reading observations")
+	}
+}
+
+// Poll は届いている応答を1つ返します。なければfalseを返します（待ちません）。
+// ボットが終了していればerrBotExitedを返します。
+func (p *BotProcess) Poll() (BotReply, bool, error) {
+	select {
+	case reply, ok := <-p.replies:
+		if !ok {
+			return BotReply{}, false, errBotExited
+		}
+		return reply, true, nil
+	default:
+		return BotReply{}, false, nil
+	}
+}
+
+// Close はボットの標準入力を閉じて終了を待ちます。終了しなければ強制終了します。
+func (p *BotProcess) Close() error {
+	close(p.send)
+	done := make(chan error, 1)
+	go func() { done <- p.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(botCloseTimeout):
+		p.cmd.Process.Kill()
+		return <-done
+	}
+}
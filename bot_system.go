@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/ecs"
+	"github.com/yohamta/donburi/filter"
+)
+
+// 外部ボット: チームの操作を外部のプロセス（bot_process.go）に任せます。どの言語で書いたボットでも、
+// AISystemと同じルールで戦わせることができます。ボットが時間内に応答しない、使えない行動を返す、
+// 終了してしまった、といった場合は組み込みのAIが代わりに行動を選びます。
+
+// BotControlledComponent は外部ボットが操作する機体に付けます。この機体はAISystemではなくBotSystemが担当します。
+type BotControlledComponent struct {
+	Bot *BotProcess
+}
+
+var BotControlledComponentType = donburi.NewComponentType[BotControlledComponent]()
+
+// botSettleInterval はヘッドレス実行で、ボットの応答が届いたかを確かめる間隔です。
+const botSettleInterval = time.Millisecond
+
+// botRequest は応答を待っている問い合わせです。
+type botRequest struct {
+	bot      *BotProcess
+	id       int       // 観測の通し番号
+	deadline time.Time // これを過ぎたら組み込みのAIで代わる
+}
+
+// BotSystem は外部ボットが操作する機体の行動を決めます。
+// 行動を選べる状態になった機体ごとに観測を送ります。応答を待つ間もゲームは止めず、その機体だけが行動選択のまま待ちます。
+type BotSystem struct {
+	bots            map[TeamID]*BotProcess
+	timeout         time.Duration
+	botQuery        *donburi.Query
+	targetableQuery *donburi.Query
+	exited          map[*BotProcess]bool // 終了したボット。以降は問い合わせずに組み込みのAIで代わる
+	pending         map[donburi.Entity]botRequest
+}
+
+// NewBotSystem はチームごとのボットと、1回の応答を待つ時間からBotSystemを作ります。
+func NewBotSystem(bots map[TeamID]*BotProcess, timeout time.Duration) *BotSystem {
+	return &BotSystem{
+		bots:    bots,
+		timeout: timeout,
+		botQuery: donburi.NewQuery(filter.And(
+			filter.Contains(BotControlledComponentType), filter.Contains(StatusComponentType),
+			filter.Contains(PartsComponentType), filter.Contains(ActionComponentType),
+			filter.Contains(IdentityComponentType), filter.Not(filter.Contains(BrokenTag)),
+		)),
+		targetableQuery: donburi.NewQuery(filter.And(
+			filter.Contains(IdentityComponentType), filter.Contains(StatusComponentType),
+			filter.Not(filter.Contains(BrokenTag)),
+		)),
+		exited:  make(map[*BotProcess]bool),
+		pending: make(map[donburi.Entity]botRequest),
+	}
+}
+
+// attach はボットが担当するチームの機体をAI制御にして、BotControlledComponentを付けます。リスタートのたびに呼びます。
+// 前の戦闘への問い合わせは捨てます（届いた応答は通し番号が合わないため使われない）。
+func (sys *BotSystem) attach(w donburi.World) {
+	sys.pending = make(map[donburi.Entity]botRequest)
+	var entries []*donburi.Entry
+	donburi.NewQuery(filter.Contains(IdentityComponentType)).Each(w, func(entry *donburi.Entry) {
+		if _, ok := sys.bots[IdentityComponentType.Get(entry).Team]; ok {
+			entries = append(entries, entry)
+		}
+	})
+	// クエリの走査中にアーキタイプを変更しないよう、収集後に付け替える
+	for _, entry := range entries {
+		if entry.HasComponent(PlayerControlledComponentType) {
+			entry.RemoveComponent(PlayerControlledComponentType)
+		}
+		if !entry.HasComponent(AIControlledComponentType) {
+			entry.AddComponent(AIControlledComponentType)
+		}
+		donburi.Add(entry, BotControlledComponentType, &BotControlledComponent{Bot: sys.bots[IdentityComponentType.Get(entry).Team]})
+	}
+}
+
+// Update は届いた応答で行動を確定させ、行動を選べる状態になったボットの機体について問い合わせを送ります。
+// 応答を待たずに戻り、時間切れになった機体は組み込みのAIで選びます。
+func (sys *BotSystem) Update(ecs *ecs.ECS) {
+	gs, ok := GameStateComponentType.First(ecs.World)
+	if !ok || GameStateComponentType.Get(gs).CurrentState != StatePlaying {
+		return
+	}
+	var ready []*donburi.Entry
+	readySet := make(map[donburi.Entity]bool)
+	sys.botQuery.Each(ecs.World, func(entry *donburi.Entry) {
+		if StatusComponentType.Get(entry).State == StateReadyToSelectAction {
+			ready = append(ready, entry)
+			readySet[entry.Entity()] = true
+		}
+	})
+	// 待っている間に破壊された機体への問い合わせは捨てる
+	for entity := range sys.pending {
+		if !readySet[entity] {
+			delete(sys.pending, entity)
+		}
+	}
+
+	sys.collectReplies(ecs)
+
+	now := time.Now()
+	for _, entry := range ready {
+		req, waiting := sys.pending[entry.Entity()]
+		switch {
+		case !waiting:
+			sys.request(ecs, entry)
+		case sys.exited[req.bot]:
+			delete(sys.pending, entry.Entity())
+			sys.fallback(ecs, entry)
+		case now.After(req.deadline):
+			delete(sys.pending, entry.Entity())
+			log.Printf("Bot %s: no reply within %v; the built-in AI chooses for %s.", req.bot.Name, sys.timeout, IdentityComponentType.Get(entry).ID)
+			sys.fallback(ecs, entry)
+		}
+	}
+}
+
+// Settle は問い合わせ中の機体すべての行動が決まる（応答が届くか時間切れになる）まで待ちます。
+// ヘッドレス実行で、ボットの速さに関わらず同じ戦闘になるよう、ゲームを進める前に呼びます。
+func (sys *BotSystem) Settle(ecs *ecs.ECS) {
+	for len(sys.pending) > 0 {
+		gs, ok := GameStateComponentType.First(ecs.World)
+		if !ok || GameStateComponentType.Get(gs).CurrentState != StatePlaying {
+			return
+		}
+		time.Sleep(botSettleInterval)
+		sys.Update(ecs)
+	}
+}
+
+// request は観測を送り、応答を待つ問い合わせとして記録します。送れなかった場合は組み込みのAIで選びます。
+func (sys *BotSystem) request(ecs *ecs.ECS, entry *donburi.Entry) {
+	slots := availableActionSlots(PartsComponentType.Get(entry))
+	if len(slots) == 0 {
+		return // 組み込みのAIと同じく、使えるパーツがなければ行動しない
+	}
+	bot := BotControlledComponentType.Get(entry).Bot
+	if sys.exited[bot] {
+		sys.fallback(ecs, entry)
+		return
+	}
+	identity := IdentityComponentType.Get(entry)
+	targetIDs := []string{}
+	for _, c := range opponentCandidates(ecs, entry, sys.targetableQuery) {
+		targetIDs = append(targetIDs, entityDisplayID(ecs.World.Entry(c)))
+	}
+	id, err := bot.Request(BotObservation{
+		Tick:    GameStateComponentType.Get(GameStateComponentType.MustFirst(ecs.World)).TickCount,
+		Self:    identity.ID,
+		Team:    identity.Team,
+		Slots:   slots,
+		Targets: targetIDs,
+		Battle:  BuildBattleSnapshot(ecs.World),
+	})
+	if err != nil {
+		log.Printf("Bot %s: %v; the built-in AI chooses for %s.", bot.Name, err, identity.ID)
+		sys.fallback(ecs, entry)
+		return
+	}
+	sys.pending[entry.Entity()] = botRequest{bot: bot, id: id, deadline: time.Now().Add(sys.timeout)}
+}
+
+// collectReplies は各ボットから届いている応答を読み、待っている問い合わせと通し番号が合うものを確定させます。
+// 時間切れになった問い合わせへの応答など、合わないものは捨てます。
+func (sys *BotSystem) collectReplies(ecs *ecs.ECS) {
+	for _, bot := range sys.bots {
+		for !sys.exited[bot] {
+			reply, ok, err := bot.Poll()
+			if errors.Is(err, errBotExited) {
+				sys.exited[bot] = true
+				log.Printf("Bot %s has exited; the built-in AI takes over.", bot.Name)
+			}
+			if !ok {
+				break
+			}
+			for entity, req := range sys.pending {
+				if req.bot == bot && req.id == reply.ID {
+					delete(sys.pending, entity)
+					sys.resolve(ecs, ecs.World.Entry(entity), bot, reply)
+					break
+				}
+			}
+		}
+	}
+}
+
+// resolve はボットが選んだ行動を確定させます。この機体に使えない行動だった場合は組み込みのAIで選びます。
+func (sys *BotSystem) resolve(ecs *ecs.ECS, entry *donburi.Entry, bot *BotProcess, reply BotReply) {
+	slotKey, target, err := sys.validate(ecs, entry, reply)
+	if err != nil {
+		log.Printf("Bot %s: %v; the built-in AI chooses for %s.", bot.Name, err, IdentityComponentType.Get(entry).ID)
+		sys.fallback(ecs, entry)
+		return
+	}
+	applyActionChoice(entry, slotKey, target)
+}
+
+// validate はボットが選んだ行動がこの機体に使えるものかを確かめ、パーツのスロットとターゲットを返します。
+func (sys *BotSystem) validate(ecs *ecs.ECS, entry *donburi.Entry, reply BotReply) (PartSlotKey, donburi.Entity, error) {
+	usable := false
+	for _, s := range availableActionSlots(PartsComponentType.Get(entry)) {
+		usable = usable || s == reply.Slot
+	}
+	if !usable {
+		return "", 0, fmt.Errorf("replied with unusable slot %q", reply.Slot)
+	}
+	category := PartsComponentType.Get(entry).Parts[reply.Slot].Category
+	if category != CategoryShoot && category != CategoryFight {
+		return reply.Slot, 0, nil // 攻撃以外はターゲット不要
+	}
+	for _, c := range opponentCandidates(ecs, entry, sys.targetableQuery) {
+		if entityDisplayID(ecs.World.Entry(c)) == reply.Target {
+			return reply.Slot, c, nil
+		}
+	}
+	return "", 0, fmt.Errorf("replied with invalid target %q", reply.Target)
+}
+
+// fallback は組み込みのAIで行動を選びます。
+func (sys *BotSystem) fallback(ecs *ecs.ECS, entry *donburi.Entry) {
+	if slotKey, target, ok := chooseAIAction(ecs.World, entry, sys.targetableQuery); ok {
+		applyActionChoice(entry, slotKey, target)
+	}
+}
+
+// Close は全てのボットを終了させます。
+func (sys *BotSystem) Close() {
+	for team, bot := range sys.bots {
+		if err := bot.Close(); err != nil {
+			log.Printf("Bot for team %d exited with error: %v", team+1, err)
+		}
+	}
+}
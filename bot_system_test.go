@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/ecs"
+)
+
+// botReplyScript は観測ごとに、使える最初のスロットで最初のターゲットを狙う応答を返すボットです。
+const botReplyScript = `exec sed -u 's/^{"type":"observation","id":\([0-9]*\),.*"slots":\["\([^"]*\)".*"targets":\["\([^"]*\)".*/{"id":\1,"slot":"\2","target":"\3"}/'`
+
+// startTestBot はシェルスクリプトをボットとして起動し、チーム2の機体を任せます。
+func startTestBot(t *testing.T, script string, timeout time.Duration) (*BotSystem, *ecs.ECS) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bot.sh")
+	if err := os.WriteFile(path, []byte(script+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bot, err := StartBotProcess("sh " + path)
+	if err != nil {
+		t.Fatalf("StartBotProcess: %v", err)
+	}
+	sys := NewBotSystem(map[TeamID]*BotProcess{Team2: bot}, timeout)
+	t.Cleanup(sys.Close)
+	battle := newTestBattle(loadTestGameData(t))
+	sys.attach(battle.World)
+	return sys, battle
+}
+
+// captureLog はテスト中のログを記録します。
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+// botEntries はボットが操作する機体を返します。
+func botEntries(sys *BotSystem, battle *ecs.ECS) []*donburi.Entry {
+	var entries []*donburi.Entry
+	sys.botQuery.Each(battle.World, func(entry *donburi.Entry) {
+		entries = append(entries, entry)
+	})
+	return entries
+}
+
+// updateUntilChosen は全ての機体の行動が決まるまでUpdateを繰り返します。
+func updateUntilChosen(t *testing.T, sys *BotSystem, battle *ecs.ECS) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sys.Update(battle)
+		chosen := true
+		for _, entry := range botEntries(sys, battle) {
+			chosen = chosen && StatusComponentType.Get(entry).State == StateActionCharging
+		}
+		if chosen {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("bot machines did not choose actions within 5s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBotSystemAppliesRepliesMatchingTheObservation(t *testing.T) {
+	logs := captureLog(t)
+	// 本物の応答の前に、どの観測にも対応しない古い応答を返す
+	sys, battle := startTestBot(t, strings.Replace(botReplyScript, `/{"id":\1`, `/{"id":0,"slot":"bogus"}\n{"id":\1`, 1), time.Minute)
+
+	type choice struct {
+		slot   PartSlotKey
+		target donburi.Entity
+	}
+	want := map[donburi.Entity]choice{}
+	for _, entry := range botEntries(sys, battle) {
+		c := choice{slot: availableActionSlots(PartsComponentType.Get(entry))[0]}
+		if category := PartsComponentType.Get(entry).Parts[c.slot].Category; category == CategoryShoot || category == CategoryFight {
+			c.target = opponentCandidates(battle, entry, sys.targetableQuery)[0]
+		}
+		want[entry.Entity()] = c
+	}
+
+	updateUntilChosen(t, sys, battle)
+	for _, entry := range botEntries(sys, battle) {
+		action := ActionComponentType.Get(entry)
+		if got := (choice{action.SelectedPartKey, action.TargetedMedarot}); got != want[entry.Entity()] {
+			t.Errorf("%s chose %+v, want %+v", IdentityComponentType.Get(entry).ID, got, want[entry.Entity()])
+		}
+	}
+	if strings.Contains(logs.String(), "built-in AI") {
+		t.Errorf("the built-in AI chose instead of the bot:\n%s", logs)
+	}
+}
+
+func TestBotSystemDoesNotBlockAndFallsBackAfterTimeout(t *testing.T) {
+	logs := captureLog(t)
+	const timeout = 100 * time.Millisecond
+	sys, battle := startTestBot(t, "while read -r line; do :; done", timeout)
+
+	start := time.Now()
+	sys.Update(battle)
+	if elapsed := time.Since(start); elapsed >= timeout {
+		t.Fatalf("Update blocked for %v", elapsed)
+	}
+	for _, entry := range botEntries(sys, battle) {
+		if state := StatusComponentType.Get(entry).State; state != StateReadyToSelectAction {
+			t.Errorf("%s is %v while waiting for the bot", IdentityComponentType.Get(entry).ID, state)
+		}
+	}
+	if len(sys.pending) != len(botEntries(sys, battle)) {
+		t.Errorf("%d pending requests, want one per bot machine", len(sys.pending))
+	}
+
+	time.Sleep(timeout)
+	updateUntilChosen(t, sys, battle)
+	if !strings.Contains(logs.String(), "no reply within") {
+		t.Errorf("timeout was not logged:\n%s", logs)
+	}
+	if len(sys.pending) != 0 {
+		t.Errorf("%d requests still pending after the fallback", len(sys.pending))
+	}
+}
+
+func TestBotSystemFallsBackWhenTheBotExits(t *testing.T) {
+	logs := captureLog(t)
+	sys, battle := startTestBot(t, "exit 0", time.Minute)
+
+	updateUntilChosen(t, sys, battle)
+	if !sys.exited[sys.bots[Team2]] {
+		t.Error("the exited bot was not marked as exited")
+	}
+	if !strings.Contains(logs.String(), "has exited") {
+		t.Errorf("exit was not logged:\n%s", logs)
+	}
+}
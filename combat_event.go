@@ -0,0 +1,80 @@
+package main
+
+import (
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/ecs"
+)
+
+// CombatEventType は戦闘イベントの種類を表します。
+type CombatEventType string
+
+const (
+	EventAttack       CombatEventType = "attack"        // 射撃・格闘の実行（命中・回避を含む）
+	EventActionUsed   CombatEventType = "action_used"   // 攻撃以外の行動の実行
+	EventActionFailed CombatEventType = "action_failed" // パーツ破壊やターゲット不在による行動失敗
+	EventBattleEnd    CombatEventType = "battle_end"    // 勝敗の決定
+)
+
+// StateTransition はエンティティの状態遷移を1件分記録します。
+type StateTransition struct {
+	EntityID string       `json:"entity_id"`
+	From     MedarotState `json:"from"`
+	To       MedarotState `json:"to"`
+}
+
+// CombatEvent は戦闘中に発生した1つの出来事です。
+// テレメトリ出力など、CombatEventListenerを実装した購読者に配信されます。
+type CombatEvent struct {
+	Tick           int               `json:"tick"`
+	Type           CombatEventType   `json:"type"`
+	AttackerID     string            `json:"attacker_id,omitempty"`
+	AttackerPartID string            `json:"attacker_part_id,omitempty"`
+	Category       ActionCategory    `json:"category,omitempty"`
+	TargetID       string            `json:"target_id,omitempty"`
+	TargetPartID   string            `json:"target_part_id,omitempty"`
+	HitChance      int               `json:"hit_chance"`
+	HitRoll        int               `json:"hit_roll"`
+	CritChance     int               `json:"crit_chance"`
+	CritRoll       int               `json:"crit_roll"`
+	Hit            bool              `json:"hit"`
+	Critical       bool              `json:"critical"`
+	Defense        float64           `json:"defense"`
+	Damage         int               `json:"damage"`
+	ArmorBefore    int               `json:"armor_before"`
+	ArmorAfter     int               `json:"armor_after"`
+	PartBroken     bool              `json:"part_broken"`
+	Winner         *TeamID           `json:"winner,omitempty"`
+	Transitions    []StateTransition `json:"transitions,omitempty"`
+	Message        string            `json:"message,omitempty"`
+}
+
+// CombatEventListener は戦闘イベントを受け取る購読者のインターフェースです。
+type CombatEventListener interface {
+	OnCombatEvent(ev CombatEvent)
+}
+
+// publishCombatEvent は現在のティックを設定した上で、登録済みの全購読者にイベントを配信します。
+func publishCombatEvent(ecs *ecs.ECS, ev CombatEvent) {
+	busEntry, ok := CombatEventComponentType.First(ecs.World)
+	if !ok {
+		return
+	}
+	bus := CombatEventComponentType.Get(busEntry)
+	if len(bus.Listeners) == 0 {
+		return
+	}
+	if gsEntry, ok := GameStateComponentType.First(ecs.World); ok {
+		ev.Tick = GameStateComponentType.Get(gsEntry).TickCount
+	}
+	for _, l := range bus.Listeners {
+		l.OnCombatEvent(ev)
+	}
+}
+
+// entityDisplayID はイベント記録用にエンティティのIDを返します。
+func entityDisplayID(entry *donburi.Entry) string {
+	if entry == nil || !entry.Valid() || !entry.HasComponent(IdentityComponentType) {
+		return ""
+	}
+	return IdentityComponentType.Get(entry).ID
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"image"
+
 	"github.com/yohamta/donburi"
 	"github.com/yohamta/donburi/features/math"
 )
@@ -10,7 +12,8 @@ import (
 // IdentityComponent はエンティティの基本的な識別情報を保持します。
 type IdentityComponent struct {
 	ID       string
-	Name     string
+	Number   int    // 機体番号（言語を切り替えたときに名前を作り直すため）
+	Name     string // 表示名（現在の言語）
 	Team     TeamID
 	IsLeader bool
 }
@@ -46,6 +49,7 @@ type ActionComponent struct {
 	SelectedPartKey PartSlotKey
 	TargetedMedarot donburi.Entity // ターゲットエンティティ
 	LastActionLog   string
+	ActionHistory   []string // 最近の行動ログ（古い順、最大maxActionHistory件）
 }
 
 var ActionComponentType = donburi.NewComponentType[ActionComponent]()
@@ -63,22 +67,38 @@ type AIControlledComponent struct{}
 
 var AIControlledComponentType = donburi.NewComponentType[AIControlledComponent]()
 
-// PlayerControlledComponent はプレイヤーによって制御されることを示すタグコンポーネントです。
-type PlayerControlledComponent struct{}
+// PlayerControlledComponent はプレイヤーによって制御されることを示すコンポーネントです。
+// ホットシート対戦では、どちらのプレイヤーが操作する機体かをPlayerで区別します。
+type PlayerControlledComponent struct {
+	Player int // 操作するプレイヤーの番号（1から）
+}
 
 var PlayerControlledComponentType = donburi.NewComponentType[PlayerControlledComponent]()
 
 // --- Singleton Components ---
 
+// GameMessage はメッセージキューに積まれる1件分のメッセージ（カットシーン）です。
+type GameMessage struct {
+	Text        string
+	Callback    func()      // メッセージを閉じた後に実行する継続処理
+	AutoAdvance int         // 0より大きい場合、このティック数が経過すると自動的に次へ送る
+	Guard       func() bool // nilでなくfalseを返す場合、表示せずに破棄する
+}
+
 // GameStateComponent はゲーム全体のグローバルな状態を保持します。
 type GameStateComponent struct {
-	TickCount           int
-	CurrentState        GameState
-	Message             string
-	PostMessageCallback func()
-	Winner              TeamID
-	RestartRequested    bool
-	DebugMode           bool
+	TickCount         int
+	CurrentState      GameState
+	Message           string        // ゲームオーバー時に表示する固定メッセージ
+	MessageQueue      []GameMessage // 表示待ちのメッセージ (FIFO)。先頭が表示中のメッセージ
+	MessageTicks      int           // 先頭のメッセージが表示されてからの経過ティック数
+	Winner            TeamID
+	MenuIndex         int            // ポーズメニューで選択中の項目
+	DetailTarget      donburi.Entity // 詳細画面に表示中のメダロット
+	PauseReturnState  GameState      // ポーズメニューを閉じたときに戻る状態
+	DetailReturnState GameState      // 詳細画面を閉じたときに戻る状態
+	RestartRequested  bool
+	DebugMode         bool
 }
 
 var GameStateComponentType = donburi.NewComponentType[GameStateComponent]()
@@ -87,7 +107,21 @@ var GameStateComponentType = donburi.NewComponentType[GameStateComponent]()
 type PlayerActionSelectComponent struct {
 	CurrentTarget    donburi.Entity
 	AvailableActions []PartSlotKey
+	FocusIndex       int              // キーボード・ゲームパッドでフォーカスしているボタンの位置
 	ActionQueue      []donburi.Entity // 行動選択待ちのエンティティのキュー
+	ActivePlayer     int              // 行動選択中のプレイヤーの番号（まだいなければ0）
+	HandOff          bool             // ホットシート対戦で、プレイヤー交代の画面を表示中
+	Committer        ActionCommitter  // 設定されていれば、確定した行動をその場で適用せずに渡す（ネット対戦）
+
+	lastCursor image.Point // マウスが動いたかを判定するための前フレームのカーソル位置
+}
+
+// ActionCommitter は、プレイヤーが確定した行動を受け取り、後で適用する仕組みです（ネット対戦で両方の端末に同じティックで適用するため）。
+type ActionCommitter interface {
+	// CanSelect は、この端末で行動を選ぶべき機体かを返します（相手の機体や、送信済みの機体はfalse）。
+	CanSelect(entry *donburi.Entry) bool
+	// Commit は確定した行動を受け取ります。
+	Commit(entry *donburi.Entry, slot PartSlotKey, target donburi.Entity)
 }
 
 var PlayerActionSelectComponentType = donburi.NewComponentType[PlayerActionSelectComponent]()
@@ -96,10 +130,20 @@ var PlayerActionSelectComponentType = donburi.NewComponentType[PlayerActionSelec
 type ConfigComponent struct {
 	GameConfig *Config
 	GameData   *GameData
+	Settings   *Settings // 実行中に変更できるプレイヤー設定（速度など）
+	Themes     *ThemeSet // 選択可能な配色テーマ（なければnil）
+	Match      MatchOptions
 }
 
 var ConfigComponentType = donburi.NewComponentType[ConfigComponent]()
 
+// CombatEventComponent は戦闘イベントの購読者（テレメトリなど）を保持します。
+type CombatEventComponent struct {
+	Listeners []CombatEventListener
+}
+
+var CombatEventComponentType = donburi.NewComponentType[CombatEventComponent]()
+
 // --- Tags ---
 
 var ReadyToExecuteActionTag = donburi.NewTag().SetName("ReadyToExecuteAction")
@@ -109,6 +153,27 @@ var ActionCooldownTag = donburi.NewTag().SetName("ActionCooldown")
 
 // --- Component Helper Methods ---
 
+// CurrentMessage は現在表示すべきメッセージを返します。
+// キューにメッセージがあればその先頭を、なければ固定メッセージを返します。
+func (gs *GameStateComponent) CurrentMessage() string {
+	if len(gs.MessageQueue) > 0 {
+		return gs.MessageQueue[0].Text
+	}
+	return gs.Message
+}
+
+// maxActionHistory は詳細画面に表示する行動ログの最大件数です。
+const maxActionHistory = 8
+
+// RecordAction は最後の行動ログを更新し、履歴に追加します。
+func (a *ActionComponent) RecordAction(logMsg string) {
+	a.LastActionLog = logMsg
+	a.ActionHistory = append(a.ActionHistory, logMsg)
+	if len(a.ActionHistory) > maxActionHistory {
+		a.ActionHistory = a.ActionHistory[len(a.ActionHistory)-maxActionHistory:]
+	}
+}
+
 // IsBroken はStatusComponentが破壊状態かどうかを返します。
 // これにより、各システムで状態をチェックするロジックが統一されます。
 func (s *StatusComponent) IsBroken() bool {
@@ -20,6 +20,53 @@ type BalanceConfig struct {
 	}
 }
 
+// AIProfile は効用AI（ai_utility.go）が行動の点数を計算するときの重みです。
+type AIProfile struct {
+	DamageWeight    float64 // 期待ダメージ（残りの装甲を超えた分は数えない）1あたりの点数
+	BreakWeight     float64 // パーツを破壊できる確率への点数
+	HeadBreakWeight float64 // 頭部を破壊できる（機能停止させられる）確率への追加の点数
+	LeaderWeight    float64 // ターゲットがリーダーのときに点数へ掛ける割増し（0で割増しなし、1で2倍）
+	ChargeWeight    float64 // チャージにかかる100ティックあたりの減点
+	SelfRiskWeight  float64 // 特性で自分の回避・防御ができなくなる危険への減点
+	Randomness      float64 // 点数に加えるゆらぎの最大値（0で常に最も点数の高い行動）
+}
+
+// AIProfiles は選べるAIのプロファイルです。
+type AIProfiles struct {
+	Balanced   AIProfile
+	Aggressive AIProfile // 破壊とリーダーを重視し、自分の危険をあまり気にしない
+	Cautious   AIProfile // チャージの短い行動を好み、回避・防御ができなくなる特性を避ける
+}
+
+// AIConfig は組み込みのAIの設定です。チームごとにプロファイルを選びます。
+type AIConfig struct {
+	// Team1Profile・Team2Profile はAIが操作する機体が使うプロファイルの名前です（aiProfileNames）。
+	// "random" は効用AIを使わず、使えるパーツと生きている敵からランダムに選びます。
+	Team1Profile string
+	Team2Profile string
+	Profiles     AIProfiles
+}
+
+// aiProfileNames は設定で指定できるプロファイルの名前です。
+var aiProfileNames = []string{"random", "balanced", "aggressive", "cautious"}
+
+// Profile はチームのプロファイルを返します。"random" の場合はfalseを返します。
+func (c AIConfig) Profile(team TeamID) (AIProfile, bool) {
+	name := c.Team1Profile
+	if team == Team2 {
+		name = c.Team2Profile
+	}
+	switch name {
+	case "balanced":
+		return c.Profiles.Balanced, true
+	case "aggressive":
+		return c.Profiles.Aggressive, true
+	case "cautious":
+		return c.Profiles.Cautious, true
+	}
+	return AIProfile{}, false
+}
+
 // UIConfig はUIのレイアウトや色に関する設定を管理します。
 type UIConfig struct {
 	Screen struct {
@@ -46,12 +93,29 @@ type UIConfig struct {
 		PartHPGaugeHeight  float32
 		TextLineHeight     float32
 		PartHPGaugeOffsetX float32
+		Columns            int // 1チームあたりの情報パネルの列数（画面幅に応じて変わる）
+		Rows               int
 	}
 	ActionModal struct {
 		ButtonWidth   float32
 		ButtonHeight  float32
 		ButtonSpacing float32
 	}
+	Message struct {
+		HoldToFastForward   bool // 早送りキーを押している間、メッセージを自動で送る
+		FastForwardInterval int  // 早送り時に1件のメッセージを表示しておくティック数
+	}
+	HUD struct {
+		ButtonWidth  float32
+		ButtonHeight float32
+		Margin       float32
+	}
+	// Style はテーマで変更できる、色以外の見た目の設定です。
+	Style struct {
+		PanelAlpha  uint8   // メッセージパネルなどの背景の不透明度
+		BorderWidth float32 // ウィンドウの枠の太さ
+		FontSize    float64 // 本文のフォントサイズ(pt)
+	}
 	Colors struct {
 		White      color.Color
 		Red        color.Color
@@ -64,6 +128,9 @@ type UIConfig struct {
 		Leader     color.Color
 		Broken     color.Color
 		HP         color.Color
+		HPLow      color.Color // 装甲が残り少ないパーツのHPバー
+		Panel      color.Color // メッセージパネルの背景
+		Tooltip    color.Color // ツールチップの背景（不透明度を含む）
 		Background color.Color
 	}
 }
@@ -71,21 +138,26 @@ type UIConfig struct {
 // Config はゲーム全体のすべての設定を保持します。
 type Config struct {
 	Balance BalanceConfig
+	AI      AIConfig
 	UI      UIConfig
 }
 
+// 画面サイズに関する定数です。ウィンドウがこれより小さい場合は、この大きさで描画して縮小表示します。
+const (
+	defaultScreenWidth  = 960
+	defaultScreenHeight = 540
+	minScreenWidth      = 800
+	minScreenHeight     = 450
+	minInfoPanelWidth   = 300 // 情報パネル1枚の最小幅。これを基に列数を決める
+)
+
 // LoadConfig はデフォルトの全設定を生成して返します。
 func LoadConfig() Config {
 	// 先にUIの基本定数を計算
-	screenWidth := 960
-	screenHeight := 540
-	playersPerTeam := 3
-	battlefieldHeight := float32(screenHeight) * 0.4
-	infoPanelHeight := float32(screenHeight) * 0.6
 	infoPanelPadding := float32(10)
 	iconRadius := float32(15)
 
-	return Config{
+	config := Config{
 		Balance: BalanceConfig{
 			Time: struct {
 				PropulsionEffectRate float64
@@ -113,14 +185,25 @@ func LoadConfig() Config {
 				MedalSkillFactor:   2,
 			},
 		},
-		UI: UIConfig{
-			Screen: struct {
-				Width  int
-				Height int
-			}{
-				Width:  screenWidth,
-				Height: screenHeight,
+		AI: AIConfig{
+			Team1Profile: "balanced",
+			Team2Profile: "balanced",
+			Profiles: AIProfiles{
+				Balanced: AIProfile{
+					DamageWeight: 1, BreakWeight: 30, HeadBreakWeight: 60, LeaderWeight: 1,
+					ChargeWeight: 10, SelfRiskWeight: 5, Randomness: 5,
+				},
+				Aggressive: AIProfile{
+					DamageWeight: 1, BreakWeight: 40, HeadBreakWeight: 100, LeaderWeight: 2,
+					ChargeWeight: 5, SelfRiskWeight: 0, Randomness: 2,
+				},
+				Cautious: AIProfile{
+					DamageWeight: 1, BreakWeight: 20, HeadBreakWeight: 40, LeaderWeight: 1,
+					ChargeWeight: 15, SelfRiskWeight: 20, Randomness: 2,
+				},
 			},
+		},
+		UI: UIConfig{
 			Battlefield: struct {
 				Height                 float32
 				Team1HomeX             float32
@@ -132,15 +215,9 @@ func LoadConfig() Config {
 				LineWidth              float32
 				MedarotVerticalSpacing float32
 			}{
-				Height:                 battlefieldHeight,
-				Team1HomeX:             100,
-				Team2HomeX:             float32(screenWidth - 100),
-				Team1ExecutionLineX:    float32(screenWidth/2) - (iconRadius + 5),
-				Team2ExecutionLineX:    float32(screenWidth/2) + (iconRadius + 5),
-				IconRadius:             iconRadius,
-				HomeMarkerRadius:       iconRadius / 3,
-				LineWidth:              1,
-				MedarotVerticalSpacing: battlefieldHeight / (float32(playersPerTeam) + 1),
+				IconRadius:       iconRadius,
+				HomeMarkerRadius: iconRadius / 3,
+				LineWidth:        1,
 			},
 			InfoPanel: struct {
 				StartY             float32
@@ -151,11 +228,10 @@ func LoadConfig() Config {
 				PartHPGaugeHeight  float32
 				TextLineHeight     float32
 				PartHPGaugeOffsetX float32
+				Columns            int
+				Rows               int
 			}{
-				StartY:             battlefieldHeight,
 				Padding:            infoPanelPadding,
-				BlockWidth:         (float32(screenWidth) - infoPanelPadding*3) / 2,
-				BlockHeight:        (infoPanelHeight - (infoPanelPadding * (float32(playersPerTeam) + 1))) / float32(playersPerTeam),
 				PartHPGaugeWidth:   100,
 				PartHPGaugeHeight:  7,
 				TextLineHeight:     12,
@@ -166,10 +242,35 @@ func LoadConfig() Config {
 				ButtonHeight  float32
 				ButtonSpacing float32
 			}{
-				ButtonWidth:   300,
+				ButtonWidth:   460,
 				ButtonHeight:  35,
 				ButtonSpacing: 5,
 			},
+			Message: struct {
+				HoldToFastForward   bool
+				FastForwardInterval int
+			}{
+				HoldToFastForward:   true,
+				FastForwardInterval: 6,
+			},
+			HUD: struct {
+				ButtonWidth  float32
+				ButtonHeight float32
+				Margin       float32
+			}{
+				ButtonWidth:  110,
+				ButtonHeight: 20,
+				Margin:       6,
+			},
+			Style: struct {
+				PanelAlpha  uint8
+				BorderWidth float32
+				FontSize    float64
+			}{
+				PanelAlpha:  200,
+				BorderWidth: 2,
+				FontSize:    10,
+			},
 			Colors: struct {
 				White      color.Color
 				Red        color.Color
@@ -182,6 +283,9 @@ func LoadConfig() Config {
 				Leader     color.Color
 				Broken     color.Color
 				HP         color.Color
+				HPLow      color.Color
+				Panel      color.Color
+				Tooltip    color.Color
 				Background color.Color
 			}{
 				White:      color.White,
@@ -195,8 +299,60 @@ func LoadConfig() Config {
 				Leader:     color.RGBA{R: 255, G: 255, B: 100, A: 255},
 				Broken:     color.RGBA{R: 128, G: 128, B: 128, A: 255},
 				HP:         color.RGBA{R: 100, G: 255, B: 100, A: 255},
+				HPLow:      color.RGBA{R: 255, G: 100, B: 100, A: 255},
+				Panel:      color.NRGBA{R: 0, G: 0, B: 0, A: 255},
+				Tooltip:    color.NRGBA{R: 0x10, G: 0x14, B: 0x1c, A: 235},
 				Background: color.NRGBA{R: 0x1a, G: 0x20, B: 0x2c, A: 0xff},
 			},
 		},
 	}
+	config.UI.ApplyScreenSize(defaultScreenWidth, defaultScreenHeight)
+	return config
+}
+
+// ApplyScreenSize は画面サイズから戦場と情報パネルの配置を計算し直します。
+// ウィンドウのサイズが変わるたびにGame.Layoutから呼ばれます。
+func (ui *UIConfig) ApplyScreenSize(width, height int) {
+	ui.Screen.Width, ui.Screen.Height = width, height
+	battlefieldHeight := float32(height) * 0.4
+	infoPanelHeight := float32(height) * 0.6
+
+	bf := &ui.Battlefield
+	bf.Height = battlefieldHeight
+	bf.Team1HomeX = 100
+	bf.Team2HomeX = float32(width - 100)
+	bf.Team1ExecutionLineX = float32(width/2) - (bf.IconRadius + 5)
+	bf.Team2ExecutionLineX = float32(width/2) + (bf.IconRadius + 5)
+	bf.MedarotVerticalSpacing = battlefieldHeight / (float32(PlayersPerTeam) + 1)
+
+	// 横に広い画面では情報パネルを1チームあたり複数列に並べる
+	ip := &ui.InfoPanel
+	columns := int((float32(width)/2 - ip.Padding) / (minInfoPanelWidth + ip.Padding))
+	if columns < 1 {
+		columns = 1
+	}
+	if columns > PlayersPerTeam {
+		columns = PlayersPerTeam
+	}
+	rows := (PlayersPerTeam + columns - 1) / columns
+	ip.Columns, ip.Rows = columns, rows
+	ip.StartY = battlefieldHeight
+	ip.BlockWidth = (float32(width) - ip.Padding*float32(columns*2+1)) / float32(columns*2)
+	ip.BlockHeight = (infoPanelHeight - ip.Padding*float32(rows+1)) / float32(rows)
+}
+
+// logicalScreenSize はウィンドウの大きさから描画に使う論理的な画面サイズを求めます。
+// ウィンドウが最小サイズより小さい場合は、縦横比を保ったまま最小サイズ以上に拡大した大きさを返します。
+func logicalScreenSize(outsideWidth, outsideHeight int) (int, int) {
+	if outsideWidth <= 0 || outsideHeight <= 0 {
+		return defaultScreenWidth, defaultScreenHeight
+	}
+	scale := 1.0
+	if s := float64(minScreenWidth) / float64(outsideWidth); s > scale {
+		scale = s
+	}
+	if s := float64(minScreenHeight) / float64(outsideHeight); s > scale {
+		scale = s
+	}
+	return int(float64(outsideWidth) * scale), int(float64(outsideHeight) * scale)
 }
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 設定の上書きに使う環境変数です。
+const (
+	configPathEnv = "MEDAROT_CONFIG" // 設定ファイルのパス（--configが優先）
+	configSetEnv  = "MEDAROT_SET"    // "key=value" をカンマ区切りで並べたもの（--setが優先）
+)
+
+// SetFlags は --set key=value を複数回受け取るためのflag.Valueです。
+type SetFlags []string
+
+func (s *SetFlags) String() string { return strings.Join(*s, ",") }
+
+func (s *SetFlags) Set(v string) error {
+	if !strings.Contains(v, "=") {
+		return fmt.Errorf("expected key=value, got %q", v)
+	}
+	*s = append(*s, v)
+	return nil
+}
+
+// ApplyConfigOverrides はデフォルトの設定に、設定ファイル・環境変数・--setの順で値を上書きし、検証します。
+// キーは "balance.hit.base_chance" のようにフィールド名を"."でつないだもので、大文字・小文字と"_"は区別しません。
+func ApplyConfigOverrides(config *Config, configPath string, sets []string) error {
+	if configPath == "" {
+		configPath = os.Getenv(configPathEnv)
+	}
+	if configPath != "" {
+		if err := applyConfigFile(config, configPath); err != nil {
+			return err
+		}
+	}
+
+	var overrides []string
+	if env := os.Getenv(configSetEnv); env != "" {
+		for _, kv := range strings.Split(env, ",") {
+			if kv = strings.TrimSpace(kv); kv != "" {
+				overrides = append(overrides, kv)
+			}
+		}
+	}
+	overrides = append(overrides, sets...)
+	var errs []error
+	for _, kv := range overrides {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			errs = append(errs, fmt.Errorf("%q: expected key=value", kv))
+			continue
+		}
+		if err := setConfigValue(config, strings.TrimSpace(key), strings.TrimSpace(value)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	config.UI.ApplyScreenSize(config.UI.Screen.Width, config.UI.Screen.Height)
+	return config.Validate()
+}
+
+// applyConfigFile はJSON形式の設定ファイルを読み込み、書かれている値だけを上書きします。
+func applyConfigFile(config *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	var tree map[string]any
+	if err := decoder.Decode(&tree); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flattenConfigTree("", tree, values); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var errs []error
+	for _, key := range keys {
+		if err := setConfigValue(config, key, values[key]); err != nil {
+			errs = append(errs, fmt.Errorf("config %s: %w", path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flattenConfigTree は入れ子のJSONオブジェクトを "a.b.c" 形式のキーと文字列の値に展開します。
+func flattenConfigTree(prefix string, node map[string]any, out map[string]string) error {
+	for name, value := range node {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			if err := flattenConfigTree(key, v, out); err != nil {
+				return err
+			}
+		case json.Number:
+			out[key] = v.String()
+		case bool:
+			out[key] = strconv.FormatBool(v)
+		case string:
+			out[key] = v
+		default:
+			return fmt.Errorf("%s: unsupported value %v", key, value)
+		}
+	}
+	return nil
+}
+
+// normalizeConfigKey はキーの比較用に、大文字・小文字と"_"の違いをなくします。
+func normalizeConfigKey(s string) string {
+	return strings.ToLower(strings.ReplaceAll(s, "_", ""))
+}
+
+// derivedConfigKeys はApplyScreenSizeが画面サイズから計算し直すキーです。設定しても上書きされるため受け付けません。
+var derivedConfigKeys = []string{
+	"ui.battlefield.height", "ui.battlefield.team1_home_x", "ui.battlefield.team2_home_x",
+	"ui.battlefield.team1_execution_line_x", "ui.battlefield.team2_execution_line_x", "ui.battlefield.medarot_vertical_spacing",
+	"ui.info_panel.start_y", "ui.info_panel.block_width", "ui.info_panel.block_height", "ui.info_panel.columns", "ui.info_panel.rows",
+}
+
+// setConfigValue はキーで指定したフィールドに値を設定します。
+func setConfigValue(config *Config, key, value string) error {
+	if slices.ContainsFunc(derivedConfigKeys, func(derived string) bool { return normalizeConfigKey(derived) == normalizeConfigKey(key) }) {
+		return fmt.Errorf("%s: derived from screen size; set ui.screen.width and ui.screen.height instead", key)
+	}
+	field := reflect.ValueOf(config).Elem()
+	for _, part := range strings.Split(key, ".") {
+		if field.Kind() != reflect.Struct {
+			return fmt.Errorf("%s: unknown key", key)
+		}
+		found := false
+		for i := 0; i < field.NumField(); i++ {
+			if normalizeConfigKey(field.Type().Field(i).Name) == normalizeConfigKey(part) {
+				field = field.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: unknown key", key)
+		}
+	}
+
+	switch field.Kind() {
+	case reflect.Int:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an integer", key, value)
+		}
+		field.SetInt(int64(v))
+	case reflect.Uint8:
+		v, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an integer between 0 and 255", key, value)
+		}
+		field.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a number", key, value)
+		}
+		field.SetFloat(v)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not true or false", key, value)
+		}
+		field.SetBool(v)
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Interface:
+		return fmt.Errorf("%s: colours are set with a theme file, not the config", key)
+	default:
+		return fmt.Errorf("%s: is a group of settings, not a single value", key)
+	}
+	return nil
+}
+
+// Validate は設定値の範囲を検査し、問題をまとめて返します。
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	percent := func(key string, v int) {
+		check(v >= 0 && v <= 100, "%s must be between 0 and 100, got %d", key, v)
+	}
+	bonus := func(key string, v int) {
+		check(v >= -100 && v <= 100, "%s must be between -100 and 100, got %d", key, v)
+	}
+
+	b := c.Balance
+	check(b.Time.OverallTimeDivisor > 0, "balance.time.overall_time_divisor must be greater than 0, got %g", b.Time.OverallTimeDivisor)
+	check(b.Time.PropulsionEffectRate >= 0, "balance.time.propulsion_effect_rate must not be negative, got %g", b.Time.PropulsionEffectRate)
+	percent("balance.hit.base_chance", b.Hit.BaseChance)
+	bonus("balance.hit.trait_aim_bonus", b.Hit.TraitAimBonus)
+	bonus("balance.hit.trait_strike_bonus", b.Hit.TraitStrikeBonus)
+	bonus("balance.hit.trait_berserk_debuff", b.Hit.TraitBerserkDebuff)
+	check(b.Damage.CriticalMultiplier >= 1, "balance.damage.critical_multiplier must be at least 1, got %g", b.Damage.CriticalMultiplier)
+	check(b.Damage.MedalSkillFactor >= 0, "balance.damage.medal_skill_factor must not be negative, got %d", b.Damage.MedalSkillFactor)
+
+	profileName := func(key, name string) {
+		check(slices.Contains(aiProfileNames, name), "%s must be one of %s, got %q", key, strings.Join(aiProfileNames, ", "), name)
+	}
+	profileWeights := func(key string, p AIProfile) {
+		weights := []float64{p.DamageWeight, p.BreakWeight, p.HeadBreakWeight, p.LeaderWeight, p.ChargeWeight, p.SelfRiskWeight, p.Randomness}
+		check(!slices.ContainsFunc(weights, func(w float64) bool { return w < 0 }), "%s weights must not be negative", key)
+	}
+	profileName("ai.team1_profile", c.AI.Team1Profile)
+	profileName("ai.team2_profile", c.AI.Team2Profile)
+	profileWeights("ai.profiles.balanced", c.AI.Profiles.Balanced)
+	profileWeights("ai.profiles.aggressive", c.AI.Profiles.Aggressive)
+	profileWeights("ai.profiles.cautious", c.AI.Profiles.Cautious)
+
+	ui := c.UI
+	check(ui.Screen.Width > 0 && ui.Screen.Height > 0, "ui.screen size must be positive, got %dx%d", ui.Screen.Width, ui.Screen.Height)
+	check(ui.Battlefield.IconRadius > 0, "ui.battlefield.icon_radius must be greater than 0, got %g", ui.Battlefield.IconRadius)
+	check(ui.Message.FastForwardInterval > 0, "ui.message.fast_forward_interval must be greater than 0, got %d", ui.Message.FastForwardInterval)
+	check(ui.Style.FontSize >= 6 && ui.Style.FontSize <= 32, "ui.style.font_size must be between 6 and 32, got %g", ui.Style.FontSize)
+	check(ui.Style.BorderWidth >= 0, "ui.style.border_width must not be negative, got %g", ui.Style.BorderWidth)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetConfigValue(t *testing.T) {
+	config := LoadConfig()
+	sets := map[string]string{
+		"balance.hit.base_chance":           "70",  // int
+		"Balance.Damage.CriticalMultiplier": "2.5", // 大文字・小文字と"_"は区別しない
+		"ui.message.hold_to_fast_forward":   "false",
+		"ui.style.panel_alpha":              "128",
+		"ai.team2_profile":                  "cautious",
+	}
+	for key, value := range sets {
+		if err := setConfigValue(&config, key, value); err != nil {
+			t.Errorf("setConfigValue(%s, %s): %v", key, value, err)
+		}
+	}
+	if config.Balance.Hit.BaseChance != 70 || config.Balance.Damage.CriticalMultiplier != 2.5 ||
+		config.UI.Message.HoldToFastForward || config.UI.Style.PanelAlpha != 128 || config.AI.Team2Profile != "cautious" {
+		t.Errorf("values were not set: %+v %+v %+v", config.Balance, config.UI.Message, config.AI)
+	}
+}
+
+func TestSetConfigValueRejects(t *testing.T) {
+	tests := []struct {
+		key, value, want string
+	}{
+		{"balance.hit.no_such_key", "1", "unknown key"},
+		{"balance.hit.base_chance.more", "1", "unknown key"},
+		{"balance.hit.base_chance", "high", "is not an integer"},
+		{"ui.style.panel_alpha", "300", "between 0 and 255"},
+		{"balance.damage.critical_multiplier", "x", "is not a number"},
+		{"ui.message.hold_to_fast_forward", "maybe", "is not true or false"},
+		{"ui.colors.team1", "red", "theme file"},
+		{"balance.hit", "1", "group of settings"},
+	}
+	for _, key := range derivedConfigKeys {
+		tests = append(tests, struct{ key, value, want string }{key, "1", "derived from screen size"})
+	}
+	tests = append(tests, struct{ key, value, want string }{"UI.InfoPanel.BlockWidth", "1", "derived from screen size"})
+
+	for _, tt := range tests {
+		config := LoadConfig()
+		err := setConfigValue(&config, tt.key, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("setConfigValue(%s, %s) = %v, want an error containing %q", tt.key, tt.value, err, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	config := LoadConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("default config is invalid: %v", err)
+	}
+
+	config.Balance.Hit.BaseChance = 101
+	config.Balance.Time.OverallTimeDivisor = 0
+	config.AI.Team1Profile = "reckless"
+	config.AI.Profiles.Cautious.SelfRiskWeight = -1
+	config.UI.Style.FontSize = 64
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Validate accepted an invalid config")
+	}
+	for _, key := range []string{
+		"balance.hit.base_chance", "balance.time.overall_time_divisor", "ai.team1_profile", "ai.profiles.cautious", "ui.style.font_size",
+	} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Validate did not report %s:\n%v", key, err)
+		}
+	}
+	if strings.Contains(err.Error(), "ai.team2_profile") {
+		t.Errorf("Validate reported a valid key:\n%v", err)
+	}
+}
+
+func TestApplyConfigOverridesOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	file := `{"balance": {"hit": {"base_chance": 60, "trait_aim_bonus": 10}}, "ui": {"screen": {"width": 1280}}}`
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(configPathEnv, "")
+	t.Setenv(configSetEnv, "balance.hit.base_chance=65, balance.hit.trait_strike_bonus=5")
+
+	config := LoadConfig()
+	if err := ApplyConfigOverrides(&config, path, []string{"balance.hit.base_chance=70"}); err != nil {
+		t.Fatalf("ApplyConfigOverrides: %v", err)
+	}
+	hit := config.Balance.Hit
+	if hit.BaseChance != 70 || hit.TraitAimBonus != 10 || hit.TraitStrikeBonus != 5 {
+		t.Errorf("hit = %+v, want base_chance from --set, trait_aim_bonus from the file and trait_strike_bonus from %s", hit, configSetEnv)
+	}
+	// 画面の幅を変えたら、配置は計算し直される
+	if config.UI.Battlefield.Team2HomeX != 1280-100 {
+		t.Errorf("team2_home_x = %g, want it recomputed for the 1280 wide screen", config.UI.Battlefield.Team2HomeX)
+	}
+
+	config = LoadConfig()
+	err := ApplyConfigOverrides(&config, "", []string{"ui.info_panel.columns=3", "balance.hit.base_chance=200"})
+	if err == nil || !strings.Contains(err.Error(), "derived from screen size") {
+		t.Errorf("ApplyConfigOverrides with a derived key = %v", err)
+	}
+}
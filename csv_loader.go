@@ -1,10 +1,8 @@
 package main
 
 import (
-	"encoding/csv"
+	"errors"
 	"fmt"
-	"io"
-	"os"
 	"strconv"
 	"strings"
 )
@@ -22,137 +20,226 @@ func parseInt(s string) int {
 	return i
 }
 
-// LoadMedals は変更ありません
-func LoadMedals(filePath string) ([]Medal, error) {
-	// (元のコードをそのままコピー)
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open medal csv file %s: %w", filePath, err)
-	}
-	defer file.Close()
-	reader := csv.NewReader(file)
-	reader.TrimLeadingSpace = true
-	headers, err := reader.Read()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read headers from medal csv %s: %w", filePath, err)
+// 列挙型として受け付ける値です。
+var (
+	validPartTypes        = map[PartType]bool{PartTypeHead: true, PartTypeRArm: true, PartTypeLArm: true, PartTypeLegs: true}
+	validActionCategories = map[ActionCategory]bool{CategoryShoot: true, CategoryFight: true, CategoryNone: true}
+	validActionTraits     = map[ActionTrait]bool{TraitNormal: true, TraitAim: true, TraitStrike: true, TraitBerserk: true, TraitNone: true}
+)
+
+// recordReader は1件のレコードから列名で値を取り出します。
+// 読み取りに失敗した項目は、ファイル・行・列を添えてerrsに追加します。
+type recordReader struct {
+	record dataRecord
+	errs   []error
+}
+
+func (r *recordReader) fail(column, format string, args ...any) {
+	source := r.record[column].Source
+	if source == "" {
+		source = r.record["id"].Source
 	}
-	var medals []Medal
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			continue
-		}
-		if len(record) < len(headers) {
-			continue
-		}
-		data := make(map[string]string)
-		for i, header := range headers {
-			normalizedHeader := strings.ToLower(strings.TrimSpace(header))
-			data[normalizedHeader] = record[i]
-		}
-		if data["id"] == "" {
-			continue
-		}
-		medal := Medal{
-			ID:           data["id"],
-			Name:         data["name_jp"],
-			Personality:  data["personality_jp"],
-			Medaforce:    data["medaforce_jp"],
-			Attribute:    data["attribute_jp"],
-			SkillShoot:   parseInt(data["skill_shoot"]),
-			SkillFight:   parseInt(data["skill_fight"]),
-			SkillScan:    parseInt(data["skill_scan"]),
-			SkillSupport: parseInt(data["skill_support"]),
-		}
-		medals = append(medals, medal)
+	r.errs = append(r.errs, &dataError{Source: source, Message: fmt.Sprintf("column %s: %s", column, fmt.Sprintf(format, args...))})
+}
+
+func (r *recordReader) str(column string) string {
+	if _, ok := r.record[column]; !ok {
+		r.fail(column, "missing (id %s)", r.record.str("id"))
+		return ""
 	}
-	return medals, nil
+	return r.record.str(column)
 }
 
-// ★★★ [変更点] 部位別LoadPartsを廃止し、単一のLoadAllPartsを新設 ★★★
-func LoadAllParts(filePath string) (map[string]*Part, error) {
-	file, err := os.Open(filePath)
+func (r *recordReader) int(column string) int {
+	value := r.str(column)
+	i, err := strconv.Atoi(value)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open parts csv file %s: %w", filePath, err)
+		r.fail(column, "%q is not a number", value)
 	}
-	defer file.Close()
+	return i
+}
 
-	reader := csv.NewReader(file)
-	// ヘッダー行を読み飛ばす
-	if _, err := reader.Read(); err != nil {
-		return nil, fmt.Errorf("failed to read header row: %w", err)
+func (r *recordReader) optionalInt(column string) OptionalInt {
+	if r.str(column) == "NONE" {
+		return OptionalInt{}
 	}
+	return SomeInt(r.int(column))
+}
 
-	partsMap := make(map[string]*Part)
+// buildPart はレコードからパーツを作ります。
+// 数値でない値や未知の部位・区分・特性は、行と列を示すエラーとしてすべて報告します。
+func buildPart(record dataRecord) (*Part, []error) {
+	row := &recordReader{record: record}
+	armor := row.int("armor")
+	part := &Part{
+		ID:         row.str("id"),
+		PartName:   row.str("part_name"),
+		Type:       PartType(row.str("part_type")),
+		Category:   ActionCategory(row.str("action_category")),
+		Trait:      ActionTrait(row.str("action_trait")),
+		WeaponType: row.str("weapon_type"),
+		Armor:      armor,
+		MaxArmor:   armor, // Max値も初期化
+		Power:      row.optionalInt("power"),
+		Charge:     row.optionalInt("charge"),
+		Cooldown:   row.optionalInt("cooldown"),
+		Defense:    row.int("defense"),
+		Accuracy:   row.int("accuracy"),
+		Mobility:   row.optionalInt("mobility"),
+		Propulsion: row.optionalInt("propulsion"),
+		IsBroken:   false,
+
+		NameText:       localizedColumns(record, "part_name", ""),
+		WeaponTypeText: localizedColumns(record, "weapon_type", ""),
+	}
+	if !validPartTypes[part.Type] {
+		row.fail("part_type", "unknown part type %q", part.Type)
+	}
+	if !validActionCategories[part.Category] {
+		row.fail("action_category", "unknown action category %q", part.Category)
+	}
+	if !validActionTraits[part.Trait] {
+		row.fail("action_trait", "unknown action trait %q", part.Trait)
+	}
+	return part, row.errs
+}
 
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to read record: %w", err)
-		}
+// buildMedal はレコードからメダルを作ります。技能値は従来どおり、数値でなければ0として読みます。
+func buildMedal(record dataRecord) Medal {
+	return Medal{
+		ID:           record.str("id"),
+		Name:         record.str("name_jp"),
+		Personality:  record.str("personality_jp"),
+		Medaforce:    record.str("medaforce_jp"),
+		Attribute:    record.str("attribute_jp"),
+		SkillShoot:   parseInt(record.str("skill_shoot")),
+		SkillFight:   parseInt(record.str("skill_fight")),
+		SkillScan:    parseInt(record.str("skill_scan")),
+		SkillSupport: parseInt(record.str("skill_support")),
+
+		NameText:        localizedColumns(record, "name", "jp"),
+		PersonalityText: localizedColumns(record, "personality", "jp"),
+		MedaforceText:   localizedColumns(record, "medaforce", "jp"),
+		AttributeText:   localizedColumns(record, "attribute", "jp"),
+	}
+}
 
-		// CSVの各列を構造体にマッピング
-		armor := parseInt(record[6])
-		part := &Part{
-			ID:         record[0],
-			PartName:   record[1],
-			Type:       PartType(record[2]),
-			Category:   ActionCategory(record[3]),
-			Trait:      ActionTrait(record[4]),
-			WeaponType: record[5],
-			Armor:      armor,
-			MaxArmor:   armor, // Max値も初期化
-			Power:      parseInt(record[7]),
-			Charge:     parseInt(record[8]),
-			Cooldown:   parseInt(record[9]),
-			Defense:    parseInt(record[10]),
-			Accuracy:   parseInt(record[11]),
-			Mobility:   parseInt(record[12]),
-			Propulsion: parseInt(record[13]),
-			IsBroken:   false,
+// buildLoadout はレコードからパーツの組み合わせを作り、存在しないパーツを参照していればエラーを返します。
+func buildLoadout(record dataRecord, parts map[string]*Part) (Loadout, []error) {
+	row := &recordReader{record: record}
+	loadout := Loadout{
+		ID:       row.str("id"),
+		Name:     record.str("name"),
+		Head:     row.str("head"),
+		RightArm: row.str("right_arm"),
+		LeftArm:  row.str("left_arm"),
+		Legs:     row.str("legs"),
+	}
+	for column, partID := range map[string]string{"head": loadout.Head, "right_arm": loadout.RightArm, "left_arm": loadout.LeftArm, "legs": loadout.Legs} {
+		if _, ok := parts[partID]; !ok && partID != "" {
+			row.fail(column, "unknown part %q", partID)
 		}
-
-		partsMap[part.ID] = part
 	}
+	return loadout, row.errs
+}
 
-	if len(partsMap) == 0 {
-		return nil, fmt.Errorf("no parts loaded from %s", filePath)
+// buildStage はレコードからステージを作り、存在しない編成を参照していればエラーを返します。
+func buildStage(record dataRecord, loadouts map[string]bool) (Stage, []error) {
+	row := &recordReader{record: record}
+	stage := Stage{
+		ID:    row.str("id"),
+		Name:  record.str("name"),
+		Team1: splitList(row.str("team1")),
+		Team2: splitList(row.str("team2")),
+	}
+	for column, ids := range map[string][]string{"team1": stage.Team1, "team2": stage.Team2} {
+		if len(ids) == 0 {
+			row.fail(column, "no loadouts")
+		}
+		for _, id := range ids {
+			if !loadouts[id] {
+				row.fail(column, "unknown loadout %q", id)
+			}
+		}
 	}
-	return partsMap, nil
+	return stage, row.errs
 }
 
 // ★★★ [変更点] GameData構造体とLoadAllGameDataをシンプルに ★★★
 type GameData struct {
-	Medals   []Medal
-	AllParts map[string]*Part // 全てのパーツをIDをキーにして保持
+	Medals     []Medal
+	AllParts   map[string]*Part // 全てのパーツをIDをキーにして保持
+	Loadouts   []Loadout        // ランダム編成に使うパーツの組み合わせ（データの順番どおり）
+	Stages     []Stage
+	Stage      *Stage           // 選択中のステージ（nilならランダム編成）
+	Sprites    *SpriteAtlas     // パーツのスプライト（読み込めなかった場合はnilで、円で描画する）
+	Packs      []DataPack       // 読み込んだデータパック（再読み込みに使う）
+	Provenance []DataProvenance // 各レコードを提供したパック
 }
 
-func LoadAllGameData() (*GameData, error) {
-	var err error
-	gameData := &GameData{}
-
-	gameData.Medals, err = LoadMedals("medals.csv")
+// LoadAllGameData はデータパックを順に重ねてゲームデータを読み込みます。
+// 先頭が基本データで、後のパック（MOD）ほど優先されます。
+func LoadAllGameData(packs []DataPack) (*GameData, error) {
+	tables, err := loadDataTables(packs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load medals: %w", err)
+		return nil, err
 	}
+	gameData := &GameData{AllParts: make(map[string]*Part), Packs: packs, Provenance: provenance(tables)}
 
-	gameData.AllParts, err = LoadAllParts("parts.csv") // 修正したparts.csvを読み込む
-	if err != nil {
-		return nil, fmt.Errorf("failed to load parts: %w", err)
+	var errs []error
+	for _, record := range tables[dataKindParts].records {
+		part, partErrs := buildPart(record)
+		errs = append(errs, partErrs...)
+		gameData.AllParts[part.ID] = part
+	}
+	for _, record := range tables[dataKindMedals].records {
+		gameData.Medals = append(gameData.Medals, buildMedal(record))
+	}
+	loadoutIDs := make(map[string]bool)
+	for _, record := range tables[dataKindLoadouts].records {
+		loadout, loadoutErrs := buildLoadout(record, gameData.AllParts)
+		errs = append(errs, loadoutErrs...)
+		gameData.Loadouts = append(gameData.Loadouts, loadout)
+		loadoutIDs[loadout.ID] = true
+	}
+	for _, record := range tables[dataKindStages].records {
+		stage, stageErrs := buildStage(record, loadoutIDs)
+		errs = append(errs, stageErrs...)
+		gameData.Stages = append(gameData.Stages, stage)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
 
+	if len(gameData.AllParts) == 0 {
+		return nil, fmt.Errorf("no parts were loaded")
+	}
+	if len(gameData.Loadouts) == 0 {
+		return nil, fmt.Errorf("no loadouts were loaded")
+	}
 	if len(gameData.Medals) == 0 {
 		fmt.Println("Warning: No medals were loaded.")
 	}
-	if len(gameData.AllParts) == 0 {
-		fmt.Println("Warning: No parts were loaded.")
+	return gameData, nil
+}
+
+// SelectStage はIDでステージを選びます。
+func (d *GameData) SelectStage(id string) error {
+	for i := range d.Stages {
+		if d.Stages[i].ID == id {
+			d.Stage = &d.Stages[i]
+			return nil
+		}
 	}
+	return fmt.Errorf("unknown stage %q", id)
+}
 
-	return gameData, nil
+// findLoadout はIDでパーツの組み合わせを探します。
+func (d *GameData) findLoadout(id string) (Loadout, bool) {
+	for _, loadout := range d.Loadouts {
+		if loadout.ID == id {
+			return loadout, true
+		}
+	}
+	return Loadout{}, false
 }
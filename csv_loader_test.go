@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testPartsHeader = "id,part_name,part_type,action_category,action_trait,weapon_type,armor,power,charge,cooldown,defense,accuracy,mobility,propulsion\n"
+
+// buildTestParts はparts.csvの行を読み、1行ずつbuildPartに渡します。
+func buildTestParts(t *testing.T, rows string) ([]*Part, []error) {
+	t.Helper()
+	records, err := readCSVRecords(strings.NewReader(testPartsHeader+rows), "test/parts.csv")
+	if err != nil {
+		t.Fatalf("readCSVRecords: %v", err)
+	}
+	var parts []*Part
+	var errs []error
+	for _, record := range records {
+		part, partErrs := buildPart(record)
+		parts = append(parts, part)
+		errs = append(errs, partErrs...)
+	}
+	return parts, errs
+}
+
+func TestBuildPartReadsNoneAsNoValue(t *testing.T) {
+	parts, errs := buildTestParts(t, ""+
+		"H-001,ヘッド,HEAD,SHOOT,AIM,ライフル,40,25,30,20,5,40,NONE,NONE\n"+
+		"L-001,レッグ,LEG,NONE,NONE,二脚,50,NONE,NONE,NONE,10,0,30,25\n")
+	if len(errs) > 0 {
+		t.Fatalf("buildPart: %v", errors.Join(errs...))
+	}
+
+	head, legs := parts[0], parts[1]
+	if head.Power != SomeInt(25) || head.Charge != SomeInt(30) || head.Cooldown != SomeInt(20) {
+		t.Errorf("head power/charge/cooldown = %v/%v/%v, want 25/30/20", head.Power, head.Charge, head.Cooldown)
+	}
+	if head.Mobility.Valid || head.Propulsion.Valid {
+		t.Errorf("head mobility/propulsion = %+v/%+v, want NONE", head.Mobility, head.Propulsion)
+	}
+	if head.Armor != 40 || head.MaxArmor != 40 || head.Defense != 5 || head.Accuracy != 40 {
+		t.Errorf("head = %+v", head)
+	}
+	if legs.Power.Valid || legs.Charge.Valid || legs.Cooldown.Valid {
+		t.Errorf("legs power/charge/cooldown = %+v/%+v/%+v, want NONE", legs.Power, legs.Charge, legs.Cooldown)
+	}
+	if legs.Mobility != SomeInt(30) || legs.Propulsion != SomeInt(25) {
+		t.Errorf("legs mobility/propulsion = %v/%v, want 30/25", legs.Mobility, legs.Propulsion)
+	}
+	// 計算ではNONEを0として扱い、表示では"-"にする
+	if legs.Power.Int() != 0 || legs.Power.String() != "-" {
+		t.Errorf("NONE power reads as %d and shows as %q", legs.Power.Int(), legs.Power.String())
+	}
+}
+
+func TestBuildPartReportsEveryBadValue(t *testing.T) {
+	_, errs := buildTestParts(t, ""+
+		"H-001,ヘッド,HEAD,SHOOT,AIM,ライフル,NONE,25,30,20,NONE,NONE,NONE,NONE\n"+
+		"H-002,ヘッド,HED,SHOT,AIMED,ライフル,40,x,30,20,5,40,NONE,7.5\n")
+	got := map[string]bool{}
+	for _, err := range errs {
+		got[err.Error()] = true
+	}
+	for _, want := range []string{
+		`test/parts.csv:2: column armor: "NONE" is not a number`,
+		`test/parts.csv:2: column defense: "NONE" is not a number`,
+		`test/parts.csv:2: column accuracy: "NONE" is not a number`,
+		`test/parts.csv:3: column power: "x" is not a number`,
+		`test/parts.csv:3: column propulsion: "7.5" is not a number`,
+		`test/parts.csv:3: column part_type: unknown part type "HED"`,
+		`test/parts.csv:3: column action_category: unknown action category "SHOT"`,
+		`test/parts.csv:3: column action_trait: unknown action trait "AIMED"`,
+	} {
+		if !got[want] {
+			t.Errorf("missing error %q", want)
+		}
+	}
+	if len(errs) != 8 {
+		t.Errorf("got %d errors, want 8: %v", len(errs), errors.Join(errs...))
+	}
+}
+
+func TestBuildPartReportsMissingColumns(t *testing.T) {
+	records, err := readCSVRecords(strings.NewReader("id,part_name,armor\nH-001,ヘッド,40\n"), "test/parts.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, errs := buildPart(records[0])
+	joined := errors.Join(errs...).Error()
+	for _, column := range []string{"part_type", "charge", "mobility"} {
+		if want := "column " + column + ": missing (id H-001)"; !strings.Contains(joined, want) {
+			t.Errorf("missing %q in:\n%s", want, joined)
+		}
+	}
+}
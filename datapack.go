@@ -0,0 +1,346 @@
+package main
+
+import (
+	"embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// データパックに含められるデータの種類です。ファイル名は種類名に拡張子(.csv/.json/.yaml/.yml)を付けたものです。
+const (
+	dataKindParts    = "parts"
+	dataKindMedals   = "medals"
+	dataKindLoadouts = "loadouts"
+	dataKindStages   = "stages"
+)
+
+var dataKinds = []string{dataKindParts, dataKindMedals, dataKindLoadouts, dataKindStages}
+
+var dataFileExtensions = []string{".csv", ".json", ".yaml", ".yml"}
+
+// DataPack はゲームデータを提供するディレクトリ（基本データまたはMOD）です。
+// 後に読み込まれたパックは、新しいIDを追加したり、既存のIDの項目を上書きしたりできます。
+type DataPack struct {
+	Name string
+	FS   fs.FS
+	Dir  string // OS上のディレクトリ（ファイル監視に使う）。埋め込みデータの場合は空
+}
+
+// embeddedData は実行ファイルに埋め込んだ基本データです。作業ディレクトリに関係なく起動できるようにします。
+//
+//go:embed parts.csv medals.csv loadouts.csv stages.csv
+var embeddedData embed.FS
+
+// EmbeddedDataPack は埋め込みの基本データをデータパックとして返します。
+func EmbeddedDataPack() DataPack {
+	return FSDataPack("base", embeddedData)
+}
+
+// FSDataPack は任意のfs.FSをデータパックとして扱います（テスト用のデータなど）。ファイルの監視はできません。
+func FSDataPack(name string, fsys fs.FS) DataPack {
+	return DataPack{Name: name, FS: fsys}
+}
+
+// DirDataPack はディレクトリをデータパックとして開きます。
+func DirDataPack(name, dir string) DataPack {
+	return DataPack{Name: name, FS: os.DirFS(dir), Dir: dir}
+}
+
+// DefaultDataPacks は読み込むデータパックを優先度の低い順に返します。
+// 埋め込みの基本データ、ユーザーデータディレクトリ（あれば）、dataDir（空でなければ）、modsDir以下のMOD（名前順）、modDirsの順です。
+// ユーザーデータディレクトリと dataDir には、置き換えたいファイルや項目だけを置けば十分です。
+func DefaultDataPacks(dataDir, modsDir string, modDirs []string) []DataPack {
+	packs := []DataPack{EmbeddedDataPack()}
+	if dir, err := UserDataDir(); err == nil {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			packs = append(packs, DirDataPack("user", dir))
+		}
+	}
+	if dataDir != "" {
+		packs = append(packs, DirDataPack("data-dir", dataDir))
+	}
+	packs = append(packs, ModDataPacks(modsDir)...)
+	for _, dir := range modDirs {
+		packs = append(packs, DirDataPack(filepath.Base(dir), dir))
+	}
+	return packs
+}
+
+// UserDataDir はユーザー設定ディレクトリ内の、基本データを上書きするディレクトリのパスを返します。
+func UserDataDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "medarot-ebiten", "data"), nil
+}
+
+// ModDataPacks はmodsDir直下の各ディレクトリを、名前順にデータパックとして返します。ディレクトリがなければ空です。
+func ModDataPacks(modsDir string) []DataPack {
+	entries, err := os.ReadDir(modsDir)
+	if err != nil {
+		return nil
+	}
+	var packs []DataPack
+	for _, entry := range entries {
+		if entry.IsDir() {
+			packs = append(packs, DirDataPack(entry.Name(), filepath.Join(modsDir, entry.Name())))
+		}
+	}
+	return packs
+}
+
+// ModDirFlags は --mod dir を複数回受け取るためのflag.Valueです。
+type ModDirFlags []string
+
+func (m *ModDirFlags) String() string { return strings.Join(*m, ",") }
+
+func (m *ModDirFlags) Set(v string) error {
+	*m = append(*m, v)
+	return nil
+}
+
+// dataField はレコードの1項目と、その値がどこから来たか（エラー表示用）です。
+type dataField struct {
+	Value  string
+	Source string // "パック名/ファイル名:行" など
+}
+
+// dataError はデータの1件についての誤りです。lintで出所（ファイル・行）ごとに並べられるよう、出所と内容を分けて持ちます。
+type dataError struct {
+	Source  string
+	Message string
+}
+
+func (e *dataError) Error() string {
+	return e.Source + ": " + e.Message
+}
+
+// dataRecord はIDを持つ1件のデータです。キーは小文字の列名です。
+type dataRecord map[string]dataField
+
+// str は列の値を返します。列がなければ空文字です。
+func (r dataRecord) str(column string) string {
+	return strings.TrimSpace(r[column].Value)
+}
+
+// dataTable はすべてのパックを重ねた後の、1種類分のレコードです。
+type dataTable struct {
+	records []dataRecord        // 最初に現れた順
+	index   map[string]int      // IDからrecordsの添字
+	packs   map[string][]string // IDごとに値を提供したパックの名前（読み込み順）
+}
+
+// DataProvenance は1件のレコードがどのパックから来たかを表します。
+type DataProvenance struct {
+	Kind  string
+	ID    string
+	Packs []string // 最初が定義したパック、以降が上書きしたパック
+}
+
+// loadDataTables は全パックの全種類のファイルを読み込み、IDごとに重ねます。
+func loadDataTables(packs []DataPack) (map[string]*dataTable, error) {
+	tables := make(map[string]*dataTable)
+	for _, kind := range dataKinds {
+		tables[kind] = &dataTable{index: make(map[string]int), packs: make(map[string][]string)}
+	}
+	var errs []error
+	for _, pack := range packs {
+		for _, kind := range dataKinds {
+			records, err := readPackRecords(pack, kind)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			table := tables[kind]
+			seen := make(map[string]bool) // 同じファイル内での重複は上書きではなく誤りとする
+			for _, record := range records {
+				id := record.str("id")
+				if id == "" {
+					errs = append(errs, &dataError{Source: record["id"].Source, Message: "record has no id"})
+					continue
+				}
+				if seen[id] {
+					errs = append(errs, &dataError{Source: record["id"].Source, Message: fmt.Sprintf("duplicate id %q", id)})
+					continue
+				}
+				seen[id] = true
+				table.packs[id] = append(table.packs[id], pack.Name)
+				if i, ok := table.index[id]; ok {
+					for column, field := range record {
+						table.records[i][column] = field
+					}
+					continue
+				}
+				table.index[id] = len(table.records)
+				table.records = append(table.records, record)
+			}
+		}
+	}
+	return tables, errors.Join(errs...)
+}
+
+// packDataFile はパック内のその種類のファイル名を返します。なければ空文字です。
+func packDataFile(pack DataPack, kind string) (string, error) {
+	var found []string
+	for _, ext := range dataFileExtensions {
+		if _, err := fs.Stat(pack.FS, kind+ext); err == nil {
+			found = append(found, kind+ext)
+		}
+	}
+	if len(found) > 1 {
+		return "", fmt.Errorf("%s: more than one %s file (%s)", pack.Name, kind, strings.Join(found, ", "))
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	return found[0], nil
+}
+
+// readPackRecords はパックからその種類のレコードを読み込みます。ファイルがなければ空です。
+func readPackRecords(pack DataPack, kind string) ([]dataRecord, error) {
+	file, err := packDataFile(pack, kind)
+	if err != nil || file == "" {
+		return nil, err
+	}
+	f, err := pack.FS.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s: %w", pack.Name, file, err)
+	}
+	defer f.Close()
+	source := pack.Name + "/" + file
+	if strings.HasSuffix(file, ".csv") {
+		return readCSVRecords(f, source)
+	}
+	var items []map[string]any
+	if strings.HasSuffix(file, ".json") {
+		decoder := json.NewDecoder(f)
+		decoder.UseNumber()
+		err = decoder.Decode(&items)
+	} else {
+		err = yaml.NewDecoder(f).Decode(&items)
+	}
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	records := make([]dataRecord, 0, len(items))
+	for i, item := range items {
+		record := make(dataRecord, len(item))
+		for key, value := range item {
+			text, err := dataValueString(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: record %d: %s: %w", source, i+1, key, err)
+			}
+			record[strings.ToLower(key)] = dataField{Value: text, Source: fmt.Sprintf("%s: record %d", source, i+1)}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// readCSVRecords はヘッダー付きのCSVをレコードに変換します。
+func readCSVRecords(r io.Reader, source string) ([]dataRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read header row: %w", source, err)
+	}
+	var records []dataRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		line, _ := reader.FieldPos(0)
+		record := make(dataRecord, len(header))
+		for i, name := range header {
+			record[strings.ToLower(strings.TrimSpace(name))] = dataField{Value: row[i], Source: fmt.Sprintf("%s:%d", source, line)}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// dataValueString はJSON/YAMLの値をCSVのセルと同じ文字列表現に変換します。リストは";"で区切ります。
+func dataValueString(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := dataValueString(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ";"), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+}
+
+// splitList は";"区切りの値を分割します。
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// provenance は全種類のレコードの提供元を、種類・IDの順に並べて返します。
+func provenance(tables map[string]*dataTable) []DataProvenance {
+	var report []DataProvenance
+	for _, kind := range dataKinds {
+		table := tables[kind]
+		ids := make([]string, 0, len(table.packs))
+		for id := range table.packs {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			report = append(report, DataProvenance{Kind: kind, ID: id, Packs: table.packs[id]})
+		}
+	}
+	return report
+}
+
+// WriteDataReport は各レコードを提供したパックの一覧を書き出します。
+func WriteDataReport(w io.Writer, gameData *GameData) {
+	for _, p := range gameData.Provenance {
+		line := fmt.Sprintf("%-8s %-10s %s", p.Kind, p.ID, p.Packs[0])
+		if len(p.Packs) > 1 {
+			line += " (overridden by " + strings.Join(p.Packs[1:], ", ") + ")"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadAllGameDataLayersPacksInOrder(t *testing.T) {
+	mod1 := fstest.MapFS{
+		// 装甲だけを置き換え、残りの列は基本データのものを使う
+		"parts.csv": {Data: []byte("id,armor\nH-001,60\n")},
+	}
+	mod2 := fstest.MapFS{
+		"parts.json": {Data: []byte(`[
+			{"id": "H-001", "armor": 70, "part_name": "ヘッドマグナム改"},
+			{"id": "H-900", "part_name": "ヘッドテスト", "part_type": "HEAD", "action_category": "SHOOT", "action_trait": "NORMAL",
+			 "weapon_type": "テスト", "armor": 10, "power": 20, "charge": 30, "cooldown": 40, "defense": 5, "accuracy": 60,
+			 "mobility": "NONE", "propulsion": "NONE"}
+		]`)},
+		"medals.yaml": {Data: []byte("- id: M001\n  skill_shoot: 99\n")},
+	}
+	gameData, err := LoadAllGameData([]DataPack{EmbeddedDataPack(), FSDataPack("mod1", mod1), FSDataPack("mod2", mod2)})
+	if err != nil {
+		t.Fatalf("LoadAllGameData: %v", err)
+	}
+
+	head := gameData.AllParts["H-001"]
+	if head.Armor != 70 || head.MaxArmor != 70 {
+		t.Errorf("H-001 armor = %d/%d, want 70/70 from the last pack", head.Armor, head.MaxArmor)
+	}
+	if head.PartName != "ヘッドマグナム改" {
+		t.Errorf("H-001 name = %q, want the override from mod2", head.PartName)
+	}
+	if head.Power != SomeInt(100) || head.Charge != SomeInt(75) || head.Mobility.Valid {
+		t.Errorf("H-001 kept power %v, charge %v, mobility %v; want the base values 100, 75, NONE", head.Power, head.Charge, head.Mobility)
+	}
+	if added := gameData.AllParts["H-900"]; added == nil || added.Charge != SomeInt(30) {
+		t.Errorf("H-900 from mod2 = %+v", added)
+	}
+	if medal := gameData.Medals[0]; medal.ID != "M001" || medal.SkillShoot != 99 || medal.SkillFight != 5 {
+		t.Errorf("M001 = %+v, want skill_shoot 99 from mod2 and skill_fight 5 from the base", medal)
+	}
+
+	for _, p := range gameData.Provenance {
+		if p.Kind == dataKindParts && p.ID == "H-001" && !slices.Equal(p.Packs, []string{"base", "mod1", "mod2"}) {
+			t.Errorf("H-001 provenance = %v, want base, mod1, mod2", p.Packs)
+		}
+	}
+}
+
+func TestLoadAllGameDataRejectsBrokenPacks(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{"duplicate in one file", fstest.MapFS{"parts.csv": {Data: []byte("id,armor\nH-001,60\nH-001,70\n")}}, `mod/parts.csv:3: duplicate id "H-001"`},
+		{"two files of a kind", fstest.MapFS{"parts.csv": {Data: []byte("id\n")}, "parts.yaml": {Data: []byte("[]")}}, "more than one parts file"},
+		{"NONE in a required column", fstest.MapFS{"parts.csv": {Data: []byte("id,defense\nH-001,NONE\n")}}, `mod/parts.csv:2: column defense: "NONE" is not a number`},
+		{"new part without columns", fstest.MapFS{"parts.csv": {Data: []byte("id,armor\nH-901,10\n")}}, "column part_name: missing (id H-901)"},
+	}
+	for _, tt := range tests {
+		_, err := LoadAllGameData([]DataPack{EmbeddedDataPack(), FSDataPack("mod", tt.fsys)})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want it to contain %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/text/language"
+)
+
+// FontStyle は用途ごとの文字の種類です。サイズは本文のサイズ（テーマのfont_size）に対する倍率で決まります。
+type FontStyle int
+
+const (
+	FontBody    FontStyle = iota // 本文（情報パネル・ボタン・メッセージ）
+	FontTitle                    // 見出し（ポーズ・行動選択のタイトル、勝敗）
+	FontSmall                    // 補足（ツールチップ・操作ガイド・プロンプト）
+	FontNumbers                  // 数値（ダメージ表示）。数字は等幅のフォントを優先する
+)
+
+// fontStyleScales は本文のサイズに対する各スタイルの倍率です。
+var fontStyleScales = map[FontStyle]float64{
+	FontBody:    1,
+	FontTitle:   1.6,
+	FontSmall:   0.9,
+	FontNumbers: 1.4,
+}
+
+// fontLayoutKey は測った文字列の幅をキャッシュするためのキーです。
+type fontLayoutKey struct {
+	style FontStyle
+	text  string
+}
+
+// maxCachedLayouts を超えたら幅のキャッシュを捨てます（毎フレーム変わる文字列で際限なく増えないように）。
+const maxCachedLayouts = 4096
+
+// FontManager は用途ごとのフォントを管理します。
+// 各スタイルは、言語ごとのフォント、M+、予備のフォントの順に字形を探す MultiFace です（M+にない字形は予備のフォントで描く）。
+type FontManager struct {
+	primary   *text.GoTextFaceSource              // M+
+	fallbacks []*text.GoTextFaceSource            // M+にない字形に使うフォント
+	numbers   *text.GoTextFaceSource              // FontNumbersで優先する等幅フォント
+	locales   map[string][]*text.GoTextFaceSource // 言語ごとに優先するフォント
+
+	baseSize float64
+	scale    float64 // 描画先のピクセル数の倍率（deviceScale）。facesはこの倍率の大きさで作る
+	language string  // facesを作ったときの言語
+	faces    map[FontStyle]text.Face
+	widths   map[fontLayoutKey]float64
+}
+
+// Fonts はゲーム全体で使うフォントです。描画するコードはどこからでも参照します。
+var Fonts *FontManager
+
+// NewFontManager は埋め込みのM+と、予備のGoフォントでフォントを準備します。英語ではGoフォントを優先します。
+func NewFontManager(baseSize float64) (*FontManager, error) {
+	primary, err := text.NewGoTextFaceSource(bytes.NewReader(mplusFontData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse M+ font: %w", err)
+	}
+	regular, err := text.NewGoTextFaceSource(bytes.NewReader(goregular.TTF))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Go Regular font: %w", err)
+	}
+	mono, err := text.NewGoTextFaceSource(bytes.NewReader(gomono.TTF))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Go Mono font: %w", err)
+	}
+	return &FontManager{
+		primary:   primary,
+		fallbacks: []*text.GoTextFaceSource{regular},
+		numbers:   mono,
+		locales:   map[string][]*text.GoTextFaceSource{"en": {regular}},
+		baseSize:  baseSize,
+		scale:     1,
+	}, nil
+}
+
+// LoadFontDir はdir直下の *.ttf・*.otf を予備のフォントに、dir/<言語>/ 以下のものをその言語で優先するフォントに追加します。
+// ディレクトリがなければ何もしません。
+func (m *FontManager) LoadFontDir(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			lang := entry.Name()
+			sources, err := loadFontFiles(fsys, path.Join(dir, lang))
+			if err != nil {
+				return err
+			}
+			m.locales[lang] = append(sources, m.locales[lang]...)
+		}
+	}
+	sources, err := loadFontFiles(fsys, dir)
+	if err != nil {
+		return err
+	}
+	m.fallbacks = append(sources, m.fallbacks...)
+	m.faces = nil
+	return nil
+}
+
+// loadFontFiles はdir直下のフォントファイルを名前順に読み込みます。
+func loadFontFiles(fsys fs.FS, dir string) ([]*text.GoTextFaceSource, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var sources []*text.GoTextFaceSource
+	for _, entry := range entries {
+		name := strings.ToLower(entry.Name())
+		if entry.IsDir() || !(strings.HasSuffix(name, ".ttf") || strings.HasSuffix(name, ".otf")) {
+			continue
+		}
+		file := path.Join(dir, entry.Name())
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		source, err := text.NewGoTextFaceSource(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		log.Printf("Loaded font %s", file)
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// SetBaseSize は本文のサイズを変更します。他のスタイルのサイズも倍率に従って変わります。
+func (m *FontManager) SetBaseSize(size float64) {
+	if size == m.baseSize {
+		return
+	}
+	m.baseSize = size
+	m.faces = nil
+}
+
+// SetScale は描画先のピクセル数の倍率を変更します。HiDPIの画面でも文字がぼやけないよう、その解像度でフォントを作り直します。
+// Measure・Ascent・LineHeightは倍率に関わらず論理座標の値を返します。
+func (m *FontManager) SetScale(scale float64) {
+	if scale == m.scale {
+		return
+	}
+	m.scale = scale
+	m.faces = nil
+}
+
+// Face はスタイルのフォントを返します。言語が切り替わっていれば作り直します。
+func (m *FontManager) Face(style FontStyle) text.Face {
+	if m.faces == nil || m.language != CurrentLanguage() {
+		m.buildFaces()
+	}
+	return m.faces[style]
+}
+
+// buildFaces は現在の言語とサイズで全スタイルのフォントを作ります。
+func (m *FontManager) buildFaces() {
+	m.language = CurrentLanguage()
+	m.faces = make(map[FontStyle]text.Face, len(fontStyleScales))
+	m.widths = make(map[fontLayoutKey]float64)
+	tag := language.Make(m.language)
+	for style, scale := range fontStyleScales {
+		var sources []*text.GoTextFaceSource
+		if style == FontNumbers {
+			sources = append(sources, m.numbers)
+		}
+		sources = append(sources, m.locales[m.language]...)
+		sources = append(sources, m.primary)
+		sources = append(sources, m.fallbacks...)
+
+		faces := make([]text.Face, 0, len(sources))
+		seen := make(map[*text.GoTextFaceSource]bool)
+		for _, source := range sources {
+			if seen[source] {
+				continue
+			}
+			seen[source] = true
+			faces = append(faces, &text.GoTextFace{Source: source, Size: m.baseSize * scale * m.scale, Language: tag})
+		}
+		face, err := text.NewMultiFace(faces...)
+		if err != nil {
+			log.Printf("Failed to combine fonts: %v", err)
+			m.faces[style] = faces[0]
+			continue
+		}
+		m.faces[style] = face
+	}
+}
+
+// Measure は文字列を描いたときの幅を返します。結果はフォントが作り直されるまでキャッシュします。
+func (m *FontManager) Measure(style FontStyle, s string) float64 {
+	face := m.Face(style)
+	key := fontLayoutKey{style: style, text: s}
+	if w, ok := m.widths[key]; ok {
+		return w
+	}
+	if len(m.widths) >= maxCachedLayouts {
+		m.widths = make(map[fontLayoutKey]float64)
+	}
+	w, _ := text.Measure(s, face, 0)
+	w /= m.scale
+	m.widths[key] = w
+	return w
+}
+
+// Ascent はベースラインから行の上端までの高さを返します。
+func (m *FontManager) Ascent(style FontStyle) float64 {
+	return m.Face(style).Metrics().HAscent / m.scale
+}
+
+// LineHeight は1行の高さを返します。
+func (m *FontManager) LineHeight(style FontStyle) float64 {
+	metrics := m.Face(style).Metrics()
+	return (metrics.HAscent + metrics.HDescent + metrics.HLineGap) / m.scale
+}
+
+// DrawText は文字列を描画します。yはベースラインの位置です。
+func DrawText(screen *ebiten.Image, s string, style FontStyle, x, y float64, clr color.Color) {
+	if Fonts == nil || s == "" {
+		return
+	}
+	op := &text.DrawOptions{}
+	op.ColorScale.ScaleWithColor(clr)
+	drawTextWithOptions(screen, s, style, x, y, op)
+}
+
+// drawTextWithOptions はopの色の指定などを使って文字列を描画します。x, yは論理座標で、yはベースラインの位置です。
+func drawTextWithOptions(screen *ebiten.Image, s string, style FontStyle, x, y float64, op *text.DrawOptions) {
+	op.GeoM.Translate(x*Fonts.scale, (y-Fonts.Ascent(style))*Fonts.scale)
+	text.Draw(screen, s, Fonts.Face(style), op)
+}
+
+// DrawTextCentered は文字列をxを中心として描画します。yはベースラインの位置です。
+func DrawTextCentered(screen *ebiten.Image, s string, style FontStyle, x, y float64, clr color.Color) {
+	if Fonts == nil || s == "" {
+		return
+	}
+	DrawText(screen, s, style, x-Fonts.Measure(style, s)/2, y, clr)
+}
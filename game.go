@@ -17,6 +17,16 @@ type Game struct {
 	systems        []System
 	renderSystems  []DrawSystem
 	gameStateEntry *donburi.Entry // グローバルな状態を持つシングルトンエンティティへの参照
+	// combatListeners はリスタート後も引き継ぐ戦闘イベントの購読者です。
+	combatListeners []CombatEventListener
+	// hotReload はファイル監視が有効な場合のみ設定されます。リスタート後も引き継ぎます。
+	hotReload *HotReloadSystem
+	// lockstep はネット対戦の場合のみ設定されます。リスタート後も引き継ぎます。
+	lockstep *LockstepSystem
+	// spectator は観戦配信が有効な場合のみ設定されます。リスタート後も引き継ぎます。
+	spectator *SpectatorServer
+	// bots は外部ボットがチームを操作する場合のみ設定されます。リスタート後も引き継ぎます。
+	bots *BotSystem
 }
 
 // System はUpdateメソッドを持つすべてのシステムのインターフェースです。
@@ -34,7 +44,7 @@ func NewGame(gameData *GameData, appConfig Config) *Game {
 	world := donburi.NewWorld()
 	gameECS := ecs.NewECS(world)
 	// --- グローバルな状態を保持するシングルトンエンティティを作成 ---
-	gameStateEntity := world.Create(GameStateComponentType, ConfigComponentType, PlayerActionSelectComponentType)
+	gameStateEntity := world.Create(GameStateComponentType, ConfigComponentType, PlayerActionSelectComponentType, CombatEventComponentType, ScreenShakeComponentType, ReloadBannerComponentType, NetworkStatusComponentType)
 	gameStateEntry := world.Entry(gameStateEntity)
 	// 各グローバルコンポーネントを初期化
 	GameStateComponentType.SetValue(gameStateEntry, GameStateComponent{
@@ -45,8 +55,12 @@ func NewGame(gameData *GameData, appConfig Config) *Game {
 	ConfigComponentType.SetValue(gameStateEntry, ConfigComponent{
 		GameConfig: &appConfig,
 		GameData:   gameData,
+		Settings:   DefaultSettings(),
 	})
 	PlayerActionSelectComponentType.SetValue(gameStateEntry, PlayerActionSelectComponent{})
+	// 演出は戦闘イベントから生成するため、AnimationSystemを最初の購読者として登録する
+	animationSystem := NewAnimationSystem()
+	CombatEventComponentType.SetValue(gameStateEntry, CombatEventComponent{Listeners: []CombatEventListener{animationSystem}})
 	// --- メダロットのエンティティを初期化 ---
 	InitializeAllMedarotEntities(world, gameData)
 	g := &Game{
@@ -62,6 +76,9 @@ func NewGame(gameData *GameData, appConfig Config) *Game {
 	g.AddSystem(NewActionExecutionSystem())
 	g.AddSystem(NewGameRuleSystem())
 	g.AddSystem(NewMessageSystem())
+	g.AddSystem(NewBattleSettingsSystem())
+	g.AddSystem(NewMenuSystem())
+	g.AddSystem(animationSystem)
 	// Drawされるシステム
 	g.AddDrawSystem(NewRenderSystem())
 	log.Println("Game instance created with ECS and systems registered.")
@@ -78,6 +95,77 @@ func (g *Game) AddDrawSystem(system DrawSystem) {
 	g.renderSystems = append(g.renderSystems, system)
 }
 
+// AddCombatEventListener は戦闘イベントの購読者を登録します。登録はリスタート後も維持されます。
+func (g *Game) AddCombatEventListener(l CombatEventListener) {
+	g.combatListeners = append(g.combatListeners, l)
+	bus := CombatEventComponentType.Get(g.gameStateEntry)
+	bus.Listeners = append(bus.Listeners, l)
+}
+
+// SetSettings はプレイヤー設定を差し替えます。設定はリスタート後も引き継がれます。
+// 設定で選ばれている表示言語もここで適用します。
+func (g *Game) SetSettings(settings *Settings) {
+	ConfigComponentType.Get(g.gameStateEntry).Settings = settings
+	if err := applyLanguage(g.World, settings.Language); err != nil {
+		log.Printf("Failed to apply language, using %s: %v", defaultLanguage, err)
+		settings.Language = ""
+		applyLanguage(g.World, defaultLanguage)
+	}
+}
+
+// SetMatch は対戦の形式を設定します。ホットシート対戦ではチーム2をプレイヤー2の操作に切り替えます。
+// 形式はリスタート後も引き継がれます。
+func (g *Game) SetMatch(match MatchOptions) {
+	ConfigComponentType.Get(g.gameStateEntry).Match = match
+	if match.Hotseat {
+		setHotseatControl(g.World)
+	}
+}
+
+// EnableHotReload はデータファイルと設定ファイルの監視を有効にします。
+func (g *Game) EnableHotReload(sys *HotReloadSystem) {
+	g.hotReload = sys
+}
+
+// EnableLockstep はネット対戦を有効にします。チーム2は相手の端末のプレイヤーが操作します。
+func (g *Game) EnableLockstep(sys *LockstepSystem) {
+	g.lockstep = sys
+	sys.attach(g.World)
+}
+
+// EnableBots は外部ボットによるチームの操作を有効にします。
+func (g *Game) EnableBots(sys *BotSystem) {
+	g.bots = sys
+	sys.attach(g.World)
+}
+
+// EnableSpectator は観戦配信を有効にします。戦闘イベントも観戦者へ配信します。
+func (g *Game) EnableSpectator(s *SpectatorServer) {
+	g.spectator = s
+	g.AddCombatEventListener(s)
+}
+
+// SetThemes は選択可能なテーマを設定し、プレイヤー設定で選ばれているテーマを適用します。
+func (g *Game) SetThemes(themes *ThemeSet) {
+	configComp := ConfigComponentType.Get(g.gameStateEntry)
+	configComp.Themes = themes
+	if configComp.Settings == nil {
+		return
+	}
+	if err := themes.Apply(&configComp.GameConfig.UI, configComp.Settings.Theme); err != nil {
+		log.Printf("Failed to apply theme %q, using default: %v", configComp.Settings.Theme, err)
+		configComp.Settings.Theme = ""
+		if err := themes.Apply(&configComp.GameConfig.UI, defaultThemeName); err != nil {
+			log.Printf("Failed to apply the default theme: %v", err)
+		}
+	}
+}
+
+// Settings は現在のプレイヤー設定を返します。
+func (g *Game) Settings() *Settings {
+	return ConfigComponentType.Get(g.gameStateEntry).Settings
+}
+
 // getSystem ヘルパーメソッド：特定の型のシステムを取得
 func (g *Game) getSystem(target System) System {
 	for _, s := range g.systems {
@@ -97,6 +185,37 @@ func (g *Game) Update() error {
 		gs.DebugMode = !gs.DebugMode
 	}
 
+	// データ・設定ファイルの変更は状態に関わらず反映する
+	if g.hotReload != nil {
+		g.hotReload.Update(g.ECS)
+	}
+
+	// ネット対戦の相手からの行動やリスタートの要求を受け取る
+	if g.lockstep != nil {
+		g.lockstep.Update(g.ECS)
+	}
+
+	// 観戦者へ戦闘の状態を配信する（一定のティックごと）
+	if g.spectator != nil {
+		g.spectator.Update(g.ECS)
+	}
+
+	// 速度やメッセージモードの切り替えは状態に関わらず受け付ける
+	g.getSystem(&BattleSettingsSystem{}).Update(g.ECS)
+
+	// 演出はメッセージ表示中も進める（攻撃の演出が再生される）。ポーズ中と詳細画面では止める
+	if gs.CurrentState != StatePaused && gs.CurrentState != StateMedarotDetail {
+		g.getSystem(&AnimationSystem{}).Update(g.ECS)
+	}
+
+	// ポーズメニュー・詳細画面の開閉。状態が切り替わったフレームは他の入力を処理しない
+	stateBeforeMenu := gs.CurrentState
+	g.getSystem(&MenuSystem{}).Update(g.ECS)
+	if gs.CurrentState != stateBeforeMenu {
+		gs.TickCount++
+		return nil
+	}
+
 	// ゲームの状態に応じて実行するシステムを切り替える
 	switch gs.CurrentState {
 	case StatePlaying:
@@ -112,12 +231,22 @@ func (g *Game) Update() error {
 		}
 
 		g.getSystem(&AISystem{}).Update(g.ECS)
+		if g.bots != nil {
+			g.bots.Update(g.ECS)
+		}
 		g.getSystem(&PlayerInputSystem{}).Update(g.ECS) // PlayerActionSelectへの遷移を担当
 		if GameStateComponentType.Get(g.gameStateEntry).CurrentState != StatePlaying {
 			return nil
 		}
 
+		// ネット対戦では、両方の端末で行動がそろうまでゲージを進めない
+		if g.lockstep != nil && !g.lockstep.Ready(g.ECS) {
+			return nil
+		}
 		g.getSystem(&GaugeUpdateSystem{}).Update(g.ECS)
+		if g.lockstep != nil {
+			g.lockstep.AfterTick(g.ECS)
+		}
 
 	case StatePlayerActionSelect:
 		// この状態ではPlayerInputSystemだけを動かす
@@ -126,8 +255,13 @@ func (g *Game) Update() error {
 	case GameStateMessage:
 		g.getSystem(&MessageSystem{}).Update(g.ECS)
 
+	case StatePaused, StateMedarotDetail:
+		// MenuSystemが操作を処理する。戦闘は進行しない
+
 	case GameStateOver:
-		if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		config := ConfigComponentType.Get(g.gameStateEntry).GameConfig
+		clicked := inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && !isCursorOnHUD(config)
+		if clicked || isInputJustPressed(InputConfirm) {
 			gs.RestartRequested = true
 		}
 	}
@@ -144,13 +278,46 @@ func (g *Game) Update() error {
 		log.Println("Restarting game...")
 		// NewGameを呼び出して自身をリセットする
 		// 元のポインタが指す先のメモリを新しいゲームインスタンスで上書き
-		config := ConfigComponentType.Get(g.gameStateEntry).GameConfig
-		gameData := ConfigComponentType.Get(g.gameStateEntry).GameData
-		*g = *NewGame(gameData, *config)
+		if g.lockstep != nil {
+			g.lockstep.RequestRestart() // 相手の端末も同じ戦闘をやり直す
+		}
+		g.restart()
 	}
 	return nil
 }
 
+// restart は同じ設定とデータで新しいゲームを作り直し、購読者などを引き継ぎます。
+func (g *Game) restart() {
+	config := ConfigComponentType.Get(g.gameStateEntry).GameConfig
+	gameData := ConfigComponentType.Get(g.gameStateEntry).GameData
+	settings := ConfigComponentType.Get(g.gameStateEntry).Settings
+	themes := ConfigComponentType.Get(g.gameStateEntry).Themes
+	match := ConfigComponentType.Get(g.gameStateEntry).Match
+	listeners := g.combatListeners
+	hotReload := g.hotReload
+	lockstep := g.lockstep
+	spectator := g.spectator
+	bots := g.bots
+	if lockstep != nil {
+		lockstep.nextRound() // 編成を決める前に、次の戦闘のシードにそろえる
+	}
+	*g = *NewGame(gameData, *config)
+	g.hotReload = hotReload
+	g.spectator = spectator // 購読者としてはlistenersで引き継ぐ
+	g.SetSettings(settings)
+	g.SetMatch(match)
+	if lockstep != nil {
+		g.EnableLockstep(lockstep)
+	}
+	if bots != nil {
+		g.EnableBots(bots)
+	}
+	ConfigComponentType.Get(g.gameStateEntry).Themes = themes
+	for _, l := range listeners {
+		g.AddCombatEventListener(l)
+	}
+}
+
 // Draw はゲーム画面を描画します。
 func (g *Game) Draw(screen *ebiten.Image) {
 	for _, s := range g.renderSystems {
@@ -158,8 +325,27 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	}
 }
 
-// Layout はEbitengineにウィンドウサイズを伝えます。
+// Layout はebiten.Gameを満たすためのメソッドです。LayoutFがあるため、Ebitengineからは呼ばれません。
 func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	width, height := g.LayoutF(float64(outsideWidth), float64(outsideHeight))
+	return int(width), int(height)
+}
+
+// LayoutF はウィンドウの大きさに合わせて画面サイズを決め、UIの配置を計算し直します。
+// outsideWidth/Heightはデバイス非依存のピクセル数で、UIの配置とカーソル座標はこの論理座標で扱います。
+// 描画先の画像はデバイスのピクセル数（論理座標×DeviceScaleFactor）にするので、HiDPIの画面でも文字や線がぼやけません。
+func (g *Game) LayoutF(outsideWidth, outsideHeight float64) (screenWidth, screenHeight float64) {
 	config := ConfigComponentType.Get(g.gameStateEntry).GameConfig
-	return config.UI.Screen.Width, config.UI.Screen.Height
+	width, height := logicalScreenSize(int(outsideWidth), int(outsideHeight))
+	if width != config.UI.Screen.Width || height != config.UI.Screen.Height {
+		config.UI.ApplyScreenSize(width, height)
+	}
+	scale := 1.0
+	if monitor := ebiten.Monitor(); monitor != nil {
+		scale = monitor.DeviceScaleFactor()
+	}
+	if scale != deviceScale {
+		setDeviceScale(scale)
+	}
+	return float64(width) * deviceScale, float64(height) * deviceScale
 }
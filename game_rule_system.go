@@ -57,16 +57,18 @@ func (sys *GameRuleSystem) Update(ecs *ecs.ECS) {
 	winnerFound := false
 	if team1LeaderExists && !team1LeaderAlive {
 		gs.Winner = Team2
-		gs.Message = "チーム2の勝利！"
+		gs.Message = T("battle.victory", "team", 2)
 		winnerFound = true
 	} else if team2LeaderExists && !team2LeaderAlive {
 		gs.Winner = Team1
-		gs.Message = "チーム1の勝利！"
+		gs.Message = T("battle.victory", "team", 1)
 		winnerFound = true
 	}
 
 	if winnerFound {
 		gs.CurrentState = GameStateOver
 		GameStateComponentType.Set(gameStateEntry, gs)
+		winner := gs.Winner
+		publishCombatEvent(ecs, CombatEvent{Type: EventBattleEnd, Winner: &winner, Message: gs.Message})
 	}
 }
@@ -20,6 +20,15 @@ func NewGaugeUpdateSystem() *GaugeUpdateSystem {
 	}
 }
 
+// gaugeSpeedMultiplier はゲージの進み方に掛ける倍率を返します。
+// 通信対戦では両者の結果を揃えるため、設定に関わらず等倍です。
+func gaugeSpeedMultiplier(config *ConfigComponent) float64 {
+	if config.Settings == nil || config.Settings.GaugeSpeed <= 0 || config.Match.Networked {
+		return 1
+	}
+	return float64(config.Settings.GaugeSpeed)
+}
+
 func (sys *GaugeUpdateSystem) Update(ecs *ecs.ECS) {
 	gs, gsOk := GameStateComponentType.First(ecs.World)
 	config, cfgOk := ConfigComponentType.First(ecs.World)
@@ -27,6 +36,7 @@ func (sys *GaugeUpdateSystem) Update(ecs *ecs.ECS) {
 		return // メッセージ表示中はゲージを更新しない
 	}
 	balanceCfg := ConfigComponentType.Get(config).GameConfig.Balance
+	speedMultiplier := gaugeSpeedMultiplier(ConfigComponentType.Get(config))
 
 	sys.query.Each(ecs.World, func(entry *donburi.Entry) {
 		status := StatusComponentType.Get(entry)
@@ -45,7 +55,7 @@ func (sys *GaugeUpdateSystem) Update(ecs *ecs.ECS) {
 		// ゲージを進めるための基礎値（パーツのチャージ/クールダウンと脚部の推進）
 		var baseStat, legPropulsion int
 		if legs, ok := parts.Parts[PartSlotLegs]; ok && !legs.IsBroken {
-			legPropulsion = legs.Propulsion
+			legPropulsion = legs.Propulsion.Int()
 		}
 
 		selectedPart, partExists := parts.Parts[action.SelectedPartKey]
@@ -57,19 +67,20 @@ func (sys *GaugeUpdateSystem) Update(ecs *ecs.ECS) {
 				resetToActionSelect(entry, status, action)
 				return
 			}
-			baseStat = selectedPart.Charge
+			baseStat = selectedPart.Charge.Int()
 		case StateActionCooldown:
 			if !isValidPartSelected { // 冷却中にパーツが壊れた
 				resetToActionSelect(entry, status, action)
 				return
 			}
-			baseStat = selectedPart.Cooldown
+			baseStat = selectedPart.Cooldown.Int()
 		default:
 			return // 他の状態ではゲージは進まない
 		}
 
-		// ゲージ更新
-		moveSpeed := (float64(baseStat) + float64(legPropulsion)*balanceCfg.Time.PropulsionEffectRate) / balanceCfg.Time.OverallTimeDivisor
+		// ゲージ更新。積を明示的にfloat64へ変換して丸め、環境によって積和演算（FMA）にまとめられないようにする
+		// （ネットワーク対戦では両方のマシンでゲージが1ビットも違わないことが必要）
+		moveSpeed := float64(gaugeStepPerTick(baseStat, legPropulsion, balanceCfg) * speedMultiplier) // プレイヤーが選んだ戦闘速度
 		status.Gauge += moveSpeed
 
 		// ゲージ満タン時の処理
@@ -87,6 +98,12 @@ func (sys *GaugeUpdateSystem) Update(ecs *ecs.ECS) {
 	})
 }
 
+// gaugeStepPerTick は、パーツのチャージ/クールダウン値と脚部の推進から、1ティックあたりのゲージ増加量を計算します。
+// 戦闘速度の倍率は含みません。推進の積はFMAにまとめられないよう、明示的に変換して丸めます。
+func gaugeStepPerTick(baseStat, legPropulsion int, cfg BalanceConfig) float64 {
+	return (float64(baseStat) + float64(float64(legPropulsion)*cfg.Time.PropulsionEffectRate)) / cfg.Time.OverallTimeDivisor
+}
+
 // resetToActionSelect はメダロットの状態を行動選択可能に戻します。
 func resetToActionSelect(entry *donburi.Entry, status *StatusComponent, action *ActionComponent) {
 	status.State = StateReadyToSelectAction
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestGaugeSpeedMultiplierIgnoresSettingsInNetworkedMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ConfigComponent
+		want   float64
+	}{
+		{"no settings", ConfigComponent{}, 1},
+		{"unset speed", ConfigComponent{Settings: &Settings{}}, 1},
+		{"local match", ConfigComponent{Settings: &Settings{GaugeSpeed: 3}}, 3},
+		{"networked match", ConfigComponent{Settings: &Settings{GaugeSpeed: 3}, Match: MatchOptions{Networked: true}}, 1},
+	}
+	for _, tt := range tests {
+		if got := gaugeSpeedMultiplier(&tt.config); got != tt.want {
+			t.Errorf("%s: gaugeSpeedMultiplier = %g, want %g", tt.name, got, tt.want)
+		}
+	}
+}
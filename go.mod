@@ -6,14 +6,16 @@ require (
 	github.com/hajimehoshi/ebiten/v2 v2.8.8
 	github.com/yohamta/donburi v1.15.7
 	golang.org/x/image v0.28.0
+	golang.org/x/text v0.26.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/ebitengine/gomobile v0.0.0-20240911145611-4856209ac325 // indirect
 	github.com/ebitengine/hideconsole v1.0.0 // indirect
 	github.com/ebitengine/purego v0.8.0 // indirect
+	github.com/go-text/typesetting v0.2.1 // indirect
 	github.com/jezek/xgb v1.1.1 // indirect
 	golang.org/x/sync v0.15.0 // indirect
 	golang.org/x/sys v0.25.0 // indirect
-	golang.org/x/text v0.26.0 // indirect
 )
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/filter"
+)
+
+// RunHeadless はウィンドウを開かずにゲームを進行させます。
+// 全機体をAI制御に切り替え、メッセージはクリックを待たずに送ります。
+// 同じシードで同じ結果になるよう、保存されたプレイヤー設定（戦闘速度など）は使わずデフォルト設定で進めます。
+// 決着がつけば勝者チームとtrueを、maxTicksに達した場合はfalseを返します。
+func RunHeadless(g *Game, maxTicks int) (TeamID, bool, error) {
+	g.SetSettings(DefaultSettings())
+	setAllAIControlled(g.World)
+
+	for i := 0; i < maxTicks; i++ {
+		// ボットの応答を待ってから進める（結果がボットの速さに左右されないように）
+		if g.bots != nil {
+			g.bots.Settle(g.ECS)
+		}
+		if err := g.Update(); err != nil {
+			return 0, false, err
+		}
+		gs := GameStateComponentType.Get(g.gameStateEntry)
+		switch gs.CurrentState {
+		case GameStateOver:
+			log.Printf("Headless battle finished at tick %d: %s", gs.TickCount, gs.Message)
+			return gs.Winner, true, nil
+		case GameStateMessage:
+			advanceMessage(g.ECS)
+		case StatePlayerActionSelect:
+			// 全機体がAI制御のため、ここに来ることはないはず
+			return 0, false, fmt.Errorf("headless battle entered player action select at tick %d", gs.TickCount)
+		}
+	}
+	log.Printf("Headless battle reached the tick limit (%d) without a winner.", maxTicks)
+	return 0, false, nil
+}
+
+// setAllAIControlled はプレイヤー制御のエンティティをすべてAI制御に切り替えます。
+func setAllAIControlled(w donburi.World) {
+	var entries []*donburi.Entry
+	donburi.NewQuery(filter.Contains(PlayerControlledComponentType)).Each(w, func(entry *donburi.Entry) {
+		entries = append(entries, entry)
+	})
+	// クエリの走査中にアーキタイプを変更しないよう、収集後に付け替える
+	for _, entry := range entries {
+		entry.RemoveComponent(PlayerControlledComponentType)
+		entry.AddComponent(AIControlledComponentType)
+	}
+}
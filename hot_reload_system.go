@@ -0,0 +1,229 @@
+package main
+
+import (
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/ecs"
+	"github.com/yohamta/donburi/filter"
+)
+
+const (
+	hotReloadPollTicks   = 30  // ファイルの更新を確認する間隔（ティック数）
+	reloadNoticeDuration = 120 // 再読み込み成功の通知を表示しておくティック数
+)
+
+// ReloadBannerComponent は再読み込みの結果を画面に表示するためのシングルトンコンポーネントです。
+type ReloadBannerComponent struct {
+	Text      string
+	IsError   bool // エラーの場合は次に再読み込みが成功するまで表示し続ける
+	Remaining int  // 成功通知の残り表示ティック数
+}
+
+var ReloadBannerComponentType = donburi.NewComponentType[ReloadBannerComponent]()
+
+// fileStamp はファイルの更新を検出するための更新時刻とサイズです。
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// HotReloadSystem はデータファイルと設定ファイルの更新をポーリングで検出し、戦闘中のゲームに反映します。
+// パーツの能力値は現在の装甲の割合を保ったまま新しい値に置き換えます。読み込みに失敗した場合はバナーに表示し、以前の値で続行します。
+type HotReloadSystem struct {
+	packs      []DataPack
+	configPath string
+	configSets []string
+	files      map[string]fileStamp
+	ticks      int
+	banner     ReloadBannerComponent
+
+	medarotQuery *donburi.Query
+}
+
+// NewHotReloadSystem は監視を開始します。configPathが空の場合は設定ファイルを監視しません。
+// 監視するデータファイルは、OS上のディレクトリにあるパックのものだけです（埋め込みデータは変わらないため）。
+func NewHotReloadSystem(packs []DataPack, configPath string, configSets []string) *HotReloadSystem {
+	sys := &HotReloadSystem{
+		packs:      packs,
+		configPath: configPath,
+		configSets: configSets,
+		files:      make(map[string]fileStamp),
+		medarotQuery: donburi.NewQuery(filter.And(
+			filter.Contains(PartsComponentType), filter.Contains(CMedal),
+		)),
+	}
+	for _, path := range sys.watchedFiles() {
+		sys.files[path] = statFile(path)
+	}
+	return sys
+}
+
+// watchedFiles は監視するファイルの一覧です。
+func (sys *HotReloadSystem) watchedFiles() []string {
+	var files []string
+	for _, pack := range sys.packs {
+		if pack.Dir == "" {
+			continue
+		}
+		for _, kind := range dataKinds {
+			for _, ext := range dataFileExtensions {
+				files = append(files, filepath.Join(pack.Dir, kind+ext))
+			}
+		}
+	}
+	if sys.configPath != "" {
+		files = append(files, sys.configPath)
+	}
+	return files
+}
+
+func statFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+func (sys *HotReloadSystem) Update(ecs *ecs.ECS) {
+	defer sys.publishBanner(ecs)
+	if sys.banner.Remaining > 0 {
+		sys.banner.Remaining--
+		if sys.banner.Remaining == 0 && !sys.banner.IsError {
+			sys.banner.Text = ""
+		}
+	}
+
+	sys.ticks++
+	if sys.ticks%hotReloadPollTicks != 0 {
+		return
+	}
+	changed := false
+	for _, path := range sys.watchedFiles() {
+		stamp := statFile(path)
+		if stamp != sys.files[path] {
+			sys.files[path] = stamp
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	if err := sys.reload(ecs); err != nil {
+		log.Printf("Hot reload failed: %v", err)
+		sys.banner = ReloadBannerComponent{Text: T("reload.failed", "error", err), IsError: true}
+		return
+	}
+	log.Println("Hot reload: data and config applied.")
+	sys.banner = ReloadBannerComponent{Text: T("reload.done"), Remaining: reloadNoticeDuration}
+}
+
+// publishBanner はバナーの内容を描画用のコンポーネントに書き込みます。
+func (sys *HotReloadSystem) publishBanner(ecs *ecs.ECS) {
+	if entry, ok := ReloadBannerComponentType.First(ecs.World); ok {
+		ReloadBannerComponentType.SetValue(entry, sys.banner)
+	}
+}
+
+// reload はすべてのファイルを読み直し、成功した場合だけゲームに反映します。
+func (sys *HotReloadSystem) reload(ecs *ecs.ECS) error {
+	configEntry, ok := ConfigComponentType.First(ecs.World)
+	if !ok {
+		return nil
+	}
+	configComp := ConfigComponentType.Get(configEntry)
+
+	newData, err := LoadAllGameData(sys.packs)
+	if err != nil {
+		return err
+	}
+	if stage := configComp.GameData.Stage; stage != nil {
+		if err := newData.SelectStage(stage.ID); err != nil {
+			return err
+		}
+	}
+	newConfig := LoadConfig()
+	if err := ApplyConfigOverrides(&newConfig, sys.configPath, sys.configSets); err != nil {
+		return err
+	}
+
+	sys.applyGameData(ecs, configComp.GameData, newData)
+	applyConfig(configComp, &newConfig)
+	return nil
+}
+
+// applyGameData はマスターデータを更新し、戦闘中のメダロットのパーツとメダルにも反映します。
+func (sys *HotReloadSystem) applyGameData(ecs *ecs.ECS, gameData, newData *GameData) {
+	for id, part := range newData.AllParts {
+		if old, ok := gameData.AllParts[id]; ok {
+			part.Sprite = old.Sprite
+		}
+	}
+	gameData.AllParts = newData.AllParts
+	gameData.Medals = newData.Medals
+	gameData.Loadouts, gameData.Stages, gameData.Stage = newData.Loadouts, newData.Stages, newData.Stage
+	gameData.Provenance = newData.Provenance
+
+	sys.medarotQuery.Each(ecs.World, func(entry *donburi.Entry) {
+		for _, part := range PartsComponentType.Get(entry).Parts {
+			if part == nil {
+				continue
+			}
+			if master, ok := newData.AllParts[part.ID]; ok {
+				applyPartMaster(part, master)
+			}
+		}
+		medalComp := CMedal.Get(entry)
+		if medalComp.Medal != nil {
+			if master := findMedalByID(newData.Medals, medalComp.Medal.ID); master != nil {
+				medalComp.Medal = master
+			}
+		}
+	})
+}
+
+// applyPartMaster はパーツの能力値をマスターデータの値に置き換えます。
+// 装甲は現在の割合（受けたダメージの比率）を保つように計算し直し、破壊状態は変えません。
+func applyPartMaster(part, master *Part) {
+	ratio := 1.0
+	if part.MaxArmor > 0 {
+		ratio = float64(part.Armor) / float64(part.MaxArmor)
+	}
+	armor, isBroken, sprite := part.Armor, part.IsBroken, part.Sprite
+	*part = *master
+	part.IsBroken, part.Sprite = isBroken, sprite
+	part.MaxArmor = master.Armor
+	part.Armor = int(math.Round(ratio * float64(part.MaxArmor)))
+	if isBroken {
+		part.Armor = 0
+	} else if part.Armor <= 0 && armor > 0 {
+		part.Armor = 1 // 割合の丸めで壊れないようにする
+	}
+}
+
+// applyConfig は新しい設定を反映します。画面サイズは現在の値を保ち、
+// 配色とスタイルは新しい設定をテーマの土台にして、選択中のテーマを当て直します。
+func applyConfig(configComp *ConfigComponent, newConfig *Config) {
+	current := configComp.GameConfig
+	ui := newConfig.UI
+	ui.ApplyScreenSize(current.UI.Screen.Width, current.UI.Screen.Height)
+	if themes := configComp.Themes; themes != nil {
+		themes.SetBase(ui)
+		theme := ""
+		if configComp.Settings != nil {
+			theme = configComp.Settings.Theme
+		}
+		if err := themes.Apply(&ui, theme); err != nil {
+			log.Printf("Failed to reapply theme %q: %v", theme, err)
+		}
+	}
+	current.Balance = newConfig.Balance
+	current.AI = newConfig.AI // AIの重みは次に行動を選ぶときから反映される
+	current.UI = ui
+}
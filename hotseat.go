@@ -0,0 +1,71 @@
+package main
+
+import (
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/filter"
+)
+
+// ホットシート対戦: 1台の端末で、チーム1をプレイヤー1、チーム2をプレイヤー2が交代で操作します。
+// キーボードとマウスは行動選択中のプレイヤーが使い、ゲームパッドが2台以上あれば接続順に各プレイヤーへ割り当てます。
+
+// MatchOptions は対戦の形式です。リスタート後も引き継ぎます。
+type MatchOptions struct {
+	Hotseat bool // チーム2もAIではなく2人目のプレイヤーが操作する
+	// HideOpponentChoices は、プレイヤーが操作する機体の行動（ターゲットや、特性による回避・防御不可）を、
+	// その機体の持ち主が行動を選んでいるとき以外は表示しないようにします。
+	HideOpponentChoices bool
+	// Networked はネット対戦（lockstep_system.go）です。両方の端末で同じ戦闘になるよう、戦闘速度はx1に固定します。
+	Networked bool
+}
+
+// setHotseatControl はチーム2のAI制御の機体を、プレイヤー2の操作に切り替えます。
+func setHotseatControl(w donburi.World) {
+	var entries []*donburi.Entry
+	donburi.NewQuery(filter.And(
+		filter.Contains(AIControlledComponentType), filter.Contains(IdentityComponentType),
+	)).Each(w, func(entry *donburi.Entry) {
+		if IdentityComponentType.Get(entry).Team == Team2 {
+			entries = append(entries, entry)
+		}
+	})
+	// クエリの走査中にアーキタイプを変更しないよう、収集後に付け替える
+	for _, entry := range entries {
+		entry.RemoveComponent(AIControlledComponentType)
+		donburi.Add(entry, PlayerControlledComponentType, &PlayerControlledComponent{Player: 2})
+	}
+}
+
+// playerOf はエンティティを操作するプレイヤーの番号を返します。AIが操作する場合は0です。
+func playerOf(entry *donburi.Entry) int {
+	if !entry.HasComponent(PlayerControlledComponentType) {
+		return 0
+	}
+	return PlayerControlledComponentType.Get(entry).Player
+}
+
+// choicesHidden は、機体が選んだ行動を画面に出してはいけないかを返します。
+// HideOpponentChoicesが有効なホットシート対戦で、プレイヤーが操作する機体のうち、
+// 行動を選んでいるプレイヤーのもの以外が対象です（戦闘の進行中は両プレイヤーの機体を隠す）。
+func choicesHidden(w donburi.World, entry *donburi.Entry) bool {
+	configEntry, ok := ConfigComponentType.First(w)
+	if !ok {
+		return false
+	}
+	match := ConfigComponentType.Get(configEntry).Match
+	player := playerOf(entry)
+	if !match.Hotseat || !match.HideOpponentChoices || player == 0 {
+		return false
+	}
+	gs := GameStateComponentType.Get(GameStateComponentType.MustFirst(w))
+	pasComp := PlayerActionSelectComponentType.Get(PlayerActionSelectComponentType.MustFirst(w))
+	return gs.CurrentState != StatePlayerActionSelect || pasComp.HandOff || player != pasComp.ActivePlayer
+}
+
+// visibleStatus は画面に出してよい範囲の状態を返します。行動を隠す機体では、特性による回避・防御不可を除きます。
+func visibleStatus(w donburi.World, entry *donburi.Entry) StatusComponent {
+	status := *StatusComponentType.Get(entry)
+	if choicesHidden(w, entry) {
+		status.IsEvasionDisabled, status.IsDefenseDisabled = false, false
+	}
+	return status
+}
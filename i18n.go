@@ -0,0 +1,284 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// defaultLanguage は基準となる言語です。他の言語のカタログはこの言語のキーをすべて持つ必要があります。
+const defaultLanguage = "ja"
+
+// embeddedLocales はUIの文言のカタログです。ファイル名（拡張子を除く）が言語コードになります。
+//
+//go:embed locales
+var embeddedLocales embed.FS
+
+// catalogMessage はカタログの1件の文言です。{name} の形で引数を埋め込みます。
+// JSONでは文字列のほか、数で形が変わる文言を {"one": "...", "other": "..."} と書けます（引数countが1ならone）。
+type catalogMessage struct {
+	One   string
+	Other string
+}
+
+func (m *catalogMessage) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		m.One, m.Other = "", s
+		return nil
+	}
+	var forms struct {
+		One   string `json:"one"`
+		Other string `json:"other"`
+	}
+	if err := json.Unmarshal(b, &forms); err != nil {
+		return errors.New(`message must be a string or {"one": ..., "other": ...}`)
+	}
+	if forms.Other == "" {
+		return errors.New(`plural message has no "other" form`)
+	}
+	m.One, m.Other = forms.One, forms.Other
+	return nil
+}
+
+// forms は文言のすべての形を返します。
+func (m catalogMessage) forms() []string {
+	if m.One == "" {
+		return []string{m.Other}
+	}
+	return []string{m.One, m.Other}
+}
+
+// Catalog は1言語分の文言です。
+type Catalog map[string]catalogMessage
+
+// Localizer は現在の言語と、読み込んだすべてのカタログを保持します。
+type Localizer struct {
+	language string
+	catalogs map[string]Catalog
+	missing  map[string]bool // 報告済みの見つからないキー
+}
+
+// localizer はゲーム全体で使う翻訳です。Fontsと同様にどこからでも参照します。
+var localizer = newEmbeddedLocalizer()
+
+func newEmbeddedLocalizer() *Localizer {
+	catalogs, err := LoadCatalogs(embeddedLocales, "locales")
+	if err != nil {
+		log.Printf("Failed to load message catalogs: %v", err)
+	}
+	return &Localizer{language: defaultLanguage, catalogs: catalogs, missing: make(map[string]bool)}
+}
+
+// LoadCatalogs はdir直下の *.json をカタログとして読み込みます。
+func LoadCatalogs(fsys fs.FS, dir string) (map[string]Catalog, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	catalogs := make(map[string]Catalog)
+	var errs []error
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var catalog Catalog
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+			continue
+		}
+		catalogs[strings.TrimSuffix(path.Base(file), ".json")] = catalog
+	}
+	return catalogs, errors.Join(errs...)
+}
+
+// Languages は利用できる言語コードを、基準の言語を先頭にして返します。
+func Languages() []string {
+	languages := make([]string, 0, len(localizer.catalogs))
+	for lang := range localizer.catalogs {
+		if lang != defaultLanguage {
+			languages = append(languages, lang)
+		}
+	}
+	sort.Strings(languages)
+	return append([]string{defaultLanguage}, languages...)
+}
+
+// CurrentLanguage は現在の言語コードを返します。
+func CurrentLanguage() string {
+	return localizer.language
+}
+
+// SetLanguage は言語を切り替えます。空文字の場合は基準の言語にします。
+func SetLanguage(lang string) error {
+	if lang == "" {
+		lang = defaultLanguage
+	}
+	if _, ok := localizer.catalogs[lang]; !ok {
+		return fmt.Errorf("unknown language %q (available: %s)", lang, strings.Join(Languages(), ", "))
+	}
+	localizer.language = lang
+	return nil
+}
+
+// NextLanguage はcurrentの次の言語コードを返します（最後の次は最初に戻る）。
+func NextLanguage(current string) string {
+	languages := Languages()
+	for i, lang := range languages {
+		if lang == current {
+			return languages[(i+1)%len(languages)]
+		}
+	}
+	return languages[0]
+}
+
+// T はキーの文言を現在の言語で返します。argsには名前と値を交互に並べます（例: T("battle.victory", "team", 1)）。
+// 現在の言語に文言がなければ基準の言語の文言を使い、それもなければキーをそのまま返します。
+func T(key string, args ...any) string {
+	message, ok := localizer.catalogs[localizer.language][key]
+	if !ok {
+		message, ok = localizer.catalogs[defaultLanguage][key]
+		if !localizer.missing[localizer.language+"/"+key] {
+			localizer.missing[localizer.language+"/"+key] = true
+			log.Printf("Missing message %q for language %s", key, localizer.language)
+		}
+	}
+	if !ok {
+		return key
+	}
+
+	template := message.Other
+	values := make(map[string]string, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		name := fmt.Sprint(args[i])
+		values[name] = fmt.Sprint(args[i+1])
+		if name == "count" && message.One != "" && values[name] == "1" {
+			template = message.One
+		}
+	}
+	return messagePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		if value, ok := values[placeholder[1:len(placeholder)-1]]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// messagePlaceholder は文言中の {name} を表します。
+var messagePlaceholder = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// messagePlaceholders は文言に含まれる引数名の集合を返します。
+func messagePlaceholders(s string) map[string]bool {
+	names := make(map[string]bool)
+	for _, m := range messagePlaceholder.FindAllString(s, -1) {
+		names[m[1:len(m)-1]] = true
+	}
+	return names
+}
+
+// CatalogIssue はカタログの問題1件です。
+type CatalogIssue struct {
+	Language string
+	Key      string
+	Message  string
+}
+
+// CheckCatalogs は基準の言語と比べて、各言語のカタログにないキー・余分なキー・引数の違いを返します。
+func CheckCatalogs(catalogs map[string]Catalog) []CatalogIssue {
+	base := catalogs[defaultLanguage]
+	var issues []CatalogIssue
+	languages := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	for _, lang := range languages {
+		catalog := catalogs[lang]
+		for _, key := range sortedCatalogKeys(base) {
+			message, ok := catalog[key]
+			if !ok {
+				issues = append(issues, CatalogIssue{Language: lang, Key: key, Message: "missing message"})
+				continue
+			}
+			expected := messagePlaceholders(base[key].Other)
+			for _, form := range message.forms() {
+				if got := messagePlaceholders(form); !sameNames(expected, got) {
+					issues = append(issues, CatalogIssue{Language: lang, Key: key, Message: fmt.Sprintf("placeholders %v do not match %s %v", sortedNames(got), defaultLanguage, sortedNames(expected))})
+					break
+				}
+			}
+		}
+		for _, key := range sortedCatalogKeys(catalog) {
+			if _, ok := base[key]; !ok {
+				issues = append(issues, CatalogIssue{Language: lang, Key: key, Message: fmt.Sprintf("not in the %s catalog", defaultLanguage)})
+			}
+		}
+	}
+	return issues
+}
+
+func sortedCatalogKeys(catalog Catalog) []string {
+	keys := make([]string, 0, len(catalog))
+	for key := range catalog {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sameNames(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name := range a {
+		if !b[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedNames(names map[string]bool) []string {
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// LocalizedText はデータの列 "xxx_en" などから読み込んだ、言語ごとの表記です。
+type LocalizedText map[string]string
+
+// Or は現在の言語の表記を返します。なければfallback（基準の表記）を返します。
+func (t LocalizedText) Or(fallback string) string {
+	if s := t[CurrentLanguage()]; s != "" {
+		return s
+	}
+	return fallback
+}
+
+// localizedColumns はレコードから "base_<言語>" の列を集めます。基準の言語の列（skip）は除きます。
+func localizedColumns(record dataRecord, base string, skip string) LocalizedText {
+	var text LocalizedText
+	for column := range record {
+		lang, ok := strings.CutPrefix(column, base+"_")
+		if !ok || lang == skip || record.str(column) == "" {
+			continue
+		}
+		if text == nil {
+			text = make(LocalizedText)
+		}
+		text[lang] = record.str(column)
+	}
+	return text
+}
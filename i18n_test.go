@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// useTestCatalogs はテストの間だけ、localesの内容を差し替えます。
+func useTestCatalogs(t *testing.T, locales fstest.MapFS, language string) map[string]Catalog {
+	t.Helper()
+	catalogs, err := LoadCatalogs(locales, "locales")
+	if err != nil {
+		t.Fatalf("LoadCatalogs: %v", err)
+	}
+	saved := localizer
+	t.Cleanup(func() { localizer = saved })
+	localizer = &Localizer{language: language, catalogs: catalogs, missing: make(map[string]bool)}
+	return catalogs
+}
+
+var testLocales = fstest.MapFS{
+	"locales/ja.json": {Data: []byte(`{
+		"battle.turn": "{name}の番",
+		"battle.remaining": {"one": "残り{count}体", "other": "残り{count}体たち"},
+		"battle.ja_only": "日本語だけ"
+	}`)},
+	"locales/en.json": {Data: []byte(`{
+		"battle.turn": "{name}'s turn",
+		"battle.remaining": {"one": "{count} medarot left", "other": "{count} medarots left"}
+	}`)},
+}
+
+func TestEmbeddedCatalogsAreComplete(t *testing.T) {
+	for _, issue := range CheckCatalogs(localizer.catalogs) {
+		t.Errorf("%s %s: %s", issue.Language, issue.Key, issue.Message)
+	}
+}
+
+func TestCheckCatalogs(t *testing.T) {
+	locales := fstest.MapFS{
+		"locales/ja.json": testLocales["locales/ja.json"],
+		"locales/en.json": {Data: []byte(`{
+			"battle.turn": "{team}'s turn",
+			"battle.remaining": {"one": "one left", "other": "{count} medarots left"},
+			"battle.en_only": "extra"
+		}`)},
+	}
+	catalogs, err := LoadCatalogs(locales, "locales")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, issue := range CheckCatalogs(catalogs) {
+		got = append(got, issue.Language+" "+issue.Key+": "+issue.Message)
+	}
+	want := []string{
+		"en battle.ja_only: missing message",
+		"en battle.remaining: placeholders [] do not match ja [count]",
+		"en battle.turn: placeholders [team] do not match ja [name]",
+		"en battle.en_only: not in the ja catalog",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("CheckCatalogs =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLoadCatalogsRejectsPluralWithoutOther(t *testing.T) {
+	locales := fstest.MapFS{"locales/ja.json": {Data: []byte(`{"battle.remaining": {"one": "残り1体"}}`)}}
+	if _, err := LoadCatalogs(locales, "locales"); err == nil || !strings.Contains(err.Error(), `no "other" form`) {
+		t.Errorf("LoadCatalogs = %v, want an error about the missing other form", err)
+	}
+}
+
+func TestTSelectsPluralForm(t *testing.T) {
+	useTestCatalogs(t, testLocales, "en")
+	tests := []struct {
+		count any
+		want  string
+	}{
+		{1, "1 medarot left"},
+		{"1", "1 medarot left"},
+		{0, "0 medarots left"},
+		{2, "2 medarots left"},
+		{11, "11 medarots left"},
+	}
+	for _, tt := range tests {
+		if got := T("battle.remaining", "count", tt.count); got != tt.want {
+			t.Errorf("T(battle.remaining, count=%v) = %q, want %q", tt.count, got, tt.want)
+		}
+	}
+	// oneがない文言は数に関わらずotherを使う
+	if got := T("battle.turn", "name", "Rokusho", "count", 1); got != "Rokusho's turn" {
+		t.Errorf("T(battle.turn) = %q", got)
+	}
+}
+
+func TestTFallsBackToTheBaseLanguage(t *testing.T) {
+	useTestCatalogs(t, testLocales, "en")
+	logs := captureLog(t)
+
+	if got := T("battle.ja_only"); got != "日本語だけ" {
+		t.Errorf("T(battle.ja_only) = %q, want the ja message", got)
+	}
+	T("battle.ja_only")
+	if n := strings.Count(logs.String(), `Missing message "battle.ja_only" for language en`); n != 1 {
+		t.Errorf("missing message logged %d times, want once:\n%s", n, logs)
+	}
+	if got := T("battle.unknown"); got != "battle.unknown" {
+		t.Errorf("T(battle.unknown) = %q, want the key", got)
+	}
+	// 渡されなかった引数はそのまま残す
+	if got := T("battle.turn"); got != "{name}'s turn" {
+		t.Errorf("T(battle.turn) without arguments = %q", got)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// 入力の抽象化。キーボードとゲームパッド（標準レイアウト）の入力を、
+// 「決定」「キャンセル」などのUI操作としてまとめて判定します。
+
+// InputAction はUI操作の種類です。
+type InputAction int
+
+const (
+	InputConfirm     InputAction = iota // 決定
+	InputCancel                         // キャンセル
+	InputUp                             // フォーカスを上へ
+	InputDown                           // フォーカスを下へ
+	InputPrevTarget                     // 前のターゲット
+	InputNextTarget                     // 次のターゲット
+	InputSkipAll                        // メッセージを全て送る
+	InputFastForward                    // 押している間メッセージを早送り
+)
+
+// inputBinding は1つのUI操作に割り当てられたキーとゲームパッドボタンです。
+type inputBinding struct {
+	keys    []ebiten.Key
+	buttons []ebiten.StandardGamepadButton
+}
+
+var inputBindings = map[InputAction]inputBinding{
+	InputConfirm: {
+		keys:    []ebiten.Key{ebiten.KeyEnter, ebiten.KeySpace, ebiten.KeyZ},
+		buttons: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonRightBottom},
+	},
+	InputCancel: {
+		keys:    []ebiten.Key{ebiten.KeyEscape, ebiten.KeyX, ebiten.KeyBackspace},
+		buttons: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonRightRight},
+	},
+	InputUp: {
+		keys:    []ebiten.Key{ebiten.KeyArrowUp},
+		buttons: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonLeftTop},
+	},
+	InputDown: {
+		keys:    []ebiten.Key{ebiten.KeyArrowDown},
+		buttons: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonLeftBottom},
+	},
+	InputPrevTarget: {
+		keys:    []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeyQ},
+		buttons: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonFrontTopLeft, ebiten.StandardGamepadButtonLeftLeft},
+	},
+	InputNextTarget: {
+		keys:    []ebiten.Key{ebiten.KeyArrowRight, ebiten.KeyE},
+		buttons: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonFrontTopRight, ebiten.StandardGamepadButtonLeftRight},
+	},
+	InputSkipAll: {
+		keys:    []ebiten.Key{messageSkipAllKey},
+		buttons: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonCenterRight},
+	},
+	InputFastForward: {
+		keys:    []ebiten.Key{messageFastForwardKey},
+		buttons: []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonFrontBottomRight},
+	},
+}
+
+// anyPlayer は、どのプレイヤーの入力でもよいことを表します。
+const anyPlayer = 0
+
+// isInputJustPressed は指定した操作がこのフレームで押されたかを返します。
+func isInputJustPressed(action InputAction) bool {
+	return isPlayerInputJustPressed(action, anyPlayer)
+}
+
+// isPlayerInputJustPressed は、プレイヤー（1から）が使える入力で指定した操作がこのフレームで押されたかを返します。
+// キーボードは共有で、ゲームパッドはplayerGamepadIDsで割り当てたものだけを見ます。
+func isPlayerInputJustPressed(action InputAction, player int) bool {
+	binding := inputBindings[action]
+	for _, key := range binding.keys {
+		if inpututil.IsKeyJustPressed(key) && !isFullscreenShortcut(key) {
+			return true
+		}
+	}
+	for _, id := range playerGamepadIDs(player) {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		for _, button := range binding.buttons {
+			if inpututil.IsStandardGamepadButtonJustPressed(id, button) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isFullscreenShortcut は、キーがAlt+Enter（フルスクリーンの切り替え）のEnterかを返します。
+// このEnterは決定として扱いません（BattleSettingsSystemが処理する）。
+func isFullscreenShortcut(key ebiten.Key) bool {
+	return key == ebiten.KeyEnter && ebiten.IsKeyPressed(ebiten.KeyAlt)
+}
+
+// isInputPressed は指定した操作が押され続けているかを返します。
+func isInputPressed(action InputAction) bool {
+	binding := inputBindings[action]
+	for _, key := range binding.keys {
+		if ebiten.IsKeyPressed(key) {
+			return true
+		}
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		for _, button := range binding.buttons {
+			if ebiten.IsStandardGamepadButtonPressed(id, button) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// playerGamepadIDs はプレイヤーが使えるゲームパッドを返します。
+// ゲームパッドが2台以上つながっていれば、IDの小さい順（おおむね接続順）に1台目をプレイヤー1、2台目をプレイヤー2に割り当てます。
+// 1台以下の場合やanyPlayerの場合は、すべてのゲームパッドを返します。
+func playerGamepadIDs(player int) []ebiten.GamepadID {
+	ids := ebiten.AppendGamepadIDs(nil)
+	if player == anyPlayer || len(ids) < 2 {
+		return ids
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if player > len(ids) {
+		return nil
+	}
+	return ids[player-1 : player]
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LintIssue はデータファイルの問題1件です。
+type LintIssue struct {
+	File    string // 空の場合はファイルを特定できない問題
+	Line    int    // 0の場合はファイル全体の問題
+	Message string
+}
+
+func (i LintIssue) String() string {
+	if i.File == "" {
+		return i.Message
+	}
+	if i.Line == 0 {
+		return fmt.Sprintf("%s: %s", i.File, i.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", i.File, i.Line, i.Message)
+}
+
+// partIDPrefixes はパーツIDの接頭辞と、それが表す部位です。
+var partIDPrefixes = map[string]PartType{
+	"H-":  PartTypeHead,
+	"RA-": PartTypeRArm,
+	"LA-": PartTypeLArm,
+	"L-":  PartTypeLegs,
+}
+
+// medalSkillColumns はmedals.csvの技能値の列です。
+var medalSkillColumns = []string{"skill_shoot", "skill_fight", "skill_scan", "skill_support"}
+
+// lintIssueAt は出所（"パック名/ファイル名:行" など）と内容から問題を作ります。行番号があれば分けて持ちます。
+func lintIssueAt(source, message string) LintIssue {
+	if i := strings.LastIndex(source, ":"); i >= 0 {
+		if line, err := strconv.Atoi(source[i+1:]); err == nil {
+			return LintIssue{File: source[:i], Line: line, Message: message}
+		}
+	}
+	return LintIssue{File: source, Message: message}
+}
+
+// lintIssuesOf はゲームの読み込み処理が返した誤り（errors.Joinでまとめたものを含む）を問題に変換します。
+func lintIssuesOf(err error) []LintIssue {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var issues []LintIssue
+		for _, e := range joined.Unwrap() {
+			issues = append(issues, lintIssuesOf(e)...)
+		}
+		return issues
+	}
+	var dataErr *dataError
+	if errors.As(err, &dataErr) {
+		return []LintIssue{lintIssueAt(dataErr.Source, dataErr.Message)}
+	}
+	return []LintIssue{{Message: err.Error()}}
+}
+
+// lintDuplicates は重ねた後のレコードで、指定した列の値が重複しているものを報告します。
+func lintDuplicates(table *dataTable, column, what string) []LintIssue {
+	var issues []LintIssue
+	first := make(map[string]string)
+	for _, record := range table.records {
+		value := record.str(column)
+		if value == "" {
+			continue
+		}
+		if source, ok := first[value]; ok {
+			issues = append(issues, lintIssueAt(record[column].Source, fmt.Sprintf("duplicate %s %q (first defined at %s)", what, value, source)))
+			continue
+		}
+		first[value] = record[column].Source
+	}
+	return issues
+}
+
+// lintPart はbuildPartでは誤りにならないが、ゲームで意図どおりに動かないパーツを報告します。
+func lintPart(record dataRecord, part *Part) []LintIssue {
+	var issues []LintIssue
+	report := func(column, format string, args ...any) {
+		issues = append(issues, lintIssueAt(record[column].Source, fmt.Sprintf(format, args...)))
+	}
+	if validPartTypes[part.Type] {
+		prefixMatched := false
+		for prefix, expected := range partIDPrefixes {
+			if strings.HasPrefix(part.ID, prefix) {
+				prefixMatched = true
+				if expected != part.Type {
+					report("part_type", "part %s has part_type %s but its ID prefix %q means %s", part.ID, part.Type, prefix, expected)
+				}
+			}
+		}
+		if !prefixMatched {
+			report("id", "part %s does not start with a known prefix (H-, RA-, LA-, L-)", part.ID)
+		}
+	}
+	if (part.Category == CategoryShoot || part.Category == CategoryFight) && part.Charge.Int() <= 0 {
+		report("charge", "attack part %s has charge %q and can never be selected as an action", part.ID, record.str("charge"))
+	}
+	return issues
+}
+
+// lintMedal はメダルの技能値を検査します。buildMedalは数値でない技能値を0として読むため、ここで報告します。
+func lintMedal(record dataRecord) []LintIssue {
+	var issues []LintIssue
+	id := record.str("id")
+	for _, column := range medalSkillColumns {
+		value := record.str(column)
+		source := record[column].Source
+		if source == "" {
+			source = record["id"].Source
+		}
+		if value == "" {
+			issues = append(issues, lintIssueAt(source, fmt.Sprintf("medal %s has an empty %s", id, column)))
+		} else if _, err := strconv.Atoi(value); err != nil {
+			issues = append(issues, lintIssueAt(source, fmt.Sprintf("medal %s: %s is %q, which is not a number (it would be read as 0)", id, column, value)))
+		}
+	}
+	return issues
+}
+
+// LintCatalogs はUIの文言のカタログを検査し、基準の言語（日本語）にあって他の言語にないキーなどを報告します。
+func LintCatalogs(fsys fs.FS, dir string) []LintIssue {
+	catalogs, err := LoadCatalogs(fsys, dir)
+	if err != nil {
+		return []LintIssue{{File: dir, Message: err.Error()}}
+	}
+	var issues []LintIssue
+	for _, issue := range CheckCatalogs(catalogs) {
+		issues = append(issues, LintIssue{File: path.Join(dir, issue.Language+".json"), Message: fmt.Sprintf("%s: %s", issue.Key, issue.Message)})
+	}
+	return issues
+}
+
+// LintGameData はデータパックをゲームと同じ順・同じ処理（loadDataTablesとbuild*）で重ねて読み込み、
+// 読み込みの誤りと、ゲームで意図どおりに動かないデータを、ファイル・行の順に並べて返します。
+// 文言のカタログは常に埋め込みのものを検査します。
+func LintGameData(packs []DataPack) []LintIssue {
+	tables, err := loadDataTables(packs)
+	issues := lintIssuesOf(err)
+
+	parts := make(map[string]*Part)
+	for _, record := range tables[dataKindParts].records {
+		part, errs := buildPart(record)
+		issues = append(issues, lintIssuesOf(errors.Join(errs...))...)
+		issues = append(issues, lintPart(record, part)...)
+		parts[part.ID] = part
+	}
+	issues = append(issues, lintDuplicates(tables[dataKindParts], "part_name", "part name")...)
+	for _, record := range tables[dataKindMedals].records {
+		issues = append(issues, lintMedal(record)...)
+	}
+	issues = append(issues, lintDuplicates(tables[dataKindMedals], "name_jp", "medal name")...)
+	loadoutIDs := make(map[string]bool)
+	for _, record := range tables[dataKindLoadouts].records {
+		loadout, errs := buildLoadout(record, parts)
+		issues = append(issues, lintIssuesOf(errors.Join(errs...))...)
+		loadoutIDs[loadout.ID] = true
+	}
+	for _, record := range tables[dataKindStages].records {
+		_, errs := buildStage(record, loadoutIDs)
+		issues = append(issues, lintIssuesOf(errors.Join(errs...))...)
+	}
+
+	issues = append(issues, LintCatalogs(embeddedLocales, "locales")...)
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+	return issues
+}
+
+// runLint は "lint" サブコマンドを実行し、終了コードを返します。問題が1件でもあれば1を返します。
+// 検査するデータはゲームの起動時と同じく、埋め込みの基本データにユーザーデータ・-data-dir・MODを重ねたものです。
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	dir := flags.String("data-dir", "", "基本データに重ねるデータのディレクトリ（置き換えるファイルや項目だけでよい）")
+	var modDirs ModDirFlags
+	flags.Var(&modDirs, "mod", "追加で重ねるMODのディレクトリ（複数指定可。mods/以下のMODより後に重ねる）")
+	flags.Parse(args)
+
+	packs := DefaultDataPacks(*dir, "mods", modDirs)
+	for _, pack := range packs[1:] {
+		fmt.Fprintf(os.Stderr, "checking data pack %q from %s\n", pack.Name, pack.Dir)
+	}
+	issues := LintGameData(packs)
+	for _, issue := range issues {
+		fmt.Fprintln(os.Stderr, issue)
+	}
+	if len(issues) > 0 {
+		fmt.Fprintf(os.Stderr, "%d problem(s) found\n", len(issues))
+		return 1
+	}
+	fmt.Println("no problems found")
+	return 0
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLintGameDataAcceptsEmbeddedData(t *testing.T) {
+	if issues := LintGameData([]DataPack{EmbeddedDataPack()}); len(issues) > 0 {
+		t.Errorf("embedded data has problems: %v", issues)
+	}
+}
+
+func TestLintGameDataChecksOverridesAfterLayering(t *testing.T) {
+	partial := fstest.MapFS{"parts.csv": {Data: []byte("id,armor\nH-001,60\n")}}
+	if issues := LintGameData([]DataPack{EmbeddedDataPack(), FSDataPack("data-dir", partial)}); len(issues) > 0 {
+		t.Errorf("partial override has problems: %v", issues)
+	}
+
+	broken := fstest.MapFS{
+		"parts.csv":    {Data: []byte("id,armor,charge\nH-001,NONE,0\n")},
+		"loadouts.csv": {Data: []byte("id,name,head,right_arm,left_arm,legs\nLD-001,x,H-404,RA-001,LA-001,L-001\n")},
+	}
+	got := map[string]bool{}
+	for _, issue := range LintGameData([]DataPack{EmbeddedDataPack(), FSDataPack("data-dir", broken)}) {
+		got[issue.String()] = true
+	}
+	for _, want := range []string{
+		`data-dir/parts.csv:2: column armor: "NONE" is not a number`,
+		`data-dir/parts.csv:2: attack part H-001 has charge "0" and can never be selected as an action`,
+		`data-dir/loadouts.csv:2: column head: unknown part "H-404"`,
+	} {
+		if !got[want] {
+			t.Errorf("missing issue %q; got %v", want, got)
+		}
+	}
+}
+
+func TestRunLintLayersDataDirOverEmbeddedData(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir()) // 開発者のユーザーデータを読まない
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "parts.csv"), []byte("id,armor\nH-001,60\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code := runLint([]string{"-data-dir", dir}); code != 0 {
+		t.Errorf("lint of a partial parts.csv exited with %d, want 0", code)
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// ネット対戦の通信部分。2台の端末をTCPでつなぎ、行動の確定（commit）などを1行1オブジェクトのJSONで送り合います。
+// 戦闘は両方の端末で同じシードから同じように計算するため、送るのはプレイヤーの行動だけです。
+
+// lockstepProtocolVersion は通信の形式の版です。異なる版どうしでは接続しません。
+const lockstepProtocolVersion = 1
+
+const (
+	lockstepDialTimeout  = 5 * time.Second
+	lockstepWriteTimeout = 5 * time.Second
+	lockstepRedialDelay  = time.Second // 切断後に参加側が接続し直すまでの間隔
+)
+
+// LockstepMessageType はメッセージの種類です。
+type LockstepMessageType string
+
+const (
+	LockstepHello   LockstepMessageType = "hello"   // 参加側からホストへの最初のメッセージ
+	LockstepWelcome LockstepMessageType = "welcome" // ホストの返事。シードとステージを伝える
+	LockstepCommit  LockstepMessageType = "commit"  // 行動の確定
+	LockstepHash    LockstepMessageType = "hash"    // 同期ずれの検出のための状態のハッシュ
+	LockstepRestart LockstepMessageType = "restart" // リスタートの要求
+)
+
+// LockstepMessage は端末間で送る1件のメッセージです。
+type LockstepMessage struct {
+	Type LockstepMessageType `json:"type"`
+	// Seq は接続の開始から送った順の通し番号です（hello・welcome以外）。再接続時の再送と重複の判定に使います。
+	Seq   int `json:"seq,omitempty"`
+	Round int `json:"round,omitempty"` // 何戦目か（リスタートのたびに増える）
+	Tick  int `json:"tick,omitempty"`  // シミュレーションのティック（ゲージを進めた回数）
+
+	// commit
+	Entity string      `json:"entity,omitempty"` // 行動する機体のIdentityComponent.ID
+	Slot   PartSlotKey `json:"slot,omitempty"`
+	Target string      `json:"target,omitempty"` // ターゲットのIdentityComponent.ID（なければ空）
+
+	// hash
+	Hash string `json:"hash,omitempty"`
+
+	// hello・welcome
+	Version  int    `json:"version,omitempty"`
+	DataHash string `json:"data_hash,omitempty"` // ゲームデータとバランス設定のハッシュ。一致しなければ対戦できない
+	Seed     int64  `json:"seed,omitempty"`
+	Stage    string `json:"stage,omitempty"`
+	Received int    `json:"received,omitempty"` // 相手から受け取り済みの最後のSeq
+	Error    string `json:"error,omitempty"`    // 接続を断る理由
+}
+
+// LockstepPeer は対戦相手との接続です。切断されると、参加側は接続し直し、ホストは再接続を待ちます。
+// 再接続すると、相手が受け取っていないメッセージを送り直します。
+type LockstepPeer struct {
+	LocalPlayer int    // この端末で操作するプレイヤー（ホストは1、参加側は2）
+	Seed        int64  // 戦闘の乱数のシード（ホストが決める）
+	Stage       string // ホストが選んだステージのID（なければ空）
+
+	dataHash string
+	listener net.Listener // ホストの場合のみ
+	addr     string       // 参加側の場合の接続先
+
+	mu        sync.Mutex
+	conn      net.Conn
+	writer    *bufio.Writer
+	connected bool
+	joined    bool // 一度でも相手が接続したか（ホストの場合、以降は同じ対戦の相手しか受け付けない）
+	closed    bool
+	sent      []LockstepMessage // 送ったメッセージ（Seq順）。再接続時に送り直す
+	received  int               // 受け取り済みの最後のSeq
+	incoming  chan LockstepMessage
+}
+
+func newLockstepPeer(localPlayer int, dataHash string) *LockstepPeer {
+	return &LockstepPeer{LocalPlayer: localPlayer, dataHash: dataHash, incoming: make(chan LockstepMessage, 256)}
+}
+
+// HostLockstep はaddrで待ち受け、最初の対戦相手が接続するまで待ちます。ホストはプレイヤー1（チーム1）です。
+func HostLockstep(addr, dataHash string, seed int64, stage string) (*LockstepPeer, error) {
+	p, err := ListenLockstep(addr, dataHash, seed, stage)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.WaitForOpponent(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ListenLockstep はaddrで待ち受けを始めます。相手の接続はWaitForOpponentで待ちます。
+// addrのポートを0にした場合、実際に待ち受けるアドレスはAddrで分かります。
+func ListenLockstep(addr, dataHash string, seed int64, stage string) (*LockstepPeer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	p := newLockstepPeer(1, dataHash)
+	p.Seed, p.Stage, p.listener = seed, stage, listener
+	return p, nil
+}
+
+// WaitForOpponent は最初の対戦相手が接続するまで待ちます。版やデータが合わずに断った接続の後も待ち続けます。
+// 接続した後は、同じ相手の再接続を受け付けます。
+func (p *LockstepPeer) WaitForOpponent() error {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			p.listener.Close()
+			return err
+		}
+		if err := p.acceptHandshake(conn); err != nil {
+			log.Printf("Rejected connection from %s: %v", conn.RemoteAddr(), err)
+			conn.Close()
+			continue
+		}
+		break
+	}
+	go p.hostLoop()
+	return nil
+}
+
+// Addr はホストが待ち受けているアドレスを返します。参加側ではnilです。
+func (p *LockstepPeer) Addr() net.Addr {
+	if p.listener == nil {
+		return nil
+	}
+	return p.listener.Addr()
+}
+
+// JoinLockstep はaddrのホストに接続します。参加側はプレイヤー2（チーム2）です。
+func JoinLockstep(addr, dataHash string) (*LockstepPeer, error) {
+	p := newLockstepPeer(2, dataHash)
+	p.addr = addr
+	conn, err := net.DialTimeout("tcp", addr, lockstepDialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	if err := p.joinHandshake(conn, true); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go p.joinLoop()
+	return p, nil
+}
+
+// acceptHandshake はホスト側の接続手順です。helloを確かめてwelcomeを返します。
+func (p *LockstepPeer) acceptHandshake(conn net.Conn) error {
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(lockstepDialTimeout))
+	var hello LockstepMessage
+	if err := readLockstepMessage(reader, &hello); err != nil {
+		return err
+	}
+	conn.SetReadDeadline(time.Time{})
+	if hello.Type != LockstepHello {
+		return fmt.Errorf("expected hello, got %q", hello.Type)
+	}
+	welcome := LockstepMessage{Type: LockstepWelcome, Version: lockstepProtocolVersion, DataHash: p.dataHash, Seed: p.Seed, Stage: p.Stage}
+	var reject error
+	switch {
+	case hello.Version != lockstepProtocolVersion:
+		reject = fmt.Errorf("protocol version %d does not match %d", hello.Version, lockstepProtocolVersion)
+	case hello.DataHash != p.dataHash:
+		reject = errors.New("game data or balance settings differ")
+	case hello.Seed != 0 && hello.Seed != p.Seed, p.hasJoined() && hello.Seed != p.Seed:
+		reject = errors.New("the opponent was in a different match")
+	}
+	if reject != nil {
+		welcome.Error = reject.Error()
+		writeLockstepMessage(conn, bufio.NewWriter(conn), welcome)
+		return reject
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	welcome.Received = p.received
+	writer := bufio.NewWriter(conn)
+	if err := writeLockstepMessage(conn, writer, welcome); err != nil {
+		return err
+	}
+	return p.attachLocked(conn, reader, writer, hello.Received)
+}
+
+// joinHandshake は参加側の接続手順です。初回の接続ではホストのシードとステージを受け取ります。
+func (p *LockstepPeer) joinHandshake(conn net.Conn, first bool) error {
+	p.mu.Lock()
+	hello := LockstepMessage{Type: LockstepHello, Version: lockstepProtocolVersion, DataHash: p.dataHash, Seed: p.Seed, Received: p.received}
+	p.mu.Unlock()
+	writer := bufio.NewWriter(conn)
+	if err := writeLockstepMessage(conn, writer, hello); err != nil {
+		return err
+	}
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(lockstepDialTimeout))
+	var welcome LockstepMessage
+	if err := readLockstepMessage(reader, &welcome); err != nil {
+		return err
+	}
+	conn.SetReadDeadline(time.Time{})
+	if welcome.Type != LockstepWelcome {
+		return fmt.Errorf("expected welcome, got %q", welcome.Type)
+	}
+	if welcome.Error != "" {
+		return fmt.Errorf("host refused the connection: %s", welcome.Error)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if first {
+		p.Seed, p.Stage = welcome.Seed, welcome.Stage
+	}
+	return p.attachLocked(conn, reader, writer, welcome.Received)
+}
+
+// attachLocked は接続を使い始め、相手が受け取っていないメッセージを送り直します。p.muを取得した状態で呼びます。
+func (p *LockstepPeer) attachLocked(conn net.Conn, reader *bufio.Reader, writer *bufio.Writer, peerReceived int) error {
+	if peerReceived < 0 || peerReceived > len(p.sent) {
+		return fmt.Errorf("opponent has received %d messages, but only %d were sent", peerReceived, len(p.sent))
+	}
+	for _, msg := range p.sent[peerReceived:] {
+		if err := writeLockstepMessage(conn, writer, msg); err != nil {
+			return err
+		}
+	}
+	// 切断に気づく前に相手が接続し直した場合は、古い接続を捨てる
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	p.conn, p.writer, p.connected, p.joined = conn, writer, true, true
+	go p.readLoop(conn, reader)
+	return nil
+}
+
+// hostLoop は切断されるたびに、同じ相手の再接続を待ちます。
+func (p *LockstepPeer) hostLoop() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			if !p.isClosed() {
+				log.Printf("Stopped accepting opponents: %v", err)
+			}
+			return
+		}
+		if err := p.acceptHandshake(conn); err != nil {
+			log.Printf("Rejected reconnection from %s: %v", conn.RemoteAddr(), err)
+			conn.Close()
+			continue
+		}
+		log.Printf("Opponent reconnected from %s", conn.RemoteAddr())
+	}
+}
+
+// joinLoop は切断されたらホストへ接続し直します。
+func (p *LockstepPeer) joinLoop() {
+	for !p.isClosed() {
+		if p.Connected() {
+			time.Sleep(lockstepRedialDelay / 4)
+			continue
+		}
+		conn, err := net.DialTimeout("tcp", p.addr, lockstepDialTimeout)
+		if err != nil {
+			time.Sleep(lockstepRedialDelay)
+			continue
+		}
+		if err := p.joinHandshake(conn, false); err != nil {
+			log.Printf("Failed to reconnect to %s: %v", p.addr, err)
+			conn.Close()
+			time.Sleep(lockstepRedialDelay)
+			continue
+		}
+		log.Printf("Reconnected to %s", p.addr)
+	}
+}
+
+// readLoop は相手のメッセージを受け取り、まだ受け取っていないものだけをincomingに渡します。
+func (p *LockstepPeer) readLoop(conn net.Conn, reader *bufio.Reader) {
+	for {
+		var msg LockstepMessage
+		if err := readLockstepMessage(reader, &msg); err != nil {
+			if !p.isClosed() {
+				log.Printf("Lost connection to the opponent: %v", err)
+			}
+			p.disconnect(conn)
+			return
+		}
+		p.mu.Lock()
+		duplicate := msg.Seq <= p.received
+		if !duplicate {
+			p.received = msg.Seq
+		}
+		p.mu.Unlock()
+		if !duplicate {
+			p.incoming <- msg
+		}
+	}
+}
+
+// disconnect はconnが現在の接続であれば閉じて、切断された状態にします。
+func (p *LockstepPeer) disconnect(conn net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conn.Close()
+	if p.conn == conn {
+		p.conn, p.writer, p.connected = nil, nil, false
+	}
+}
+
+// Send はメッセージに通し番号を付けて送ります。切断中は再接続したときに送ります。
+func (p *LockstepPeer) Send(msg LockstepMessage) {
+	p.mu.Lock()
+	msg.Seq = len(p.sent) + 1
+	p.sent = append(p.sent, msg)
+	conn, writer := p.conn, p.writer
+	var err error
+	if p.connected {
+		err = writeLockstepMessage(conn, writer, msg)
+	}
+	p.mu.Unlock()
+	if err != nil {
+		log.Printf("Failed to send to the opponent: %v", err)
+		p.disconnect(conn)
+	}
+}
+
+// Receive は受け取ったメッセージを1件返します。なければfalseを返します（待ちません）。
+func (p *LockstepPeer) Receive() (LockstepMessage, bool) {
+	select {
+	case msg := <-p.incoming:
+		return msg, true
+	default:
+		return LockstepMessage{}, false
+	}
+}
+
+// Connected は相手とつながっているかを返します。
+func (p *LockstepPeer) Connected() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.connected
+}
+
+func (p *LockstepPeer) hasJoined() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.joined
+}
+
+func (p *LockstepPeer) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// Close は接続と待ち受けを閉じます。
+func (p *LockstepPeer) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	conn := p.conn
+	p.mu.Unlock()
+	if conn != nil {
+		p.disconnect(conn)
+	}
+	if p.listener != nil {
+		return p.listener.Close()
+	}
+	return nil
+}
+
+// writeLockstepMessage はメッセージを1行のJSONとして書き込みます。
+func writeLockstepMessage(conn net.Conn, writer *bufio.Writer, msg LockstepMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	conn.SetWriteDeadline(time.Now().Add(lockstepWriteTimeout))
+	if _, err := writer.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// readLockstepMessage は1行のJSONを読み込みます。
+func readLockstepMessage(reader *bufio.Reader, msg *LockstepMessage) error {
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(line, msg); err != nil {
+		return fmt.Errorf("invalid message from the opponent: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/ecs"
+	"github.com/yohamta/donburi/filter"
+)
+
+const testDataHash = "test-data"
+
+// startLockstepHost は127.0.0.1の空いているポートでホストを待ち受けさせます。
+// 返すチャネルには、最初の相手が接続したときのWaitForOpponentの結果が届きます。
+func startLockstepHost(t *testing.T, seed int64) (*LockstepPeer, <-chan error) {
+	t.Helper()
+	host, err := ListenLockstep("127.0.0.1:0", testDataHash, seed, "stage-1")
+	if err != nil {
+		t.Fatalf("ListenLockstep: %v", err)
+	}
+	t.Cleanup(func() { host.Close() })
+	accepted := make(chan error, 1)
+	go func() { accepted <- host.WaitForOpponent() }()
+	return host, accepted
+}
+
+// connectLockstep はホストと参加側を同じプロセスで接続します。
+func connectLockstep(t *testing.T, seed int64) (host, joiner *LockstepPeer) {
+	t.Helper()
+	host, accepted := startLockstepHost(t, seed)
+	joiner, err := JoinLockstep(host.Addr().String(), testDataHash)
+	if err != nil {
+		t.Fatalf("JoinLockstep: %v", err)
+	}
+	t.Cleanup(func() { joiner.Close() })
+	if err := <-accepted; err != nil {
+		t.Fatalf("WaitForOpponent: %v", err)
+	}
+	return host, joiner
+}
+
+// receiveLockstep はメッセージが届くまで待ちます。
+func receiveLockstep(t *testing.T, p *LockstepPeer) LockstepMessage {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if msg, ok := p.Receive(); ok {
+			return msg
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("no message from the opponent within 5s")
+	return LockstepMessage{}
+}
+
+// expectNoLockstepMessage は余分なメッセージ（重複など）が届かないことを確かめます。
+func expectNoLockstepMessage(t *testing.T, p *LockstepPeer) {
+	t.Helper()
+	time.Sleep(50 * time.Millisecond)
+	if msg, ok := p.Receive(); ok {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+}
+
+// dropLockstepConnection は相手に知らせずに接続を切ります（回線が切れた場合と同じ）。
+func dropLockstepConnection(p *LockstepPeer) {
+	p.mu.Lock()
+	conn := p.conn
+	p.mu.Unlock()
+	if conn != nil {
+		p.disconnect(conn)
+	}
+}
+
+func TestLockstepLoopbackExchangesCommits(t *testing.T) {
+	host, joiner := connectLockstep(t, 42)
+	if joiner.Seed != 42 || joiner.Stage != "stage-1" {
+		t.Fatalf("joiner got seed %d and stage %q from the host, want 42 and stage-1", joiner.Seed, joiner.Stage)
+	}
+	if host.LocalPlayer != 1 || joiner.LocalPlayer != 2 {
+		t.Fatalf("players = %d/%d, want 1/2", host.LocalPlayer, joiner.LocalPlayer)
+	}
+
+	host.Send(LockstepMessage{Type: LockstepCommit, Tick: 3, Entity: "p1", Slot: PartSlotHead, Target: "p4"})
+	joiner.Send(LockstepMessage{Type: LockstepCommit, Tick: 3, Entity: "p4", Slot: PartSlotLeftArm})
+
+	got := receiveLockstep(t, joiner)
+	if got.Seq != 1 || got.Entity != "p1" || got.Slot != PartSlotHead || got.Target != "p4" {
+		t.Errorf("joiner received %+v", got)
+	}
+	got = receiveLockstep(t, host)
+	if got.Seq != 1 || got.Entity != "p4" || got.Slot != PartSlotLeftArm {
+		t.Errorf("host received %+v", got)
+	}
+}
+
+func TestLockstepReconnectResendsUnreceivedMessages(t *testing.T) {
+	host, joiner := connectLockstep(t, 42)
+	host.Send(LockstepMessage{Type: LockstepCommit, Entity: "p1"})
+	if got := receiveLockstep(t, joiner); got.Entity != "p1" {
+		t.Fatalf("joiner received %+v", got)
+	}
+
+	dropLockstepConnection(joiner)
+	// 切断中（またはホストが切断に気づく前）に送ったメッセージは、再接続したときに送り直される
+	host.Send(LockstepMessage{Type: LockstepCommit, Entity: "p2"})
+	host.Send(LockstepMessage{Type: LockstepCommit, Entity: "p3"})
+	joiner.Send(LockstepMessage{Type: LockstepCommit, Entity: "p4"})
+
+	for i, want := range []string{"p2", "p3"} {
+		got := receiveLockstep(t, joiner)
+		if got.Entity != want || got.Seq != i+2 {
+			t.Errorf("joiner received %+v, want %s with seq %d", got, want, i+2)
+		}
+	}
+	if got := receiveLockstep(t, host); got.Entity != "p4" || got.Seq != 1 {
+		t.Errorf("host received %+v, want p4 with seq 1", got)
+	}
+	expectNoLockstepMessage(t, joiner)
+	expectNoLockstepMessage(t, host)
+	if !host.Connected() || !joiner.Connected() {
+		t.Error("peers are not connected after reconnecting")
+	}
+}
+
+func TestLockstepReadLoopDropsDuplicates(t *testing.T) {
+	p := newLockstepPeer(2, testDataHash)
+	local, remote := net.Pipe()
+	defer remote.Close()
+	go p.readLoop(local, bufio.NewReader(local))
+
+	// 相手が受け取り済みの数を古く覚えていた場合、同じSeqのメッセージが再び届く
+	writer := bufio.NewWriter(remote)
+	for _, seq := range []int{1, 2, 1, 2, 3} {
+		if err := writeLockstepMessage(remote, writer, LockstepMessage{Type: LockstepCommit, Seq: seq}); err != nil {
+			t.Fatalf("write seq %d: %v", seq, err)
+		}
+	}
+	for want := 1; want <= 3; want++ {
+		if got := receiveLockstep(t, p); got.Seq != want {
+			t.Fatalf("received seq %d, want %d", got.Seq, want)
+		}
+	}
+	expectNoLockstepMessage(t, p)
+}
+
+// rawLockstepHandshake はhelloを1つ送り、ホストの返事を返します。
+func rawLockstepHandshake(t *testing.T, addr string, hello LockstepMessage) LockstepMessage {
+	t.Helper()
+	conn, err := net.DialTimeout("tcp", addr, lockstepDialTimeout)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	if err := writeLockstepMessage(conn, bufio.NewWriter(conn), hello); err != nil {
+		t.Fatalf("write hello: %v", err)
+	}
+	var welcome LockstepMessage
+	if err := readLockstepMessage(bufio.NewReader(conn), &welcome); err != nil {
+		t.Fatalf("read welcome: %v", err)
+	}
+	return welcome
+}
+
+func TestLockstepHandshakeRejections(t *testing.T) {
+	host, accepted := startLockstepHost(t, 42)
+	addr := host.Addr().String()
+
+	if _, err := JoinLockstep(addr, "other-data"); err == nil || !strings.Contains(err.Error(), "game data") {
+		t.Errorf("join with different data: err = %v, want a game data error", err)
+	}
+	valid := LockstepMessage{Type: LockstepHello, Version: lockstepProtocolVersion, DataHash: testDataHash}
+	tests := []struct {
+		name  string
+		hello func(LockstepMessage) LockstepMessage
+		want  string
+	}{
+		{"version", func(m LockstepMessage) LockstepMessage { m.Version++; return m }, "protocol version"},
+		{"data", func(m LockstepMessage) LockstepMessage { m.DataHash = "other-data"; return m }, "game data"},
+		{"other match", func(m LockstepMessage) LockstepMessage { m.Seed = 7; return m }, "different match"},
+	}
+	for _, tt := range tests {
+		welcome := rawLockstepHandshake(t, addr, tt.hello(valid))
+		if !strings.Contains(welcome.Error, tt.want) {
+			t.Errorf("%s: welcome error = %q, want it to contain %q", tt.name, welcome.Error, tt.want)
+		}
+	}
+	select {
+	case err := <-accepted:
+		t.Fatalf("host accepted a rejected opponent: %v", err)
+	default:
+	}
+
+	joiner, err := JoinLockstep(addr, testDataHash)
+	if err != nil {
+		t.Fatalf("JoinLockstep: %v", err)
+	}
+	defer joiner.Close()
+	if err := <-accepted; err != nil {
+		t.Fatalf("WaitForOpponent: %v", err)
+	}
+	// 一度対戦が始まったら、シードを持たない（別の対戦の初回の）接続も受け付けない
+	if welcome := rawLockstepHandshake(t, addr, valid); !strings.Contains(welcome.Error, "different match") {
+		t.Errorf("new joiner after the match started: welcome error = %q", welcome.Error)
+	}
+}
+
+// lockstepTestSide はネット対戦の片方の端末です。Game.UpdateのStatePlayingと同じ順にシステムを動かします。
+type lockstepTestSide struct {
+	sys       *LockstepSystem
+	ecs       *ecs.ECS
+	rand      *rand.Rand // 端末ごとの戦闘の乱数。同じプロセスで2台分を動かすため、動かす側に差し替える
+	rule      *GameRuleSystem
+	execution *ActionExecutionSystem
+	gauge     *GaugeUpdateSystem
+	targets   *donburi.Query
+}
+
+func newLockstepTestSide(peer *LockstepPeer, gameData *GameData) *lockstepTestSide {
+	battleRand = rand.New(rand.NewSource(0))
+	sys := NewLockstepSystem(peer, 10) // 編成を決める前にシードをそろえる
+	battle := newTestBattle(gameData)
+	sys.attach(battle.World)
+
+	return &lockstepTestSide{
+		sys:       sys,
+		ecs:       battle,
+		rand:      battleRand,
+		rule:      NewGameRuleSystem(),
+		execution: NewActionExecutionSystem(),
+		gauge:     NewGaugeUpdateSystem(),
+		targets: donburi.NewQuery(filter.And(
+			filter.Contains(IdentityComponentType), filter.Contains(StatusComponentType), filter.Not(filter.Contains(BrokenTag)),
+		)),
+	}
+}
+
+func (s *lockstepTestSide) state() GameState {
+	return GameStateComponentType.Get(GameStateComponentType.MustFirst(s.ecs.World)).CurrentState
+}
+
+// step は1フレーム分進めます。この端末のプレイヤーの代わりに、使える最初のパーツで最初の敵を狙います。
+func (s *lockstepTestSide) step() {
+	battleRand = s.rand
+	s.sys.Update(s.ecs)
+	switch s.state() {
+	case GameStateMessage:
+		advanceMessage(s.ecs)
+		return
+	case StatePlaying:
+	default:
+		return
+	}
+	if s.rule.Update(s.ecs); s.state() != StatePlaying {
+		return
+	}
+	if s.execution.Update(s.ecs); s.state() != StatePlaying {
+		return
+	}
+
+	var ready []*donburi.Entry
+	donburi.NewQuery(filter.And(
+		filter.Contains(PlayerControlledComponentType), filter.Contains(StatusComponentType), filter.Not(filter.Contains(BrokenTag)),
+	)).Each(s.ecs.World, func(entry *donburi.Entry) {
+		if StatusComponentType.Get(entry).State == StateReadyToSelectAction && s.sys.CanSelect(entry) {
+			ready = append(ready, entry)
+		}
+	})
+	for _, entry := range ready {
+		slots := availableActionSlots(PartsComponentType.Get(entry))
+		if len(slots) == 0 {
+			continue
+		}
+		target := donburi.Entity(0)
+		if candidates := opponentCandidates(s.ecs, entry, s.targets); len(candidates) > 0 {
+			target = candidates[0]
+		}
+		s.sys.Commit(entry, slots[0], target)
+	}
+
+	if !s.sys.Ready(s.ecs) {
+		return
+	}
+	s.gauge.Update(s.ecs)
+	s.sys.AfterTick(s.ecs)
+}
+
+func TestLockstepSystemsStayInSync(t *testing.T) {
+	saved := battleRand
+	t.Cleanup(func() { battleRand = saved })
+	gameData := loadTestGameData(t)
+	hostPeer, joinPeer := connectLockstep(t, 20240601)
+	host := newLockstepTestSide(hostPeer, gameData)
+	joiner := newLockstepTestSide(joinPeer, gameData)
+
+	const ticks = 600
+	deadline := time.Now().Add(20 * time.Second)
+	done := func(s *lockstepTestSide) bool { return s.sys.tick >= ticks || s.state() == GameStateOver }
+	for !done(host) || !done(joiner) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out at ticks %d/%d", host.sys.tick, joiner.sys.tick)
+		}
+		for _, side := range []*lockstepTestSide{host, joiner} {
+			if !done(side) {
+				side.step()
+			}
+		}
+	}
+
+	if host.sys.tick != joiner.sys.tick {
+		t.Fatalf("stopped at different ticks: host %d, joiner %d", host.sys.tick, joiner.sys.tick)
+	}
+	if a, b := battleStateHash(host.ecs.World), battleStateHash(joiner.ecs.World); a != b {
+		t.Errorf("state hash differs at tick %d: host %s, joiner %s", host.sys.tick, a, b)
+	}
+	if host.sys.desyncTick >= 0 || joiner.sys.desyncTick >= 0 {
+		t.Errorf("desync detected at tick %d/%d", host.sys.desyncTick, joiner.sys.desyncTick)
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/ecs"
+	"github.com/yohamta/donburi/filter"
+)
+
+// ネット対戦（ロックステップ）: 両方の端末が同じシードで同じ戦闘を計算し、プレイヤーの行動だけを送り合います。
+// ゲージを進めた回数をシミュレーションのティックとし、プレイヤーの機体が行動選択待ちになったティックでは、
+// 両プレイヤーの行動がそろうまでゲージを止めます。そろった行動は機体のID順に適用するため、両方の端末で同じ結果になります。
+
+// NetworkStatusComponent はネット対戦の状態（相手待ち・切断・同期ずれ）を画面に表示するためのシングルトンコンポーネントです。
+type NetworkStatusComponent struct {
+	Text    string
+	IsError bool
+}
+
+var NetworkStatusComponentType = donburi.NewComponentType[NetworkStatusComponent]()
+
+// lockstepKey は行動の確定を、何戦目・どのティック・どの機体かで識別します。
+type lockstepKey struct {
+	round  int
+	tick   int
+	entity string
+}
+
+// lockstepTickKey は状態のハッシュを、何戦目・どのティックかで識別します。
+type lockstepTickKey struct {
+	round int
+	tick  int
+}
+
+// LockstepSystem はネット対戦の行動の送受信、適用のタイミング、同期ずれの検出を担当します。
+// リスタート後も引き継ぎます。
+type LockstepSystem struct {
+	peer         *LockstepPeer
+	hashInterval int // このティック数ごとに状態のハッシュを送る（0以下なら送らない）
+
+	round   int
+	tick    int
+	commits map[lockstepKey]LockstepMessage // 適用待ちの行動（自分と相手の両方）
+
+	localHashes  map[lockstepTickKey]string
+	remoteHashes map[lockstepTickKey]string
+	desyncTick   int // 同期ずれを検出したティック（検出していなければ-1）
+
+	restartSent     bool // この回のリスタートを相手に伝えたか
+	remoteRestarted bool // 相手がこの回をリスタートした
+	waiting         bool // 相手の行動を待っている
+}
+
+// NewLockstepSystem は接続済みの相手とのネット対戦を準備し、戦闘の乱数のシードを設定します。
+func NewLockstepSystem(peer *LockstepPeer, hashInterval int) *LockstepSystem {
+	sys := &LockstepSystem{
+		peer:         peer,
+		hashInterval: hashInterval,
+		commits:      make(map[lockstepKey]LockstepMessage),
+		localHashes:  make(map[lockstepTickKey]string),
+		remoteHashes: make(map[lockstepTickKey]string),
+		desyncTick:   -1,
+	}
+	SeedBattle(peer.Seed)
+	return sys
+}
+
+// attach はゲームのワールドをネット対戦用に設定します。チーム2は相手（参加側）のプレイヤーの機体になります。
+func (sys *LockstepSystem) attach(w donburi.World) {
+	configComp := ConfigComponentType.Get(ConfigComponentType.MustFirst(w))
+	configComp.Match.Networked = true
+	setHotseatControl(w)
+	PlayerActionSelectComponentType.Get(PlayerActionSelectComponentType.MustFirst(w)).Committer = sys
+}
+
+// nextRound はリスタートで次の戦闘に進みます。両方の端末で同じシードになるよう、回数からシードを決め直します。
+func (sys *LockstepSystem) nextRound() {
+	for key := range sys.commits {
+		if key.round <= sys.round {
+			delete(sys.commits, key)
+		}
+	}
+	sys.round++
+	sys.tick = 0
+	sys.restartSent, sys.remoteRestarted, sys.waiting = false, false, false
+	SeedBattle(sys.peer.Seed + int64(sys.round))
+}
+
+// CanSelect はActionCommitterの実装です。この端末のプレイヤーの機体で、このティックの行動をまだ送っていなければtrueです。
+func (sys *LockstepSystem) CanSelect(entry *donburi.Entry) bool {
+	if playerOf(entry) != sys.peer.LocalPlayer {
+		return false
+	}
+	_, sent := sys.commits[lockstepKey{round: sys.round, tick: sys.tick, entity: entityDisplayID(entry)}]
+	return !sent
+}
+
+// Commit はActionCommitterの実装です。行動を相手に送り、両方の行動がそろうまで適用を待ちます。
+func (sys *LockstepSystem) Commit(entry *donburi.Entry, slot PartSlotKey, target donburi.Entity) {
+	msg := LockstepMessage{Type: LockstepCommit, Round: sys.round, Tick: sys.tick, Entity: entityDisplayID(entry), Slot: slot}
+	if entry.World.Valid(target) {
+		msg.Target = entityDisplayID(entry.World.Entry(target))
+	}
+	sys.commits[lockstepKey{round: msg.Round, tick: msg.Tick, entity: msg.Entity}] = msg
+	sys.peer.Send(msg)
+}
+
+// RequestRestart は相手にもリスタートを伝えます。1回の戦闘につき1度だけ送ります。
+func (sys *LockstepSystem) RequestRestart() {
+	if sys.restartSent {
+		return
+	}
+	sys.restartSent = true
+	sys.peer.Send(LockstepMessage{Type: LockstepRestart, Round: sys.round})
+}
+
+// Update は毎フレーム呼ばれ、相手からのメッセージを処理して、画面に出す状態を更新します。
+func (sys *LockstepSystem) Update(ecs *ecs.ECS) {
+	for {
+		msg, ok := sys.peer.Receive()
+		if !ok {
+			break
+		}
+		sys.handleMessage(msg)
+	}
+	if sys.remoteRestarted {
+		GameStateComponentType.Get(GameStateComponentType.MustFirst(ecs.World)).RestartRequested = true
+	}
+	if entry, ok := NetworkStatusComponentType.First(ecs.World); ok {
+		NetworkStatusComponentType.SetValue(entry, sys.status())
+	}
+}
+
+// handleMessage は相手からのメッセージを1件処理します。
+func (sys *LockstepSystem) handleMessage(msg LockstepMessage) {
+	switch msg.Type {
+	case LockstepCommit:
+		if msg.Round < sys.round {
+			return // 既に終わった戦闘の行動
+		}
+		sys.commits[lockstepKey{round: msg.Round, tick: msg.Tick, entity: msg.Entity}] = msg
+	case LockstepHash:
+		key := lockstepTickKey{round: msg.Round, tick: msg.Tick}
+		sys.remoteHashes[key] = msg.Hash
+		sys.compareHashes(key)
+	case LockstepRestart:
+		if msg.Round == sys.round {
+			sys.remoteRestarted = true
+		}
+	default:
+		log.Printf("Ignoring unexpected message %q from the opponent", msg.Type)
+	}
+}
+
+// status は画面に出すネット対戦の状態を返します。
+func (sys *LockstepSystem) status() NetworkStatusComponent {
+	switch {
+	case sys.desyncTick >= 0:
+		return NetworkStatusComponent{Text: T("net.desync", "tick", sys.desyncTick), IsError: true}
+	case !sys.peer.Connected():
+		return NetworkStatusComponent{Text: T("net.disconnected"), IsError: true}
+	case sys.waiting:
+		return NetworkStatusComponent{Text: T("net.waiting")}
+	}
+	return NetworkStatusComponent{}
+}
+
+// Ready はゲージを進めてよいかを返します。行動選択待ちのプレイヤーの機体すべての行動がこのティックでそろっていれば、
+// それらを機体のID順に適用してtrueを返します。そろっていなければfalseを返し、相手を待ちます。
+func (sys *LockstepSystem) Ready(ecs *ecs.ECS) bool {
+	var waitingFor []*donburi.Entry
+	donburi.NewQuery(filter.And(
+		filter.Contains(PlayerControlledComponentType), filter.Contains(StatusComponentType),
+		filter.Contains(PartsComponentType), filter.Not(filter.Contains(BrokenTag)),
+	)).Each(ecs.World, func(entry *donburi.Entry) {
+		// 使えるパーツがない機体は行動を選ばないので待たない
+		if StatusComponentType.Get(entry).State == StateReadyToSelectAction && len(availableActionSlots(PartsComponentType.Get(entry))) > 0 {
+			waitingFor = append(waitingFor, entry)
+		}
+	})
+	sort.Slice(waitingFor, func(i, j int) bool { return entityDisplayID(waitingFor[i]) < entityDisplayID(waitingFor[j]) })
+
+	for _, entry := range waitingFor {
+		if _, ok := sys.commits[lockstepKey{round: sys.round, tick: sys.tick, entity: entityDisplayID(entry)}]; !ok {
+			sys.waiting = true
+			return false
+		}
+	}
+	sys.waiting = false
+	for _, entry := range waitingFor {
+		key := lockstepKey{round: sys.round, tick: sys.tick, entity: entityDisplayID(entry)}
+		sys.applyCommit(ecs.World, entry, sys.commits[key])
+		delete(sys.commits, key)
+	}
+	return true
+}
+
+// applyCommit は確定した行動を機体に適用します。
+// 使えないパーツが指定されていた場合も両方の端末で同じ結果になるよう、使える最初のパーツに置き換えます。
+func (sys *LockstepSystem) applyCommit(w donburi.World, entry *donburi.Entry, msg LockstepMessage) {
+	slots := availableActionSlots(PartsComponentType.Get(entry))
+	slot := slots[0]
+	for _, s := range slots {
+		if s == msg.Slot {
+			slot = s
+		}
+	}
+	if slot != msg.Slot {
+		log.Printf("Commit for %s at tick %d uses unusable slot %q; using %s", msg.Entity, msg.Tick, msg.Slot, slot)
+	}
+	target := donburi.Entity(0)
+	if msg.Target != "" {
+		donburi.NewQuery(filter.Contains(IdentityComponentType)).Each(w, func(e *donburi.Entry) {
+			if IdentityComponentType.Get(e).ID == msg.Target {
+				target = e.Entity()
+			}
+		})
+	}
+	applyActionChoice(entry, slot, target)
+}
+
+// AfterTick はゲージを進めた後に呼ばれ、ティックを数えます。hashIntervalごとに状態のハッシュを相手に送ります。
+func (sys *LockstepSystem) AfterTick(ecs *ecs.ECS) {
+	sys.tick++
+	if sys.hashInterval <= 0 || sys.tick%sys.hashInterval != 0 {
+		return
+	}
+	key := lockstepTickKey{round: sys.round, tick: sys.tick}
+	sys.localHashes[key] = battleStateHash(ecs.World)
+	sys.peer.Send(LockstepMessage{Type: LockstepHash, Round: key.round, Tick: key.tick, Hash: sys.localHashes[key]})
+	sys.compareHashes(key)
+}
+
+// compareHashes は両方の端末のハッシュがそろっていれば比べます。
+func (sys *LockstepSystem) compareHashes(key lockstepTickKey) {
+	local, okLocal := sys.localHashes[key]
+	remote, okRemote := sys.remoteHashes[key]
+	if !okLocal || !okRemote {
+		return
+	}
+	delete(sys.localHashes, key)
+	delete(sys.remoteHashes, key)
+	if local != remote && sys.desyncTick < 0 {
+		sys.desyncTick = key.tick
+		log.Printf("Desync detected in round %d at tick %d: local %s, opponent %s", key.round, key.tick, local, remote)
+	}
+}
+
+// battleStateHash は全機体の状態（StatusComponent）とパーツの装甲から、同期ずれを検出するためのハッシュを作ります。
+func battleStateHash(w donburi.World) string {
+	var entries []*donburi.Entry
+	donburi.NewQuery(filter.And(
+		filter.Contains(IdentityComponentType), filter.Contains(StatusComponentType), filter.Contains(PartsComponentType),
+	)).Each(w, func(entry *donburi.Entry) {
+		entries = append(entries, entry)
+	})
+	sort.Slice(entries, func(i, j int) bool { return entityDisplayID(entries[i]) < entityDisplayID(entries[j]) })
+
+	h := fnv.New64a()
+	for _, entry := range entries {
+		status := StatusComponentType.Get(entry)
+		fmt.Fprintf(h, "%s|%s|%x|%t|%t", entityDisplayID(entry), status.State, math.Float64bits(status.Gauge), status.IsEvasionDisabled, status.IsDefenseDisabled)
+		parts := PartsComponentType.Get(entry)
+		for _, slot := range infoPanelSlots {
+			if part, ok := parts.Parts[slot]; ok && part != nil {
+				fmt.Fprintf(h, "|%s:%d:%t", slot, part.Armor, part.IsBroken)
+			}
+		}
+		h.Write([]byte{'\n'})
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// LockstepDataHash は戦闘の結果に関わるゲームデータとバランス設定のハッシュを返します。
+// 両方の端末で一致しなければ、同じ戦闘を計算できないため対戦しません。
+func LockstepDataHash(gameData *GameData, balance BalanceConfig) string {
+	ids := make([]string, 0, len(gameData.AllParts))
+	for id := range gameData.AllParts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	parts := make([]Part, 0, len(ids))
+	for _, id := range ids {
+		part := *gameData.AllParts[id]
+		part.Sprite = "" // 画像は端末ごとのアセットで変わってよい
+		parts = append(parts, part)
+	}
+	data, err := json.Marshal(struct {
+		Balance  BalanceConfig
+		Parts    []Part
+		Medals   []Medal
+		Loadouts []Loadout
+		Stages   []Stage
+	}{balance, parts, gameData.Medals, gameData.Loadouts, gameData.Stages})
+	if err != nil {
+		log.Printf("Failed to hash game data: %v", err)
+	}
+	h := fnv.New64a()
+	h.Write(data)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
@@ -1,61 +1,79 @@
 package main
 
 import (
-	// "bytes" // opentype.Parseは[]byteを直接受け取るため不要
 	_ "embed" // Required for go:embed
+	"flag"
 	"log"
 	"math/rand"
 	"os"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"golang.org/x/image/font"
-	"golang.org/x/image/font/opentype"
 )
 
 //go:embed MPLUS1p-Regular.ttf
 var mplusFontData []byte
 
-var MplusFont font.Face // This will be accessed by game.go
-
 func loadFont() error {
-	tt, err := opentype.Parse(mplusFontData)
-	if err != nil {
-		return err
-	}
-	const dpi = 72
-	// Adjust font size as needed
-	MplusFont, err = opentype.NewFace(tt, &opentype.FaceOptions{
-		Size:    10,
-		DPI:     dpi,
-		Hinting: font.HintingFull,
-	})
+	fonts, err := NewFontManager(10)
 	if err != nil {
 		return err
 	}
+	Fonts = fonts
 	log.Println("Custom font loaded successfully.")
 	return nil
 }
 
 func main() {
+	// データ検査のサブコマンド: medarot-ebiten lint [-data-dir dir] [-mod dir]（ゲームと同じくデータパックを重ねて検査する）
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(runLint(os.Args[2:]))
+	}
+
+	telemetryPath := flag.String("telemetry", "", "戦闘イベントをJSON Lines形式で書き出すファイルのパス")
+	headless := flag.Bool("headless", false, "ウィンドウを開かずにAI同士で1戦だけ実行する")
+	maxTicks := flag.Int("max-ticks", 100000, "ヘッドレス実行時の最大ティック数")
+	assetsDir := flag.String("assets", "", "スプライトなどのアセットを読み込むディレクトリ（省略時は埋め込みのアセット）")
+	configPath := flag.String("config", "", "デフォルト設定を上書きするJSON設定ファイルのパス（環境変数 MEDAROT_CONFIG でも指定可）")
+	hotReload := flag.Bool("hot-reload", false, "parts.csv・medals.csv・設定ファイルの変更を監視し、戦闘中に反映する")
+	dataDir := flag.String("data-dir", "", "埋め込みの基本データを上書きするparts.csvなどを置いたディレクトリ")
+	stageID := flag.String("stage", "", "stages.csvのステージIDを指定して、その編成で戦う（省略時はランダム編成）")
+	hotseat := flag.Bool("hotseat", false, "1台の端末で2人が交代で操作する対戦にする（チーム2もプレイヤーが操作する）")
+	hideChoices := flag.Bool("hide-choices", false, "ホットシート対戦で、相手が選んだ行動（ターゲットや特性）を表示しない")
+	hostAddr := flag.String("host", "", "ネット対戦のホストとして待ち受けるアドレス（例: :7777）。ホストはチーム1を操作する")
+	joinAddr := flag.String("join", "", "ネット対戦で接続するホストのアドレス（例: 192.168.0.10:7777）。参加側はチーム2を操作する")
+	desyncInterval := flag.Int("desync-check", 60, "ネット対戦で、このティック数ごとに状態のハッシュを比べて同期ずれを検出する")
+	bot1Command := flag.String("bot1", "", "チーム1を操作する外部ボットのコマンド（例: \"python3 bot.py\"）。標準入出力でJSONをやり取りする")
+	bot2Command := flag.String("bot2", "", "チーム2を操作する外部ボットのコマンド")
+	botTimeout := flag.Duration("bot-timeout", time.Second, "外部ボットの応答を待つ時間。過ぎたら組み込みのAIが代わりに行動を選ぶ")
+	spectateAddr := flag.String("spectate", "", "観戦配信のHTTPサーバーを待ち受けるアドレス（例: :8080）。ブラウザで開くとビューアが表示される")
+	spectateInterval := flag.Int("spectate-interval", 6, "観戦配信で、このティック数ごとに戦闘の状態のスナップショットを送る")
+	dataReport := flag.Bool("data-report", false, "各データがどのパック（基本データ・MOD）から読み込まれたかを表示して終了する")
+	var configSets SetFlags
+	var modDirs ModDirFlags
+	flag.Var(&modDirs, "mod", "追加で読み込むMODのディレクトリ（複数指定可。mods/以下のMODより後に読み込む）")
+	flag.Var(&configSets, "set", "設定値を key=value 形式で上書きする（例: --set balance.hit.base_chance=80、複数指定可）")
+	flag.Parse()
+
 	// Load font first
 	if err := loadFont(); err != nil {
 		log.Fatalf("フォントの読み込みに失敗しました: %v", err)
 	}
 
+	// 文言のカタログの不足は表示が崩れるだけなので、警告にとどめる
+	for _, issue := range CheckCatalogs(localizer.catalogs) {
+		log.Printf("Warning: locales/%s.json: %s: %s", issue.Language, issue.Key, issue.Message)
+	}
+
 	// Seed the random number generator
 	rand.Seed(time.Now().UnixNano())
 
-	// Log current working directory
-	wd, err := os.Getwd()
-	if err != nil {
-		log.Printf("Failed to get current working directory: %v", err)
-	} else {
-		log.Printf("Current working directory: %s", wd)
+	// Load game data: 埋め込みの基本データに、ユーザーデータ・-data-dir・MODの順で重ねる
+	packs := DefaultDataPacks(*dataDir, "mods", modDirs)
+	for _, pack := range packs[1:] {
+		log.Printf("Loading data pack %q from %s", pack.Name, pack.Dir)
 	}
-
-	// Load game data
-	gameData, err := LoadAllGameData()
+	gameData, err := LoadAllGameData(packs)
 	if err != nil {
 		log.Fatalf("Failed to load game data: %v", err)
 	}
@@ -63,6 +81,17 @@ func main() {
 		log.Fatal("Game data is nil after loading.")
 	}
 
+	if *dataReport {
+		WriteDataReport(os.Stdout, gameData)
+		return
+	}
+	if *stageID != "" {
+		if err := gameData.SelectStage(*stageID); err != nil {
+			log.Fatalf("Failed to select stage: %v", err)
+		}
+		log.Printf("Stage: %s (%s)", gameData.Stage.Name, gameData.Stage.ID)
+	}
+
 	// Check if crucial data is loaded
 	if len(gameData.Medals) == 0 {
 		log.Println("Warning: No medals were loaded. Medarots might use fallback medals.")
@@ -71,14 +100,158 @@ func main() {
 		log.Println("Warning: No parts were loaded. Medarots might use placeholder parts.")
 	}
 
+	// スプライトを読み込む。失敗した場合は円で描画する
+	assetFS, err := OpenAssetFS(*assetsDir)
+	if err != nil {
+		log.Printf("Failed to open assets, falling back to circles: %v", err)
+	}
+	if !*headless && assetFS != nil {
+		// 追加のフォント（assets/fonts/*.ttf は予備、assets/fonts/<言語>/ はその言語で優先）
+		if err := Fonts.LoadFontDir(assetFS, "fonts"); err != nil {
+			log.Printf("Failed to load fonts: %v", err)
+		}
+		if atlas, manifest, err := LoadSpriteAtlas(assetFS); err != nil {
+			log.Printf("Failed to load sprites, falling back to circles: %v", err)
+		} else {
+			ApplyManifest(gameData.AllParts, manifest)
+			gameData.Sprites = atlas
+		}
+	}
+
 	// ★★★ [変更点] Configをロード ★★★
 	config := LoadConfig()
+	if err := ApplyConfigOverrides(&config, *configPath, configSets); err != nil {
+		log.Fatalf("設定に誤りがあります:\n%v", err)
+	}
+
+	// ネット対戦: 相手と接続し、同じシード・ステージ・データで戦闘を始める
+	var peer *LockstepPeer
+	if *hostAddr != "" || *joinAddr != "" {
+		if *hostAddr != "" && *joinAddr != "" {
+			log.Fatal("-host and -join cannot be used together.")
+		}
+		if *headless || *hotseat || *hotReload {
+			log.Fatal("Network play cannot be combined with -headless, -hotseat or -hot-reload.")
+		}
+		dataHash := LockstepDataHash(gameData, config.Balance)
+		if *hostAddr != "" {
+			stage := ""
+			if gameData.Stage != nil {
+				stage = gameData.Stage.ID
+			}
+			log.Printf("Waiting for an opponent on %s...", *hostAddr)
+			peer, err = HostLockstep(*hostAddr, dataHash, time.Now().UnixNano(), stage)
+		} else {
+			log.Printf("Connecting to %s...", *joinAddr)
+			peer, err = JoinLockstep(*joinAddr, dataHash)
+		}
+		if err != nil {
+			log.Fatalf("Failed to start network play: %v", err)
+		}
+		defer peer.Close()
+		// ステージはホストの選んだものにそろえる
+		gameData.Stage = nil
+		if peer.Stage != "" {
+			if err := gameData.SelectStage(peer.Stage); err != nil {
+				log.Fatalf("Failed to select the host's stage: %v", err)
+			}
+		}
+		log.Printf("Connected. You are player %d.", peer.LocalPlayer)
+	}
+	// 外部ボット: 編成を決める前に起動しておく（起動に失敗したら対戦を始めない）
+	var bots *BotSystem
+	if *bot1Command != "" || *bot2Command != "" {
+		if peer != nil || *hotseat {
+			log.Fatal("-bot1 and -bot2 cannot be combined with network play or -hotseat.")
+		}
+		processes := map[TeamID]*BotProcess{}
+		for team, command := range map[TeamID]string{Team1: *bot1Command, Team2: *bot2Command} {
+			if command == "" {
+				continue
+			}
+			bot, err := StartBotProcess(command)
+			if err != nil {
+				log.Fatalf("Failed to start bot for team %d: %v", team+1, err)
+			}
+			processes[team] = bot
+			log.Printf("Team %d is controlled by bot: %s", team+1, command)
+		}
+		bots = NewBotSystem(processes, *botTimeout)
+		defer bots.Close()
+	}
+	var lockstep *LockstepSystem
+	if peer != nil {
+		lockstep = NewLockstepSystem(peer, *desyncInterval) // 編成を決める前に戦闘の乱数のシードをそろえる
+	}
 
 	// Create a new game instance
 	game := NewGame(gameData, config) // 引数にconfigを追加
 	if game == nil {
 		log.Fatal("Failed to create new game instance.")
 	}
+	// プレイヤー設定（戦闘速度など）を読み込む。失敗してもデフォルト設定で続行する
+	// ヘッドレスでは同じシードで同じ結果になるよう、保存された設定は読まない
+	if *headless {
+		game.SetSettings(DefaultSettings())
+	} else if settingsPath, err := DefaultSettingsPath(); err != nil {
+		log.Printf("Settings will not be saved: %v", err)
+	} else {
+		settings, err := LoadSettings(settingsPath)
+		if err != nil {
+			log.Printf("Failed to load settings, using defaults: %v", err)
+		}
+		game.SetSettings(settings)
+	}
+	game.SetMatch(MatchOptions{Hotseat: *hotseat, HideOpponentChoices: *hideChoices})
+	if lockstep != nil {
+		game.EnableLockstep(lockstep)
+	}
+	if bots != nil {
+		game.EnableBots(bots)
+	}
+	if *hotseat {
+		log.Printf("Hotseat match: team 1 is player 1, team 2 is player 2 (hide choices: %t)", *hideChoices)
+	}
+	if !*headless {
+		game.SetThemes(LoadThemes(config.UI, assetFS))
+	}
+	if *hotReload {
+		watchedConfig := *configPath
+		if watchedConfig == "" {
+			watchedConfig = os.Getenv(configPathEnv)
+		}
+		game.EnableHotReload(NewHotReloadSystem(packs, watchedConfig, configSets))
+		log.Println("Hot reload enabled.")
+	}
+
+	var recorder *TelemetryRecorder
+	if *telemetryPath != "" {
+		recorder, err = NewTelemetryRecorder(*telemetryPath)
+		if err != nil {
+			log.Fatalf("Failed to start telemetry: %v", err)
+		}
+		defer recorder.Close()
+		game.AddCombatEventListener(recorder)
+		log.Printf("Recording combat telemetry to %s", *telemetryPath)
+	}
+
+	if *spectateAddr != "" {
+		spectator, err := StartSpectatorServer(*spectateAddr, *spectateInterval)
+		if err != nil {
+			log.Fatalf("Failed to start spectator server: %v", err)
+		}
+		defer spectator.Close()
+		game.EnableSpectator(spectator)
+		log.Printf("Spectator stream: http://%s/", spectator.Addr())
+	}
+
+	if *headless {
+		if _, _, err := RunHeadless(game, *maxTicks); err != nil {
+			log.Printf("Headless battle failed: %v", err)
+		}
+		return
+	}
+
 	// game.Medarots はECS化により削除されたため、このチェックは不要。
 	// エンティティ数のチェックは NewGame 内で行われる。
 	// if len(game.Medarots) == 0 {
@@ -87,10 +260,22 @@ func main() {
 
 	// ★★★ [修正] Configからウィンドウサイズを設定 ★★★
 	ebiten.SetWindowSize(config.UI.Screen.Width, config.UI.Screen.Height)
-	ebiten.SetWindowTitle("メダロット風ゲーム (Ebitengine)")
+	ebiten.SetWindowSizeLimits(minScreenWidth/2, minScreenHeight/2, -1, -1)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetWindowTitle(T("window.title"))
+	if settings := game.Settings(); settings != nil {
+		ebiten.SetFullscreen(settings.Fullscreen)
+	}
 
 	// Start the game loop
-	if err := ebiten.RunGame(game); err != nil {
+	err = ebiten.RunGame(game)
+	if recorder != nil {
+		recorder.Close() // log.Fatalではdeferが実行されないため、先に閉じる
+	}
+	if bots != nil && err != nil {
+		bots.Close()
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/ecs"
+)
+
+// loadTestGameData は埋め込みの基本データを読み込みます。
+func loadTestGameData(t *testing.T) *GameData {
+	t.Helper()
+	gameData, err := LoadAllGameData([]DataPack{EmbeddedDataPack()})
+	if err != nil {
+		t.Fatalf("LoadAllGameData: %v", err)
+	}
+	return gameData
+}
+
+// newTestBattle はNewGameと同じシングルトンと機体を持つワールドを、描画なしで作ります。
+// チーム1はプレイヤー1、チーム2はAIが操作します。
+func newTestBattle(gameData *GameData) *ecs.ECS {
+	world := donburi.NewWorld()
+	gsEntry := world.Entry(world.Create(GameStateComponentType, ConfigComponentType, PlayerActionSelectComponentType, CombatEventComponentType, NetworkStatusComponentType))
+	GameStateComponentType.SetValue(gsEntry, GameStateComponent{CurrentState: StatePlaying})
+	config := LoadConfig()
+	ConfigComponentType.SetValue(gsEntry, ConfigComponent{GameConfig: &config, GameData: gameData, Settings: DefaultSettings()})
+	InitializeAllMedarotEntities(world, gameData)
+	return ecs.NewECS(world)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/yohamta/donburi"
+)
+
+// partSlotMessageKeys はスロットの表示名のカタログキー（"slot." と "slot.short." に続く部分）です。
+var partSlotMessageKeys = map[PartSlotKey]string{
+	PartSlotHead:     "head",
+	PartSlotRightArm: "right_arm",
+	PartSlotLeftArm:  "left_arm",
+	PartSlotLegs:     "legs",
+}
+
+// partSlotLabel はスロットの表示名を返します。shortの場合は情報パネル用の短い表記です。
+func partSlotLabel(slot PartSlotKey, short bool) string {
+	key, ok := partSlotMessageKeys[slot]
+	if !ok {
+		return string(slot)
+	}
+	if short {
+		return T("slot.short." + key)
+	}
+	return T("slot." + key)
+}
+
+// medarotDetailLines は詳細画面に表示する内容を、左列（メダルとパーツ）と右列（合計値・状態・行動履歴）に分けて返します。
+// speedはgaugeSpeedMultiplierの倍率で、充填・冷却の秒数を実際のゲージの進み方に合わせます。
+func medarotDetailLines(entry *donburi.Entry, config *Config, speed float64) (left, right []string) {
+	identity := IdentityComponentType.Get(entry)
+	status := visibleStatus(entry.World, entry) // ホットシート対戦で相手の行動を隠す場合は、特性による異常も出さない
+	parts := PartsComponentType.Get(entry)
+
+	teamStr := T("team.name", "team", 1)
+	if identity.Team == Team2 {
+		teamStr = T("team.name", "team", 2)
+	}
+	title := T("detail.title", "name", identity.Name, "team", teamStr)
+	if identity.IsLeader {
+		title = T("detail.title_leader", "name", identity.Name, "team", teamStr)
+	}
+	left = append(left, title, "")
+
+	// メダル
+	if entry.HasComponent(CMedal) {
+		if medal := CMedal.Get(entry).Medal; medal != nil {
+			left = append(left, medarotTooltipLines(identity, medal)[1:]...)
+			left = append(left, "")
+		}
+	}
+
+	// パーツ
+	var legs *Part
+	totalArmor, totalMaxArmor := 0, 0
+	for _, slotKey := range infoPanelSlots {
+		part, ok := parts.Parts[slotKey]
+		if !ok || part == nil {
+			left = append(left, T("detail.no_part", "slot", partSlotLabel(slotKey, false)))
+			continue
+		}
+		if slotKey == PartSlotLegs {
+			legs = part
+		}
+		totalArmor += part.Armor
+		totalMaxArmor += part.MaxArmor
+		partLines := partTooltipLines(part)
+		left = append(left, fmt.Sprintf("%s: %s", partSlotLabel(slotKey, false), partLines[0]))
+		for _, line := range partLines[1:] {
+			left = append(left, "  "+line)
+		}
+	}
+
+	// 合計値
+	right = append(right, T("detail.totals"), T("detail.total_armor", "armor", totalArmor, "max", totalMaxArmor))
+	legPropulsion := 0
+	if legs != nil && !legs.IsBroken {
+		legPropulsion = legs.Propulsion.Int()
+	}
+	rate := config.Balance.Time.PropulsionEffectRate
+	right = append(right, T("detail.propulsion", "effect", fmt.Sprintf("%.1f", float64(legPropulsion)*rate), "propulsion", legPropulsion, "rate", fmt.Sprintf("%.2f", rate)))
+	for _, slotKey := range []PartSlotKey{PartSlotHead, PartSlotRightArm, PartSlotLeftArm} {
+		part, ok := parts.Parts[slotKey]
+		if !ok || part == nil || part.IsBroken || part.Charge.Int() <= 0 {
+			continue
+		}
+		right = append(right, T("detail.charge_cooldown", "slot", partSlotLabel(slotKey, false),
+			"charge", formatGaugeSeconds(part.Charge.Int(), legPropulsion, config.Balance, speed),
+			"cooldown", formatGaugeSeconds(part.Cooldown.Int(), legPropulsion, config.Balance, speed)))
+	}
+
+	// 状態
+	right = append(right, "", T("detail.status"), T("detail.gauge", "state", status.State, "gauge", fmt.Sprintf("%.0f", status.Gauge)))
+	if status.IsEvasionDisabled {
+		right = append(right, T("detail.evasion_disabled"))
+	}
+	if status.IsDefenseDisabled {
+		right = append(right, T("detail.defense_disabled"))
+	}
+	if !status.IsEvasionDisabled && !status.IsDefenseDisabled {
+		right = append(right, T("detail.no_ailments"))
+	}
+	if status.State == StateActionCharging && entry.HasComponent(ActionComponentType) {
+		right = append(right, chargingActionLine(entry))
+	}
+
+	// 行動履歴
+	right = append(right, "", T("detail.recent_actions"))
+	history := []string{}
+	if entry.HasComponent(ActionComponentType) {
+		history = ActionComponentType.Get(entry).ActionHistory
+	}
+	if len(history) == 0 {
+		right = append(right, T("detail.no_actions"))
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		right = append(right, "・"+history[i])
+	}
+	return left, right
+}
+
+// chargingActionLine は充填中の行動（パーツとターゲット）を表す行を返します。
+func chargingActionLine(entry *donburi.Entry) string {
+	if choicesHidden(entry.World, entry) {
+		return T("detail.charging_hidden")
+	}
+	action := ActionComponentType.Get(entry)
+	partName := string(action.SelectedPartKey)
+	if part, ok := PartsComponentType.Get(entry).Parts[action.SelectedPartKey]; ok && part != nil {
+		partName = part.DisplayName()
+	}
+	targetName := "-"
+	if entry.World.Valid(action.TargetedMedarot) {
+		targetName = IdentityComponentType.Get(entry.World.Entry(action.TargetedMedarot)).Name
+	}
+	return T("detail.charging", "part", partName, "target", targetName)
+}
+
+// formatGaugeSeconds は、ゲージが0から100まで溜まるのにかかる秒数を整形します。
+func formatGaugeSeconds(baseStat, legPropulsion int, cfg BalanceConfig, speed float64) string {
+	step := gaugeStepPerTick(baseStat, legPropulsion, cfg) * speed
+	if step <= 0 {
+		return "-"
+	}
+	seconds := 100 / step / float64(ebiten.TPS())
+	return T("detail.seconds", "seconds", fmt.Sprintf("%.1f", seconds))
+}
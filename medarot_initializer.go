@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"log"
-	"math/rand"
 
 	"github.com/yohamta/donburi"
 	//"github.com/yohamta/donburi/features/math"
@@ -11,20 +10,41 @@ import (
 
 const PlayersPerTeam = 3
 
-type DefaultLoadout struct {
+// Loadout は1体分のパーツの組み合わせです（loadouts.csv）。
+type Loadout struct {
+	ID       string
+	Name     string
 	Head     string
 	RightArm string
 	LeftArm  string
 	Legs     string
 }
 
-var defaultLoadouts = []DefaultLoadout{
-	{Head: "H-001", RightArm: "RA-001", LeftArm: "LA-001", Legs: "L-001"},
-	{Head: "H-002", RightArm: "RA-002", LeftArm: "LA-002", Legs: "L-002"},
-	{Head: "H-003", RightArm: "RA-003", LeftArm: "LA-003", Legs: "L-003"},
-	{Head: "H-004", RightArm: "RA-004", LeftArm: "LA-004", Legs: "L-004"},
-	{Head: "H-005", RightArm: "RA-005", LeftArm: "LA-005", Legs: "L-005"},
-	{Head: "H-006", RightArm: "RA-006", LeftArm: "LA-006", Legs: "L-006"},
+// Stage はチームごとの編成を決めたステージです（stages.csv）。
+// Team1・Team2には機体の順にLoadoutのIDを並べ、足りない場合は先頭から繰り返します。
+type Stage struct {
+	ID    string
+	Name  string
+	Team1 []string
+	Team2 []string
+}
+
+// chooseLoadout は機体のパーツの組み合わせを決めます。
+// ステージが選ばれていればその編成を、なければランダムに選びます（チーム1のリーダーは先頭の組み合わせ）。
+func chooseLoadout(gameData *GameData, teamID TeamID, index int, isLeader bool) Loadout {
+	if stage := gameData.Stage; stage != nil {
+		lineup := stage.Team1
+		if teamID == Team2 {
+			lineup = stage.Team2
+		}
+		if loadout, ok := gameData.findLoadout(lineup[index%len(lineup)]); ok {
+			return loadout
+		}
+	}
+	if teamID == Team1 && isLeader {
+		return gameData.Loadouts[0]
+	}
+	return gameData.Loadouts[battleRand.Intn(len(gameData.Loadouts))]
 }
 
 // findPartByID はパーツIDでパーツを検索し、コピーを返します。
@@ -55,13 +75,14 @@ func findMedalByID(medals []Medal, id string) *Medal {
 
 func createMedarotEntity(w donburi.World, teamID TeamID, medarotNumber int, isLeader bool, gameData *GameData, drawIndex int) donburi.Entity {
 	medarotDisplayID := fmt.Sprintf("p%d", medarotNumber)
-	medarotName := fmt.Sprintf("機体 %d", medarotNumber)
+	medarotName := T("medarot.name", "number", medarotNumber)
 
-	entity := w.Create(IdentityComponentType, CMedal, PartsComponentType, StatusComponentType, ActionComponentType, RenderComponentType)
+	entity := w.Create(IdentityComponentType, CMedal, PartsComponentType, StatusComponentType, ActionComponentType, RenderComponentType, ArmorDisplayComponentType)
 
 	// IdentityComponent
 	IdentityComponentType.SetValue(w.Entry(entity), IdentityComponent{
 		ID:       medarotDisplayID,
+		Number:   medarotNumber,
 		Name:     medarotName,
 		Team:     teamID,
 		IsLeader: isLeader,
@@ -69,17 +90,16 @@ func createMedarotEntity(w donburi.World, teamID TeamID, medarotNumber int, isLe
 
 	// MedalComponent & PartsComponent
 	var selectedMedal *Medal // This Medal is models.Medal
-	var partsConfig DefaultLoadout = defaultLoadouts[rand.Intn(len(defaultLoadouts))]
+	partsConfig := chooseLoadout(gameData, teamID, drawIndex, isLeader)
 
 	if teamID == Team1 && isLeader {
 		metabeeMedal := findMedalByID(gameData.Medals, "M001")
 		if metabeeMedal != nil {
 			selectedMedal = metabeeMedal
 		}
-		partsConfig = defaultLoadouts[0]
 	} else {
 		if len(gameData.Medals) > 0 {
-			medalIndex := rand.Intn(len(gameData.Medals))
+			medalIndex := battleRand.Intn(len(gameData.Medals))
 			selectedMedal = &gameData.Medals[medalIndex]
 		}
 	}
@@ -110,6 +130,13 @@ func createMedarotEntity(w donburi.World, teamID TeamID, medarotNumber int, isLe
 	}
 	PartsComponentType.SetValue(w.Entry(entity), PartsComponent{Parts: partsMap})
 
+	// ArmorDisplayComponent (装甲バーの表示値は実際の装甲値から始める)
+	armorDisplay := ArmorDisplayComponent{Values: make(map[PartSlotKey]float64, len(partsMap))}
+	for slot, part := range partsMap {
+		armorDisplay.Values[slot] = float64(part.Armor)
+	}
+	ArmorDisplayComponentType.SetValue(w.Entry(entity), armorDisplay)
+
 	// StatusComponent
 	StatusComponentType.SetValue(w.Entry(entity), StatusComponent{
 		State:             StateReadyToSelectAction,
@@ -128,7 +155,7 @@ func createMedarotEntity(w donburi.World, teamID TeamID, medarotNumber int, isLe
 	// w.AddComponentではなく、w.Entry(entity).AddComponent を使用する
 	medarotEntry := w.Entry(entity) // Entryを一度取得
 	if teamID == Team1 {
-		medarotEntry.AddComponent(PlayerControlledComponentType)
+		donburi.Add(medarotEntry, PlayerControlledComponentType, &PlayerControlledComponent{Player: 1})
 	} else {
 		medarotEntry.AddComponent(AIControlledComponentType)
 	}
@@ -168,7 +195,7 @@ func logMedarotInitialization(entity donburi.Entity, w donburi.World) {
 	log.Printf("  - EntityID: %d, Name: %s (%s), Leader: %t, Medal: %s", entity.Id(), identity.Name, teamStr, identity.IsLeader, medalComp.Medal.Name)
 	for slot, part := range partsComp.Parts {
 		if part != nil {
-			log.Printf("    %s: %s (Armor: %d/%d, Pow: %d)", string(slot), part.PartName, part.Armor, part.MaxArmor, part.Power)
+			log.Printf("    %s: %s (Armor: %d/%d, Pow: %v)", string(slot), part.PartName, part.Armor, part.MaxArmor, part.Power)
 		} else {
 			log.Printf("    %s: <NONE>", string(slot))
 		}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"image"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/ecs"
+	"github.com/yohamta/donburi/filter"
+)
+
+const pauseKey = ebiten.KeyP // ポーズメニューの開閉
+
+// MenuSystem はポーズメニューとメダロットの詳細画面の開閉・操作を担当します。
+type MenuSystem struct {
+	medarotQuery *donburi.Query
+}
+
+func NewMenuSystem() *MenuSystem {
+	return &MenuSystem{
+		medarotQuery: donburi.NewQuery(filter.And(
+			filter.Contains(IdentityComponentType), filter.Contains(StatusComponentType),
+			filter.Contains(RenderComponentType), filter.Contains(PartsComponentType),
+		)),
+	}
+}
+
+func (sys *MenuSystem) Update(ecs *ecs.ECS) {
+	gameStateEntry, ok := GameStateComponentType.First(ecs.World)
+	if !ok {
+		return
+	}
+	gs := GameStateComponentType.Get(gameStateEntry)
+	config := ConfigComponentType.Get(ConfigComponentType.MustFirst(ecs.World)).GameConfig
+
+	switch gs.CurrentState {
+	case StatePlaying, StatePlayerActionSelect, GameStateMessage:
+		if isPauseJustPressed() {
+			gs.PauseReturnState = gs.CurrentState
+			gs.MenuIndex = 0
+			gs.CurrentState = StatePaused
+			return
+		}
+		// 戦闘進行中は情報パネルのクリックで詳細画面を開く（メッセージ表示中のクリックはメッセージ送りに使う）
+		if gs.CurrentState == StatePlaying && inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+			cursor := cursorPosition()
+			if target, ok := sys.medarotUnderCursor(ecs, config, cursor); ok {
+				openMedarotDetail(gs, target, StatePlaying)
+			}
+		}
+	case StatePaused:
+		sys.updatePauseMenu(ecs, gs, config)
+	case StateMedarotDetail:
+		clicked := inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && !isCursorOnHUD(config)
+		if clicked || isInputJustPressed(InputCancel) || isInputJustPressed(InputConfirm) || isPauseJustPressed() {
+			gs.CurrentState = gs.DetailReturnState
+		}
+	}
+}
+
+// updatePauseMenu はポーズメニューのフォーカス移動と決定を処理します。
+func (sys *MenuSystem) updatePauseMenu(ecs *ecs.ECS, gs *GameStateComponent, config *Config) {
+	items := pauseMenuItems(ecs, sys.medarotQuery)
+	if isPauseJustPressed() || isInputJustPressed(InputCancel) {
+		gs.CurrentState = gs.PauseReturnState
+		return
+	}
+
+	selected := -1
+	switch {
+	case isInputJustPressed(InputUp):
+		gs.MenuIndex = (gs.MenuIndex - 1 + len(items)) % len(items)
+	case isInputJustPressed(InputDown):
+		gs.MenuIndex = (gs.MenuIndex + 1) % len(items)
+	case isInputJustPressed(InputConfirm):
+		selected = gs.MenuIndex
+	case inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft):
+		cursor := cursorPosition()
+		for i := range items {
+			if cursor.In(pauseMenuItemRect(&config.UI, i, len(items))) {
+				gs.MenuIndex = i
+				selected = i
+				break
+			}
+		}
+	}
+	if selected < 0 {
+		return
+	}
+
+	item := items[selected]
+	switch item.Kind {
+	case PauseMenuResume:
+		gs.CurrentState = gs.PauseReturnState
+	case PauseMenuDetail:
+		// 詳細画面を閉じたらポーズメニューに戻る
+		openMedarotDetail(gs, item.Entity, StatePaused)
+	case PauseMenuRestart:
+		gs.RestartRequested = true
+	}
+}
+
+// medarotUnderCursor はカーソルが乗っている情報パネルのメダロットを返します。
+func (sys *MenuSystem) medarotUnderCursor(ecs *ecs.ECS, config *Config, cursor image.Point) (donburi.Entity, bool) {
+	var found donburi.Entity
+	ok := false
+	sys.medarotQuery.Each(ecs.World, func(entry *donburi.Entry) {
+		if ok {
+			return
+		}
+		if cursor.In(infoPanelRect(config, IdentityComponentType.Get(entry), RenderComponentType.Get(entry))) {
+			found, ok = entry.Entity(), true
+		}
+	})
+	return found, ok
+}
+
+// openMedarotDetail は詳細画面に切り替えます。
+func openMedarotDetail(gs *GameStateComponent, target donburi.Entity, returnState GameState) {
+	gs.DetailTarget = target
+	gs.DetailReturnState = returnState
+	gs.CurrentState = StateMedarotDetail
+}
+
+// isPauseJustPressed はポーズの開閉操作（Pキー / ゲームパッドのセレクトボタン）を判定します。
+func isPauseJustPressed() bool {
+	if inpututil.IsKeyJustPressed(pauseKey) {
+		return true
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if ebiten.IsStandardGamepadLayoutAvailable(id) && inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonCenterLeft) {
+			return true
+		}
+	}
+	return false
+}
+
+// PauseMenuItemKind はポーズメニューの項目の種類です。
+type PauseMenuItemKind int
+
+const (
+	PauseMenuResume PauseMenuItemKind = iota
+	PauseMenuDetail
+	PauseMenuRestart
+)
+
+// PauseMenuItem はポーズメニューの1項目です。
+type PauseMenuItem struct {
+	Kind   PauseMenuItemKind
+	Label  string
+	Entity donburi.Entity // PauseMenuDetailの場合の対象
+}
+
+// pauseMenuItems は「再開」「各メダロットの詳細」「リスタート」の順に項目を並べます。
+// ネット対戦では、戦闘の途中で片方の端末だけが作り直されないよう、リスタートは決着後の画面からだけ行います。
+func pauseMenuItems(ecs *ecs.ECS, medarotQuery *donburi.Query) []PauseMenuItem {
+	var entries []*donburi.Entry
+	medarotQuery.Each(ecs.World, func(entry *donburi.Entry) {
+		entries = append(entries, entry)
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		ti, tj := IdentityComponentType.Get(entries[i]).Team, IdentityComponentType.Get(entries[j]).Team
+		if ti != tj {
+			return ti < tj
+		}
+		return drawIndexOf(entries[i]) < drawIndexOf(entries[j])
+	})
+
+	items := []PauseMenuItem{{Kind: PauseMenuResume, Label: T("menu.resume")}}
+	for _, entry := range entries {
+		items = append(items, PauseMenuItem{Kind: PauseMenuDetail, Label: T("menu.detail", "name", IdentityComponentType.Get(entry).Name), Entity: entry.Entity()})
+	}
+	if configEntry, ok := ConfigComponentType.First(ecs.World); ok && ConfigComponentType.Get(configEntry).Match.Networked {
+		return items
+	}
+	return append(items, PauseMenuItem{Kind: PauseMenuRestart, Label: T("menu.restart")})
+}
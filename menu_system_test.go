@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestPauseMenuOmitsRestartInNetworkedMatches(t *testing.T) {
+	battle := newTestBattle(loadTestGameData(t))
+	hasRestart := func() bool {
+		for _, item := range pauseMenuItems(battle, NewMenuSystem().medarotQuery) {
+			if item.Kind == PauseMenuRestart {
+				return true
+			}
+		}
+		return false
+	}
+	if !hasRestart() {
+		t.Error("the pause menu has no restart item")
+	}
+
+	// ネット対戦の途中で片方だけが作り直されると、相手の端末と戦闘がずれる
+	ConfigComponentType.Get(ConfigComponentType.MustFirst(battle.World)).Match.Networked = true
+	if hasRestart() {
+		t.Error("the pause menu offers restart in a networked match")
+	}
+}
@@ -7,6 +7,13 @@ import (
 	"github.com/yohamta/donburi/ecs"
 )
 
+const (
+	messageSkipAllKey     = ebiten.KeyS       // キュー内のメッセージをすべて送る
+	messageFastForwardKey = ebiten.KeyControl // 押している間メッセージを早送りする
+	maxSkipAllMessages    = 1000              // コールバックが延々とメッセージを積む場合の安全装置
+)
+
+// MessageSystem はメッセージキューを先頭から順に表示し、クリック・自動送り・早送りで進めます。
 type MessageSystem struct{}
 
 func NewMessageSystem() *MessageSystem { return &MessageSystem{} }
@@ -21,18 +28,108 @@ func (sys *MessageSystem) Update(ecs *ecs.ECS) {
 	if gs.CurrentState != GameStateMessage {
 		return
 	}
+	if !discardGuardedMessages(gs) {
+		gs.CurrentState = StatePlaying
+		GameStateComponentType.Set(gameStateEntry, gs)
+		return
+	}
 
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
-		callback := gs.PostMessageCallback
-		gs.PostMessageCallback = nil
+	configComp := ConfigComponentType.Get(ConfigComponentType.MustFirst(ecs.World))
+	msgConfig := configComp.GameConfig.UI.Message
+	head := gs.MessageQueue[0]
+	// 攻撃の演出が終わるまでは自動送り・早送りの時間を数えない
+	if !animationsPlaying(ecs.World) {
+		gs.MessageTicks++
+	}
 
-		// コールバックがあれば実行し、なければ状態をPlayingに戻す
-		if callback != nil {
-			GameStateComponentType.Set(gameStateEntry, gs) // 先にgsを保存
-			callback()
-		} else {
+	// プレイヤー設定のメッセージモードを反映する
+	autoAdvance := head.AutoAdvance
+	if settings := configComp.Settings; settings != nil {
+		switch settings.MessageMode {
+		case MessageModeInstant:
+			skipAllMessages(ecs)
+			return
+		case MessageModeAuto:
+			if autoAdvance == 0 {
+				autoAdvance = messageAutoAdvanceTicks(settings)
+			}
+		}
+	}
+
+	switch {
+	case isInputJustPressed(InputSkipAll):
+		skipAllMessages(ecs)
+	case inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && !isCursorOnHUD(configComp.GameConfig):
+		advanceMessage(ecs)
+	case isInputJustPressed(InputConfirm):
+		advanceMessage(ecs)
+	case autoAdvance > 0 && gs.MessageTicks >= autoAdvance:
+		advanceMessage(ecs)
+	case msgConfig.HoldToFastForward && isInputPressed(InputFastForward) && gs.MessageTicks >= msgConfig.FastForwardInterval:
+		advanceMessage(ecs)
+	}
+}
+
+// discardGuardedMessages はGuardがfalseを返す先頭のメッセージを破棄します。
+// 表示すべきメッセージが残っていればtrueを返します。
+func discardGuardedMessages(gs *GameStateComponent) bool {
+	for len(gs.MessageQueue) > 0 {
+		head := gs.MessageQueue[0]
+		if head.Guard == nil || head.Guard() {
+			return true
+		}
+		gs.MessageQueue = gs.MessageQueue[1:]
+		gs.MessageTicks = 0
+	}
+	return false
+}
+
+// advanceMessage は先頭のメッセージを閉じ、そのコールバックを実行します。
+// コールバック内で追加されたメッセージは残りのキューより先に並べ、
+// キューが空になれば状態をPlayingに戻します。
+func advanceMessage(ecs *ecs.ECS) {
+	gameStateEntry, ok := GameStateComponentType.First(ecs.World)
+	if !ok {
+		return
+	}
+	gs := GameStateComponentType.Get(gameStateEntry)
+	if !discardGuardedMessages(gs) {
+		if gs.CurrentState == GameStateMessage {
 			gs.CurrentState = StatePlaying
-			GameStateComponentType.Set(gameStateEntry, gs)
 		}
+		GameStateComponentType.Set(gameStateEntry, gs)
+		return
+	}
+
+	head := gs.MessageQueue[0]
+	rest := append([]GameMessage(nil), gs.MessageQueue[1:]...)
+	gs.MessageQueue = nil
+	gs.MessageTicks = 0
+	GameStateComponentType.Set(gameStateEntry, gs) // 先にgsを保存
+
+	if head.Callback != nil {
+		head.Callback()
+	}
+
+	gs = GameStateComponentType.Get(gameStateEntry)
+	gs.MessageQueue = append(gs.MessageQueue, rest...)
+	if len(gs.MessageQueue) == 0 && gs.CurrentState == GameStateMessage {
+		gs.CurrentState = StatePlaying
+	}
+	GameStateComponentType.Set(gameStateEntry, gs)
+}
+
+// skipAllMessages はキューが空になるまで、すべてのメッセージのコールバックを順に実行します。
+func skipAllMessages(ecs *ecs.ECS) {
+	gameStateEntry, ok := GameStateComponentType.First(ecs.World)
+	if !ok {
+		return
+	}
+	for i := 0; i < maxSkipAllMessages; i++ {
+		gs := GameStateComponentType.Get(gameStateEntry)
+		if gs.CurrentState != GameStateMessage || len(gs.MessageQueue) == 0 {
+			break
+		}
+		advanceMessage(ecs)
 	}
 }
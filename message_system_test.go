@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/ecs"
+)
+
+// newMessageTestECS はゲーム状態だけを持つECSを作ります。
+func newMessageTestECS() (*ecs.ECS, *donburi.Entry) {
+	world := donburi.NewWorld()
+	entry := world.Entry(world.Create(GameStateComponentType))
+	GameStateComponentType.SetValue(entry, GameStateComponent{CurrentState: StatePlaying})
+	return ecs.NewECS(world), entry
+}
+
+func messageTexts(gs *GameStateComponent) []string {
+	var texts []string
+	for _, msg := range gs.MessageQueue {
+		texts = append(texts, msg.Text)
+	}
+	return texts
+}
+
+func TestAdvanceMessageShowsCallbackMessagesFirst(t *testing.T) {
+	e, entry := newMessageTestECS()
+	var ran []string
+	showGameMessage(e, "attack", func() {
+		ran = append(ran, "attack")
+		showGameMessage(e, "damage", func() { ran = append(ran, "damage") })
+	})
+	showGameMessage(e, "next turn", func() { ran = append(ran, "next turn") })
+
+	gs := GameStateComponentType.Get(entry)
+	gs.MessageTicks = 30
+	advanceMessage(e)
+	if got := messageTexts(gs); !slices.Equal(got, []string{"damage", "next turn"}) {
+		t.Errorf("queue after the first message = %v, want the callback's message before the rest", got)
+	}
+	if gs.MessageTicks != 0 || gs.CurrentState != GameStateMessage {
+		t.Errorf("ticks %d, state %v; want 0 and still showing messages", gs.MessageTicks, gs.CurrentState)
+	}
+
+	advanceMessage(e)
+	advanceMessage(e)
+	if !slices.Equal(ran, []string{"attack", "damage", "next turn"}) {
+		t.Errorf("callbacks ran in order %v", ran)
+	}
+	if gs.CurrentState != StatePlaying || len(gs.MessageQueue) != 0 {
+		t.Errorf("state %v with %d messages after the last one, want StatePlaying and none", gs.CurrentState, len(gs.MessageQueue))
+	}
+}
+
+func TestAdvanceMessageDiscardsGuardedMessages(t *testing.T) {
+	e, entry := newMessageTestECS()
+	targetAlive := true
+	var ran []string
+	showGameMessage(e, "attack", func() { targetAlive = false })
+	enqueueGameMessage(e, GameMessage{Text: "target dodges", Guard: func() bool { return targetAlive }, Callback: func() { ran = append(ran, "target dodges") }})
+	showGameMessage(e, "next turn", func() { ran = append(ran, "next turn") })
+
+	// Guardは先頭に来たときに評価され、falseなら表示もコールバックもせずに破棄する
+	advanceMessage(e)
+	advanceMessage(e)
+	gs := GameStateComponentType.Get(entry)
+	if !slices.Equal(ran, []string{"next turn"}) {
+		t.Errorf("callbacks ran %v, want only the message after the discarded one", ran)
+	}
+	if gs.CurrentState != StatePlaying || len(gs.MessageQueue) != 0 {
+		t.Errorf("state %v with %v queued, want StatePlaying and an empty queue", gs.CurrentState, messageTexts(gs))
+	}
+
+	// 表示するものが残っていなければ戦闘に戻る
+	enqueueGameMessage(e, GameMessage{Text: "stale", Guard: func() bool { return false }})
+	advanceMessage(e)
+	if gs.CurrentState != StatePlaying || len(gs.MessageQueue) != 0 {
+		t.Errorf("state %v with %v queued, want StatePlaying and an empty queue", gs.CurrentState, messageTexts(gs))
+	}
+}
+
+func TestSkipAllMessagesRunsEveryCallback(t *testing.T) {
+	e, entry := newMessageTestECS()
+	var ran []string
+	for i := range 3 {
+		text := fmt.Sprintf("message %d", i)
+		showGameMessage(e, text, func() {
+			ran = append(ran, text)
+			if i == 0 {
+				showGameMessage(e, "follow-up", func() { ran = append(ran, "follow-up") })
+			}
+		})
+	}
+
+	skipAllMessages(e)
+	if want := []string{"message 0", "follow-up", "message 1", "message 2"}; !slices.Equal(ran, want) {
+		t.Errorf("callbacks ran %v, want %v", ran, want)
+	}
+	if gs := GameStateComponentType.Get(entry); gs.CurrentState != StatePlaying {
+		t.Errorf("state = %v after skipping, want StatePlaying", gs.CurrentState)
+	}
+}
+
+func TestSkipAllMessagesStopsAtGameOverAndEndlessQueues(t *testing.T) {
+	e, entry := newMessageTestECS()
+	over := func() {
+		GameStateComponentType.Get(entry).CurrentState = GameStateOver
+		showGameMessage(e, "winner", nil)
+	}
+	showGameMessage(e, "last hit", over)
+	skipAllMessages(e)
+	gs := GameStateComponentType.Get(entry)
+	if gs.CurrentState != GameStateOver || !slices.Equal(messageTexts(gs), []string{"winner"}) {
+		t.Errorf("state %v with %v queued, want the winner message kept on the game over screen", gs.CurrentState, messageTexts(gs))
+	}
+
+	// メッセージを積み続けるコールバックでも止まる
+	e, entry = newMessageTestECS()
+	count := 0
+	var endless func()
+	endless = func() {
+		count++
+		showGameMessage(e, "again", endless)
+	}
+	showGameMessage(e, "again", endless)
+	skipAllMessages(e)
+	if count != maxSkipAllMessages {
+		t.Errorf("skipAllMessages ran %d callbacks, want it to stop after %d", count, maxSkipAllMessages)
+	}
+}
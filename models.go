@@ -1,5 +1,7 @@
 package main
 
+import "strconv"
+
 // PartSlotKey はパーツのスロットを一意に識別するための型です。
 type PartSlotKey string
 
@@ -54,15 +56,52 @@ const (
 
 // Medal はメダルのデータ構造です。
 type Medal struct {
-	ID           string
-	Name         string
-	Personality  string
-	Medaforce    string
-	Attribute    string
-	SkillShoot   int
-	SkillFight   int
-	SkillScan    int
-	SkillSupport int
+	ID          string
+	Name        string
+	Personality string
+	Medaforce   string
+	Attribute   string
+	// 他の言語の表記（medals.csvの name_en などの列）。なければ上の日本語の値を使う
+	NameText        LocalizedText
+	PersonalityText LocalizedText
+	MedaforceText   LocalizedText
+	AttributeText   LocalizedText
+	SkillShoot      int
+	SkillFight      int
+	SkillScan       int
+	SkillSupport    int
+}
+
+// DisplayName は現在の言語でのメダル名を返します。
+func (m *Medal) DisplayName() string {
+	return m.NameText.Or(m.Name)
+}
+
+// OptionalInt はCSVで "NONE" と書かれる、値を持たないことがある数値です。NONEと0は区別されます。
+type OptionalInt struct {
+	Value int
+	Valid bool // falseの場合はNONE
+}
+
+// SomeInt は値を持つOptionalIntを返します。
+func SomeInt(v int) OptionalInt {
+	return OptionalInt{Value: v, Valid: true}
+}
+
+// Int は値を返します。NONEの場合は0です。計算ではNONEを0として扱います。
+func (o OptionalInt) Int() int {
+	if !o.Valid {
+		return 0
+	}
+	return o.Value
+}
+
+// String は表示用の文字列を返します。NONEの場合は "-" です。
+func (o OptionalInt) String() string {
+	if !o.Valid {
+		return "-"
+	}
+	return strconv.Itoa(o.Value)
 }
 
 // Part はパーツのデータ構造です。
@@ -75,15 +114,24 @@ type Part struct {
 	WeaponType string
 	Armor      int
 	MaxArmor   int
-	Power      int
-	Charge     int
-	Cooldown   int
+	Power      OptionalInt // 脚部など、行動しないパーツではNONE
+	Charge     OptionalInt
+	Cooldown   OptionalInt
 	Defense    int
 	Accuracy   int
-	Mobility   int
-	Propulsion int
+	Mobility   OptionalInt // 脚部以外ではNONE
+	Propulsion OptionalInt
 	IsBroken   bool
 	SetID      string
+	Sprite     string // アセットマニフェストで割り当てられた画像のパス（なければ空）
+	// 他の言語の表記（parts.csvの part_name_en などの列）。なければPartName・WeaponTypeを使う
+	NameText       LocalizedText
+	WeaponTypeText LocalizedText
+}
+
+// DisplayName は現在の言語でのパーツ名を返します。
+func (p *Part) DisplayName() string {
+	return p.NameText.Or(p.PartName)
 }
 
 // Medarot はメダロットのデータ構造です。
@@ -114,6 +162,8 @@ const (
 	StatePlayerActionSelect
 	GameStateMessage
 	GameStateOver
+	StatePaused        // ポーズメニュー表示中
+	StateMedarotDetail // メダロットの詳細画面表示中
 )
 
 // TeamID はチームを識別します。
@@ -4,6 +4,7 @@ package main
 import (
 	"image"
 	"math/rand"
+	"sort"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -45,11 +46,24 @@ func (sys *PlayerInputSystem) Update(ecs *ecs.ECS) {
 			return
 		}
 
+		var ready []*donburi.Entry
 		sys.actionSelectQuery.Each(ecs.World, func(entry *donburi.Entry) {
-			if StatusComponentType.Get(entry).State == StateReadyToSelectAction {
-				pasComp.ActionQueue = append(pasComp.ActionQueue, entry.Entity())
+			if StatusComponentType.Get(entry).State != StateReadyToSelectAction {
+				return
 			}
+			// ネット対戦では、この端末のプレイヤーの機体で、まだ行動を送っていないものだけを選ぶ
+			if pasComp.Committer != nil && !pasComp.Committer.CanSelect(entry) {
+				return
+			}
+			ready = append(ready, entry)
+		})
+		// プレイヤーの交代が少なくなるよう、直前に選んでいたプレイヤーの機体から順に、持ち主ごとにまとめて並べる
+		sort.SliceStable(ready, func(i, j int) bool {
+			return queueOrder(ready[i], pasComp.ActivePlayer) < queueOrder(ready[j], pasComp.ActivePlayer)
 		})
+		for _, entry := range ready {
+			pasComp.ActionQueue = append(pasComp.ActionQueue, entry.Entity())
+		}
 
 		// キューに誰かが追加されたら、ゲーム状態を行動選択中に変更
 		if len(pasComp.ActionQueue) > 0 {
@@ -71,26 +85,58 @@ func (sys *PlayerInputSystem) Update(ecs *ecs.ECS) {
 			return
 		}
 
+		// ホットシート対戦では、行動を選ぶプレイヤーが替わるときに交代の画面をはさむ
+		match := ConfigComponentType.Get(ConfigComponentType.MustFirst(ecs.World)).Match
+		player, inputPlayer := playerOf(actingMedarotEntry), anyPlayer
+		if match.Hotseat {
+			inputPlayer = player
+			if player != pasComp.ActivePlayer {
+				pasComp.HandOff = true
+			}
+		}
+		pasComp.ActivePlayer = player
+		if pasComp.HandOff {
+			handleHandOffInput(ecs, pasComp, inputPlayer)
+			return
+		}
+
 		// UIの初期化 (このキャラが初めて選択された場合)
 		if len(pasComp.AvailableActions) == 0 {
 			initializeActionUI(ecs, actingMedarotEntry, pasComp, sys.targetableQuery)
+			return
 		}
 
-		// UIのクリック処理
-		handleMouseInput(ecs, actingMedarotEntry, gs, pasComp)
+		// キーボード・ゲームパッドの操作を先に処理し、確定しなかった場合のみクリックを処理する
+		if handleNavigationInput(ecs, actingMedarotEntry, gs, pasComp, sys.targetableQuery, inputPlayer) {
+			return
+		}
+		handleMouseInput(ecs, actingMedarotEntry, gs, pasComp, sys.targetableQuery)
+	}
+}
+
+// queueOrder は行動選択のキューに並べる順番を返します。直前に選んでいたプレイヤーの機体が先頭になります。
+func queueOrder(entry *donburi.Entry, activePlayer int) int {
+	if player := playerOf(entry); player != activePlayer {
+		return player
+	}
+	return -1
+}
+
+// handleHandOffInput はプレイヤー交代の画面で、次のプレイヤーの決定（またはクリック）を待ちます。
+func handleHandOffInput(ecs *ecs.ECS, pasComp *PlayerActionSelectComponent, player int) {
+	config := ConfigComponentType.Get(ConfigComponentType.MustFirst(ecs.World)).GameConfig
+	clicked := inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && !isCursorOnHUD(config)
+	if clicked || isPlayerInputJustPressed(InputConfirm, player) {
+		pasComp.HandOff = false
+		// 交代の画面を閉じたクリックの位置で、ボタンのフォーカスが移らないようにする
+		pasComp.lastCursor = cursorPosition()
 	}
 }
 
 // initializeActionUI は行動選択UIの初期設定を行います。
 func initializeActionUI(ecs *ecs.ECS, entry *donburi.Entry, pasComp *PlayerActionSelectComponent, targetQuery *donburi.Query) {
-	partsComp := PartsComponentType.Get(entry)
-	pasComp.AvailableActions = []PartSlotKey{}
-	slots := []PartSlotKey{PartSlotHead, PartSlotRightArm, PartSlotLeftArm}
-	for _, slotKey := range slots {
-		if part, ok := partsComp.Parts[slotKey]; ok && !part.IsBroken && part.Charge > 0 {
-			pasComp.AvailableActions = append(pasComp.AvailableActions, slotKey)
-		}
-	}
+	pasComp.AvailableActions = availableActionSlots(PartsComponentType.Get(entry))
+	pasComp.FocusIndex = 0
 
 	// 行動がなければキューから外す
 	if len(pasComp.AvailableActions) == 0 {
@@ -99,79 +145,233 @@ func initializeActionUI(ecs *ecs.ECS, entry *donburi.Entry, pasComp *PlayerActio
 	}
 
 	// デフォルトターゲットを選定
+	candidates := opponentCandidates(ecs, entry, targetQuery)
+	if len(candidates) > 0 {
+		pasComp.CurrentTarget = candidates[rand.Intn(len(candidates))]
+	}
+}
+
+// availableActionSlots は行動に使えるパーツのスロットを、頭部・右腕・左腕の順に返します。
+func availableActionSlots(partsComp *PartsComponent) []PartSlotKey {
+	slots := []PartSlotKey{}
+	for _, slotKey := range []PartSlotKey{PartSlotHead, PartSlotRightArm, PartSlotLeftArm} {
+		if part, ok := partsComp.Parts[slotKey]; ok && !part.IsBroken && part.Charge.Int() > 0 {
+			slots = append(slots, slotKey)
+		}
+	}
+	return slots
+}
+
+// opponentCandidates は攻撃対象にできる敵チームのエンティティを、描画順に並べて返します。
+func opponentCandidates(ecs *ecs.ECS, entry *donburi.Entry, targetQuery *donburi.Query) []donburi.Entity {
 	actingID := IdentityComponentType.Get(entry)
 	var opponentTeam TeamID = Team2
 	if actingID.Team == Team2 {
 		opponentTeam = Team1
 	}
-	candidates := []donburi.Entity{}
+	candidates := []*donburi.Entry{}
 	targetQuery.Each(ecs.World, func(targetEntry *donburi.Entry) {
 		if IdentityComponentType.Get(targetEntry).Team == opponentTeam && !StatusComponentType.Get(targetEntry).IsBroken() {
-			candidates = append(candidates, targetEntry.Entity())
+			candidates = append(candidates, targetEntry)
 		}
 	})
-	if len(candidates) > 0 {
-		pasComp.CurrentTarget = candidates[rand.Intn(len(candidates))]
+	// ターゲットの切り替え順を画面上の並びと一致させる
+	sort.Slice(candidates, func(i, j int) bool {
+		return drawIndexOf(candidates[i]) < drawIndexOf(candidates[j])
+	})
+	entities := make([]donburi.Entity, len(candidates))
+	for i, c := range candidates {
+		entities[i] = c.Entity()
 	}
+	return entities
 }
 
-// handleMouseInput は行動選択UIでのクリックを処理します。
-func handleMouseInput(ecs *ecs.ECS, entry *donburi.Entry, gs *GameStateComponent, pasComp *PlayerActionSelectComponent) {
-	if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+// drawIndexOf はエンティティの描画順を返します。RenderComponentがなければ0です。
+func drawIndexOf(entry *donburi.Entry) int {
+	if !entry.HasComponent(RenderComponentType) {
+		return 0
+	}
+	return RenderComponentType.Get(entry).DrawIndex
+}
+
+// cycleTarget は現在のターゲットを候補の中で前後に切り替えます。
+func cycleTarget(ecs *ecs.ECS, entry *donburi.Entry, pasComp *PlayerActionSelectComponent, targetQuery *donburi.Query, step int) {
+	candidates := opponentCandidates(ecs, entry, targetQuery)
+	if len(candidates) == 0 {
+		return
+	}
+	current := -1
+	for i, c := range candidates {
+		if c == pasComp.CurrentTarget {
+			current = i
+			break
+		}
+	}
+	if current < 0 {
+		pasComp.CurrentTarget = candidates[0]
+		return
+	}
+	next := (current + step + len(candidates)) % len(candidates)
+	pasComp.CurrentTarget = candidates[next]
+}
+
+// handleNavigationInput はキーボード・ゲームパッドによる行動選択を処理します。
+// 上下でフォーカスを移動し、左右（ショルダーボタン）でターゲットを切り替え、決定で行動を確定します。
+// キャンセルすると、同じプレイヤーの行動選択待ちの機体が他にいればこの機体を後回しにします。
+// playerは入力を受け付けるプレイヤーです（anyPlayerならすべての入力）。
+// 行動が確定した場合はtrueを返します。
+func handleNavigationInput(ecs *ecs.ECS, entry *donburi.Entry, gs *GameStateComponent, pasComp *PlayerActionSelectComponent, targetQuery *donburi.Query, player int) bool {
+	actionCount := len(pasComp.AvailableActions)
+	switch {
+	case isPlayerInputJustPressed(InputUp, player):
+		pasComp.FocusIndex = (pasComp.FocusIndex - 1 + actionCount) % actionCount
+	case isPlayerInputJustPressed(InputDown, player):
+		pasComp.FocusIndex = (pasComp.FocusIndex + 1) % actionCount
+	case isPlayerInputJustPressed(InputPrevTarget, player):
+		cycleTarget(ecs, entry, pasComp, targetQuery, -1)
+	case isPlayerInputJustPressed(InputNextTarget, player):
+		cycleTarget(ecs, entry, pasComp, targetQuery, 1)
+	case isPlayerInputJustPressed(InputCancel, player):
+		deferSelection(ecs, pasComp)
+	case isPlayerInputJustPressed(InputConfirm, player):
+		if pasComp.FocusIndex >= 0 && pasComp.FocusIndex < actionCount {
+			return confirmAction(ecs, entry, gs, pasComp, pasComp.AvailableActions[pasComp.FocusIndex])
+		}
+	}
+	return false
+}
+
+// deferSelection はキューの先頭の機体を、同じプレイヤーの最後の機体の後ろへ回します。
+// 他のプレイヤーの機体より後ろには回さないので、キャンセルでプレイヤーの交代は起きません。
+func deferSelection(ecs *ecs.ECS, pasComp *PlayerActionSelectComponent) {
+	queue := pasComp.ActionQueue
+	player := playerOf(ecs.World.Entry(queue[0]))
+	last := 0
+	for i := 1; i < len(queue); i++ {
+		if ecs.World.Valid(queue[i]) && playerOf(ecs.World.Entry(queue[i])) == player {
+			last = i
+		}
+	}
+	if last == 0 {
 		return
 	}
+	head := queue[0]
+	copy(queue, queue[1:last+1])
+	queue[last] = head
+	pasComp.AvailableActions = nil
+	pasComp.CurrentTarget = donburi.Entity(0)
+}
 
+// handleMouseInput は行動選択UIでのクリックを処理します。
+// 敵のアイコンや情報パネルをクリックするとターゲットを変更し、
+// カーソルが乗っているボタンにはフォーカスを移します。
+func handleMouseInput(ecs *ecs.ECS, entry *donburi.Entry, gs *GameStateComponent, pasComp *PlayerActionSelectComponent, targetQuery *donburi.Query) {
 	config := ConfigComponentType.Get(ConfigComponentType.MustFirst(ecs.World)).GameConfig
-	uiConfig := config.UI
-	mx, my := ebiten.CursorPosition()
+	cursor := cursorPosition()
+	clicked := inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft)
+
+	if clicked {
+		if target, ok := targetUnderCursor(ecs, entry, targetQuery, config, cursor); ok {
+			pasComp.CurrentTarget = target
+			pasComp.lastCursor = cursor
+			return
+		}
+	}
 
 	for i, slotKey := range pasComp.AvailableActions {
-		btnW, btnH, btnS := uiConfig.ActionModal.ButtonWidth, uiConfig.ActionModal.ButtonHeight, uiConfig.ActionModal.ButtonSpacing
-		btnX := uiConfig.Screen.Width/2 - int(btnW/2)
-		btnY := uiConfig.Screen.Height/2 - 50 + (int(btnH)+int(btnS))*i
-		buttonRect := image.Rect(btnX, btnY, btnX+int(btnW), btnY+int(btnH))
-
-		if (image.Point{X: mx, Y: my}).In(buttonRect) {
-			// ボタンがクリックされた
-			status := StatusComponentType.Get(entry)
-			actionComp := ActionComponentType.Get(entry)
-			partData := PartsComponentType.Get(entry).Parts[slotKey]
-
-			// ターゲットの検証
-			targetIsValid := false
-			if targetEntry := ecs.World.Entry(pasComp.CurrentTarget); targetEntry.Valid() && !StatusComponentType.Get(targetEntry).IsBroken() {
-				targetIsValid = true
-			}
-			if (partData.Category == CategoryShoot || partData.Category == CategoryFight) && !targetIsValid {
-				return // 攻撃には有効なターゲットが必要
-			}
+		if !cursor.In(actionButtonRect(&config.UI, i)) {
+			continue
+		}
+		if cursor != pasComp.lastCursor {
+			pasComp.FocusIndex = i // マウスを動かしたときだけフォーカスを奪う
+		}
+		if clicked {
+			confirmAction(ecs, entry, gs, pasComp, slotKey)
+		}
+		break
+	}
+	pasComp.lastCursor = cursor
+}
 
-			// アクションを確定
-			actionComp.SelectedPartKey = slotKey
-			actionComp.TargetedMedarot = pasComp.CurrentTarget
-			status.State = StateActionCharging
-			status.Gauge = 0
-			switch partData.Trait {
-			case TraitAim:
-				status.IsEvasionDisabled = true
-			case TraitStrike:
-				status.IsDefenseDisabled = true
-			case TraitBerserk:
-				status.IsEvasionDisabled, status.IsDefenseDisabled = true, true
-			}
+// targetUnderCursor は、カーソル位置にある攻撃可能な敵を返します。
+// 戦場のアイコンと情報パネルの両方を判定しますが、行動選択ウィンドウと重なる部分は除外します。
+func targetUnderCursor(ecs *ecs.ECS, entry *donburi.Entry, targetQuery *donburi.Query, config *Config, cursor image.Point) (donburi.Entity, bool) {
+	if cursor.In(actionModalRect(&config.UI)) {
+		return donburi.Entity(0), false
+	}
+	radius := float64(config.UI.Battlefield.IconRadius)
+	for _, candidate := range opponentCandidates(ecs, entry, targetQuery) {
+		targetEntry := ecs.World.Entry(candidate)
+		if !targetEntry.HasComponent(RenderComponentType) {
+			continue
+		}
+		identity := IdentityComponentType.Get(targetEntry)
+		render := RenderComponentType.Get(targetEntry)
+
+		x, y := medarotIconPosition(config, identity, StatusComponentType.Get(targetEntry), render)
+		dx, dy := float64(cursor.X)-float64(x), float64(cursor.Y)-float64(y)
+		if dx*dx+dy*dy <= radius*radius || cursor.In(infoPanelRect(config, identity, render)) {
+			return candidate, true
+		}
+	}
+	return donburi.Entity(0), false
+}
 
-			entry.AddComponent(ActionChargingTag)
-			StatusComponentType.Set(entry, status)
-			ActionComponentType.Set(entry, actionComp)
+// confirmAction は選択されたパーツで行動を確定し、チャージを開始させます。
+// ネット対戦ではその場では適用せず、Committerに渡します。
+// 攻撃に有効なターゲットがない場合は確定せずfalseを返します。
+func confirmAction(ecs *ecs.ECS, entry *donburi.Entry, gs *GameStateComponent, pasComp *PlayerActionSelectComponent, slotKey PartSlotKey) bool {
+	partData := PartsComponentType.Get(entry).Parts[slotKey]
 
-			// 状態をリセットして次へ
-			pasComp.ActionQueue = pasComp.ActionQueue[1:]
-			pasComp.AvailableActions = nil
-			pasComp.CurrentTarget = donburi.Entity(0)
-			if len(pasComp.ActionQueue) == 0 {
-				gs.CurrentState = StatePlaying
-			}
-			return // このフレームの入力処理は終了
+	// ターゲットの検証
+	targetIsValid := false
+	if ecs.World.Valid(pasComp.CurrentTarget) {
+		if targetEntry := ecs.World.Entry(pasComp.CurrentTarget); targetEntry.Valid() && !StatusComponentType.Get(targetEntry).IsBroken() {
+			targetIsValid = true
 		}
 	}
+	if (partData.Category == CategoryShoot || partData.Category == CategoryFight) && !targetIsValid {
+		return false // 攻撃には有効なターゲットが必要
+	}
+
+	// アクションを確定
+	if pasComp.Committer != nil {
+		pasComp.Committer.Commit(entry, slotKey, pasComp.CurrentTarget)
+	} else {
+		applyActionChoice(entry, slotKey, pasComp.CurrentTarget)
+	}
+
+	// 状態をリセットして次へ
+	pasComp.ActionQueue = pasComp.ActionQueue[1:]
+	pasComp.AvailableActions = nil
+	pasComp.FocusIndex = 0
+	pasComp.CurrentTarget = donburi.Entity(0)
+	if len(pasComp.ActionQueue) == 0 {
+		gs.CurrentState = StatePlaying
+	}
+	return true
+}
+
+// applyActionChoice は機体に行動とターゲットを設定し、チャージを開始させます。
+func applyActionChoice(entry *donburi.Entry, slotKey PartSlotKey, target donburi.Entity) {
+	status := StatusComponentType.Get(entry)
+	actionComp := ActionComponentType.Get(entry)
+	partData := PartsComponentType.Get(entry).Parts[slotKey]
+
+	actionComp.SelectedPartKey = slotKey
+	actionComp.TargetedMedarot = target
+	status.State = StateActionCharging
+	status.Gauge = 0
+	switch partData.Trait {
+	case TraitAim:
+		status.IsEvasionDisabled = true
+	case TraitStrike:
+		status.IsDefenseDisabled = true
+	case TraitBerserk:
+		status.IsEvasionDisabled, status.IsDefenseDisabled = true, true
+	}
+
+	entry.AddComponent(ActionChargingTag)
+	StatusComponentType.Set(entry, status)
+	ActionComponentType.Set(entry, actionComp)
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/ecs"
+)
+
+// newPlayerMedarots は指定したプレイヤーが操作する機体を作ります。0はAIが操作する機体です。
+func newPlayerMedarots(world donburi.World, players ...int) []*donburi.Entry {
+	var entries []*donburi.Entry
+	for _, player := range players {
+		entry := world.Entry(world.Create(StatusComponentType))
+		if player > 0 {
+			entry.AddComponent(PlayerControlledComponentType)
+			PlayerControlledComponentType.SetValue(entry, PlayerControlledComponent{Player: player})
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestQueueOrderGroupsByPlayerStartingWithTheActiveOne(t *testing.T) {
+	world := donburi.NewWorld()
+	entries := newPlayerMedarots(world, 1, 2, 1, 2, 1)
+	sorted := func(activePlayer int) []donburi.Entity {
+		ready := slices.Clone(entries)
+		sort.SliceStable(ready, func(i, j int) bool {
+			return queueOrder(ready[i], activePlayer) < queueOrder(ready[j], activePlayer)
+		})
+		var queue []donburi.Entity
+		for _, entry := range ready {
+			queue = append(queue, entry.Entity())
+		}
+		return queue
+	}
+	e := func(i int) donburi.Entity { return entries[i].Entity() }
+
+	// 直前のプレイヤー2の機体が先頭に来て、交代は1回で済む
+	if got, want := sorted(2), []donburi.Entity{e(1), e(3), e(0), e(2), e(4)}; !slices.Equal(got, want) {
+		t.Errorf("active player 2: queue %v, want %v", got, want)
+	}
+	// まだ誰も選んでいなければプレイヤー番号の順
+	if got, want := sorted(0), []donburi.Entity{e(0), e(2), e(4), e(1), e(3)}; !slices.Equal(got, want) {
+		t.Errorf("no active player: queue %v, want %v", got, want)
+	}
+	if got, want := sorted(1), sorted(0); !slices.Equal(got, want) {
+		t.Errorf("active player 1: queue %v, want %v", got, want)
+	}
+}
+
+func TestDeferSelectionStaysWithinThePlayer(t *testing.T) {
+	world := donburi.NewWorld()
+	e := ecs.NewECS(world)
+	entries := newPlayerMedarots(world, 1, 1, 1, 2)
+	ids := func(indexes ...int) []donburi.Entity {
+		var queue []donburi.Entity
+		for _, i := range indexes {
+			queue = append(queue, entries[i].Entity())
+		}
+		return queue
+	}
+
+	pasComp := &PlayerActionSelectComponent{
+		ActionQueue:      ids(0, 1, 2, 3),
+		AvailableActions: []PartSlotKey{PartSlotHead},
+		CurrentTarget:    entries[3].Entity(),
+	}
+	deferSelection(e, pasComp)
+	if want := ids(1, 2, 0, 3); !slices.Equal(pasComp.ActionQueue, want) {
+		t.Errorf("queue = %v, want %v", pasComp.ActionQueue, want)
+	}
+	if pasComp.AvailableActions != nil || pasComp.CurrentTarget != 0 {
+		t.Errorf("the deferred machine's UI was kept: actions %v, target %v", pasComp.AvailableActions, pasComp.CurrentTarget)
+	}
+
+	// 同じプレイヤーの機体がほかになければ、キャンセルしても順番は変わらない
+	pasComp = &PlayerActionSelectComponent{ActionQueue: ids(2, 3), AvailableActions: []PartSlotKey{PartSlotHead}}
+	deferSelection(e, pasComp)
+	if want := ids(2, 3); !slices.Equal(pasComp.ActionQueue, want) || pasComp.AvailableActions == nil {
+		t.Errorf("queue = %v (actions %v), want %v unchanged", pasComp.ActionQueue, pasComp.AvailableActions, want)
+	}
+
+	// 破壊されて消えた機体の後ろには回さない
+	pasComp = &PlayerActionSelectComponent{ActionQueue: ids(0, 1, 2)}
+	world.Remove(entries[2].Entity())
+	deferSelection(e, pasComp)
+	if want := ids(1, 0, 2); !slices.Equal(pasComp.ActionQueue, want) {
+		t.Errorf("queue = %v, want %v", pasComp.ActionQueue, want)
+	}
+}
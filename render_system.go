@@ -4,12 +4,13 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"math"
+	"math/rand"
 	"sort"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/text"
-	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
 	"github.com/yohamta/donburi"
 	"github.com/yohamta/donburi/ecs"
 	"github.com/yohamta/donburi/filter"
@@ -17,7 +18,9 @@ import (
 
 // RenderSystem はゲームの描画を担当します。
 type RenderSystem struct {
-	medarotQuery *donburi.Query
+	medarotQuery   *donburi.Query
+	animationQuery *donburi.Query
+	canvas         *ebiten.Image // 画面揺れの際に戦場を一度描き込むオフスクリーン画像
 }
 
 // NewRenderSystem はRenderSystemを初期化します。
@@ -27,6 +30,7 @@ func NewRenderSystem() *RenderSystem {
 			filter.Contains(IdentityComponentType), filter.Contains(StatusComponentType),
 			filter.Contains(RenderComponentType), filter.Contains(PartsComponentType),
 		)),
+		animationQuery: donburi.NewQuery(filter.Contains(AnimationComponentType)),
 	}
 }
 
@@ -47,9 +51,28 @@ func (sys *RenderSystem) Draw(ecs *ecs.ECS, screen *ebiten.Image) {
 	appConfig := ConfigComponentType.Get(configEntry).GameConfig
 	pasComp := PlayerActionSelectComponentType.Get(pasEntry)
 
-	sys.drawBattlefield(screen, ecs, appConfig)
-	sys.drawAllMedarots(screen, ecs, appConfig)
+	// 戦場と情報パネルは画面揺れの対象。揺れている間はオフスクリーンに描いてからずらして転写する
+	world := screen
+	shakeX, shakeY := screenShakeOffset(ecs)
+	if shakeX != 0 || shakeY != 0 {
+		world = sys.canvasFor(screen)
+		world.Clear()
+	}
+	sys.drawBattlefield(world, ecs, appConfig)
+	sys.drawAllMedarots(world, ecs, appConfig)
+	sys.drawAnimations(world, ecs, appConfig)
+	if world != screen {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(shakeX*deviceScale, shakeY*deviceScale)
+		screen.Fill(appConfig.UI.Colors.Background)
+		screen.DrawImage(world, op)
+	}
+
+	sys.drawHUD(screen, ConfigComponentType.Get(configEntry).Settings, appConfig)
 	sys.drawUI(screen, ecs, gs, pasComp, appConfig)
+	sys.drawTooltip(screen, ecs, gs, pasComp, appConfig)
+	sys.drawReloadBanner(screen, ecs, appConfig)
+	sys.drawNetworkStatus(screen, ecs, appConfig)
 	sys.drawDebugInfo(screen, ecs, gs, pasComp, appConfig)
 }
 
@@ -57,7 +80,7 @@ func (sys *RenderSystem) Draw(ecs *ecs.ECS, screen *ebiten.Image) {
 func (sys *RenderSystem) drawBattlefield(screen *ebiten.Image, ecs *ecs.ECS, config *Config) {
 	screen.Fill(config.UI.Colors.Background)
 	bf := config.UI.Battlefield
-	vector.StrokeRect(screen, 0, 0, float32(config.UI.Screen.Width), bf.Height, bf.LineWidth, config.UI.Colors.White, false)
+	strokeRect(screen, 0, 0, float32(config.UI.Screen.Width), bf.Height, bf.LineWidth, config.UI.Colors.White, false)
 
 	medarotCount := 0
 	donburi.NewQuery(filter.Contains(IdentityComponentType)).Each(ecs.World, func(_ *donburi.Entry) { medarotCount++ })
@@ -65,11 +88,11 @@ func (sys *RenderSystem) drawBattlefield(screen *ebiten.Image, ecs *ecs.ECS, con
 
 	for i := 0; i < playersPerTeam; i++ {
 		yPos := bf.MedarotVerticalSpacing * (float32(i) + 1)
-		vector.StrokeCircle(screen, bf.Team1HomeX, yPos, bf.HomeMarkerRadius, bf.LineWidth, config.UI.Colors.Gray, true)
-		vector.StrokeCircle(screen, bf.Team2HomeX, yPos, bf.HomeMarkerRadius, bf.LineWidth, config.UI.Colors.Gray, true)
+		strokeCircle(screen, bf.Team1HomeX, yPos, bf.HomeMarkerRadius, bf.LineWidth, config.UI.Colors.Gray, true)
+		strokeCircle(screen, bf.Team2HomeX, yPos, bf.HomeMarkerRadius, bf.LineWidth, config.UI.Colors.Gray, true)
 	}
-	vector.StrokeLine(screen, bf.Team1ExecutionLineX, 0, bf.Team1ExecutionLineX, bf.Height, bf.LineWidth, config.UI.Colors.Gray, false)
-	vector.StrokeLine(screen, bf.Team2ExecutionLineX, 0, bf.Team2ExecutionLineX, bf.Height, bf.LineWidth, config.UI.Colors.Gray, false)
+	strokeLine(screen, bf.Team1ExecutionLineX, 0, bf.Team1ExecutionLineX, bf.Height, bf.LineWidth, config.UI.Colors.Gray, false)
+	strokeLine(screen, bf.Team2ExecutionLineX, 0, bf.Team2ExecutionLineX, bf.Height, bf.LineWidth, config.UI.Colors.Gray, false)
 }
 
 // MedarotDrawInfo は描画用にソートするための一時的な構造体です。
@@ -78,17 +101,25 @@ type MedarotDrawInfo struct {
 	Status   *StatusComponent
 	Render   *RenderComponent
 	Parts    *PartsComponent
+	Entity   donburi.Entity
+	Armor    *ArmorDisplayComponent // 装甲バーの表示値（なければnil）
 }
 
 // drawAllMedarots は全てのメダロットのアイコンと情報パネルを描画します。
 func (sys *RenderSystem) drawAllMedarots(screen *ebiten.Image, ecs *ecs.ECS, config *Config) {
 	allMedarotsToDraw := []MedarotDrawInfo{}
 	sys.medarotQuery.Each(ecs.World, func(entry *donburi.Entry) {
+		var armor *ArmorDisplayComponent
+		if entry.HasComponent(ArmorDisplayComponentType) {
+			armor = ArmorDisplayComponentType.Get(entry)
+		}
 		allMedarotsToDraw = append(allMedarotsToDraw, MedarotDrawInfo{
 			Identity: IdentityComponentType.Get(entry),
 			Status:   StatusComponentType.Get(entry),
 			Render:   RenderComponentType.Get(entry),
 			Parts:    PartsComponentType.Get(entry),
+			Entity:   entry.Entity(),
+			Armor:    armor,
 		})
 	})
 	// チームと描画インデックスでソート
@@ -99,33 +130,24 @@ func (sys *RenderSystem) drawAllMedarots(screen *ebiten.Image, ecs *ecs.ECS, con
 		return allMedarotsToDraw[i].Render.DrawIndex < allMedarotsToDraw[j].Render.DrawIndex
 	})
 
+	var sprites *SpriteAtlas
+	if configEntry, ok := ConfigComponentType.First(ecs.World); ok && ConfigComponentType.Get(configEntry).GameData != nil {
+		sprites = ConfigComponentType.Get(configEntry).GameData.Sprites
+	}
 	for _, mdi := range allMedarotsToDraw {
-		sys.drawMedarotIcon(screen, mdi.Identity, mdi.Status, mdi.Render, config)
-		sys.drawMedarotInfo(screen, mdi.Identity, mdi.Status, mdi.Parts, mdi.Render, config, GameStateComponentType.Get(GameStateComponentType.MustFirst(ecs.World)).DebugMode)
+		offsetX, offsetY := animationIconOffset(ecs.World, sys.animationQuery, mdi.Entity)
+		sys.drawMedarotIcon(screen, mdi.Identity, mdi.Status, mdi.Render, mdi.Parts, sprites, config, float32(offsetX), float32(offsetY))
+		sys.drawMedarotInfo(screen, mdi.Identity, mdi.Status, mdi.Parts, mdi.Armor, mdi.Render, config, GameStateComponentType.Get(GameStateComponentType.MustFirst(ecs.World)).DebugMode)
 	}
 }
 
 // drawMedarotIcon はメダロットのアイコンをバトルフィールドに描画します。
-func (sys *RenderSystem) drawMedarotIcon(screen *ebiten.Image, identity *IdentityComponent, status *StatusComponent, render *RenderComponent, config *Config) {
+// パーツのスプライトがそろっていればそれを重ねて描き、なければ円で描きます。
+// offsetX, offsetY は突進・回避の演出によるずれです。
+func (sys *RenderSystem) drawMedarotIcon(screen *ebiten.Image, identity *IdentityComponent, status *StatusComponent, render *RenderComponent, parts *PartsComponent, sprites *SpriteAtlas, config *Config, offsetX, offsetY float32) {
 	bf := config.UI.Battlefield
-	baseYPos := bf.MedarotVerticalSpacing * float32(render.DrawIndex+1)
-	progress := status.Gauge / 100.0
-	homeX, execX := bf.Team1HomeX, bf.Team1ExecutionLineX
-	if identity.Team == Team2 {
-		homeX, execX = bf.Team2HomeX, bf.Team2ExecutionLineX
-	}
-
-	var currentX float32
-	switch status.State {
-	case StateActionCharging:
-		currentX = homeX + float32(progress)*(execX-homeX)
-	case StateReadyToExecuteAction:
-		currentX = execX
-	case StateActionCooldown:
-		currentX = execX - float32(progress)*(execX-homeX)
-	default:
-		currentX = homeX
-	}
+	currentX, baseYPos := medarotIconPosition(config, identity, status, render)
+	currentX, baseYPos = currentX+offsetX, baseYPos+offsetY
 
 	iconColor := config.UI.Colors.Team1
 	if identity.Team == Team2 {
@@ -134,34 +156,192 @@ func (sys *RenderSystem) drawMedarotIcon(screen *ebiten.Image, identity *Identit
 	if status.IsBroken() {
 		iconColor = config.UI.Colors.Broken
 	}
-	vector.DrawFilledCircle(screen, currentX, baseYPos, bf.IconRadius, iconColor, true)
+	if !drawMedarotSprite(screen, sprites, identity, status, parts, currentX, baseYPos, config) {
+		fillCircle(screen, currentX, baseYPos, bf.IconRadius, iconColor, true)
+	}
 	if identity.IsLeader {
-		vector.StrokeCircle(screen, currentX, baseYPos, bf.IconRadius+2, 2, config.UI.Colors.Leader, true)
+		strokeCircle(screen, currentX, baseYPos, bf.IconRadius+2, 2, config.UI.Colors.Leader, true)
 	}
 }
 
 // drawMedarotInfo は情報パネルを描画します。ui_draw.goのヘルパーを呼び出します。
-func (sys *RenderSystem) drawMedarotInfo(screen *ebiten.Image, identity *IdentityComponent, status *StatusComponent, parts *PartsComponent, render *RenderComponent, config *Config, debug bool) {
-	ip := config.UI.InfoPanel
-	var panelX, panelY float32
-	if identity.Team == Team1 {
-		panelX = ip.Padding
-		panelY = ip.StartY + ip.Padding + float32(render.DrawIndex)*(ip.BlockHeight+ip.Padding)
-	} else {
-		panelX = ip.Padding*2 + ip.BlockWidth
-		panelY = ip.StartY + ip.Padding + float32(render.DrawIndex)*(ip.BlockHeight+ip.Padding)
+func (sys *RenderSystem) drawMedarotInfo(screen *ebiten.Image, identity *IdentityComponent, status *StatusComponent, parts *PartsComponent, armor *ArmorDisplayComponent, render *RenderComponent, config *Config, debug bool) {
+	panelRect := infoPanelRect(config, identity, render)
+	drawMedarotInfoPanel(screen, identity, status, parts, armor, float32(panelRect.Min.X), float32(panelRect.Min.Y), config, debug)
+}
+
+// canvasFor は画面と同じ大きさのオフスクリーン画像を返します。サイズが変わった場合は作り直します。
+func (sys *RenderSystem) canvasFor(screen *ebiten.Image) *ebiten.Image {
+	if sys.canvas == nil || sys.canvas.Bounds().Size() != screen.Bounds().Size() {
+		if sys.canvas != nil {
+			sys.canvas.Deallocate()
+		}
+		sys.canvas = ebiten.NewImage(screen.Bounds().Dx(), screen.Bounds().Dy())
+	}
+	return sys.canvas
+}
+
+// screenShakeOffset は現在の画面揺れによるずれを返します。
+func screenShakeOffset(ecs *ecs.ECS) (float64, float64) {
+	entry, ok := ScreenShakeComponentType.First(ecs.World)
+	if !ok {
+		return 0, 0
+	}
+	shake := ScreenShakeComponentType.Get(entry)
+	if shake.Remaining <= 0 || shake.Duration <= 0 {
+		return 0, 0
+	}
+	amount := shake.Intensity * shake.Remaining / shake.Duration
+	return (rand.Float64()*2 - 1) * amount, (rand.Float64()*2 - 1) * amount
+}
+
+// drawAnimations は弾・ダメージ数値・フラッシュ・火花などの演出を描画します。
+func (sys *RenderSystem) drawAnimations(screen *ebiten.Image, ecs *ecs.ECS, config *Config) {
+	ui := config.UI
+	sys.animationQuery.Each(ecs.World, func(entry *donburi.Entry) {
+		anim := AnimationComponentType.Get(entry)
+		if !anim.Active() {
+			return
+		}
+		p := anim.Progress()
+		switch anim.Kind {
+		case AnimProjectile:
+			x := anim.From.X + (anim.To.X-anim.From.X)*p
+			y := anim.From.Y + (anim.To.Y-anim.From.Y)*p
+			fillCircle(screen, float32(x), float32(y), 4, ui.Colors.Yellow, true)
+		case AnimDamagePopup:
+			if Fonts == nil {
+				return
+			}
+			popupColor, style := ui.Colors.White, FontNumbers
+			if anim.Critical {
+				popupColor, style = ui.Colors.Orange, FontTitle
+			}
+			alpha := 1 - math.Max(p-0.6, 0)/0.4 // 後半で消えていく
+			op := &text.DrawOptions{}
+			op.ColorScale.ScaleWithColor(popupColor)
+			op.ColorScale.ScaleAlpha(float32(alpha))
+			drawTextWithOptions(screen, anim.Text, style, anim.From.X-Fonts.Measure(style, anim.Text)/2, anim.From.Y-30*p, op)
+		case AnimCritFlash:
+			alpha := uint8(140 * (1 - p))
+			fillRect(screen, 0, 0, float32(ui.Screen.Width), float32(ui.Screen.Height), color.NRGBA{255, 255, 255, alpha}, false)
+		case AnimSparks:
+			const sparkCount = 8
+			for i := 0; i < sparkCount; i++ {
+				angle := 2 * math.Pi * float64(i) / sparkCount
+				inner, outer := 6+20*p, 12+30*p
+				x0, y0 := anim.From.X+math.Cos(angle)*inner, anim.From.Y+math.Sin(angle)*inner
+				x1, y1 := anim.From.X+math.Cos(angle)*outer, anim.From.Y+math.Sin(angle)*outer
+				sparkColor := color.NRGBA{255, uint8(200 - 120*p), 60, uint8(255 * (1 - p))}
+				strokeLine(screen, float32(x0), float32(y0), float32(x1), float32(y1), 2, sparkColor, true)
+			}
+		}
+	})
+}
+
+// drawHUD は戦闘速度・メッセージモード・テーマ・表示言語の切り替えボタンを描画します。
+func (sys *RenderSystem) drawHUD(screen *ebiten.Image, settings *Settings, config *Config) {
+	if settings == nil {
+		return
 	}
-	drawMedarotInfoPanel(screen, identity, status, parts, panelX, panelY, config, debug)
+	ui := config.UI
+	speedRect, modeRect, themeRect, languageRect := hudButtonRects(config)
+	DrawButton(screen, speedRect, T("hud.speed", "speed", settings.GaugeSpeed), FontSmall, ui.Colors.Background, ui.Colors.White, ui.Colors.Gray)
+	DrawButton(screen, modeRect, T("hud.message_mode", "mode", messageModeLabel(settings.MessageMode)), FontSmall, ui.Colors.Background, ui.Colors.White, ui.Colors.Gray)
+	themeName := settings.Theme
+	if themeName == "" {
+		themeName = defaultThemeName
+	}
+	DrawButton(screen, themeRect, fmt.Sprintf("%s [T]", themeName), FontSmall, ui.Colors.Background, ui.Colors.White, ui.Colors.Gray)
+	DrawButton(screen, languageRect, T("hud.language", "language", T("language.name")), FontSmall, ui.Colors.Background, ui.Colors.White, ui.Colors.Gray)
 }
 
 // drawUI はゲームの状態に応じたUI（行動選択モーダル、メッセージパネルなど）を描画します。
 func (sys *RenderSystem) drawUI(screen *ebiten.Image, ecs *ecs.ECS, gs *GameStateComponent, pasComp *PlayerActionSelectComponent, config *Config) {
 	switch gs.CurrentState {
 	case StatePlayerActionSelect:
+		if pasComp.HandOff {
+			sys.drawHandOffScreen(screen, pasComp, config)
+			return
+		}
 		sys.drawActionSelectModal(screen, ecs, pasComp, config)
 	case GameStateMessage, GameStateOver:
 		sys.drawGameMessagePanel(screen, gs, config)
+	case StatePaused:
+		sys.drawPauseMenu(screen, ecs, gs, config)
+	case StateMedarotDetail:
+		sys.drawMedarotDetail(screen, ecs, gs, config)
+	}
+}
+
+// drawPauseMenu はポーズメニューを描画します。
+func (sys *RenderSystem) drawPauseMenu(screen *ebiten.Image, ecs *ecs.ECS, gs *GameStateComponent, config *Config) {
+	ui := config.UI
+	fillRect(screen, 0, 0, float32(ui.Screen.Width), float32(ui.Screen.Height), color.NRGBA{R: 0, G: 0, B: 0, A: 180}, false)
+
+	items := pauseMenuItems(ecs, sys.medarotQuery)
+	first := pauseMenuItemRect(&ui, 0, len(items))
+	last := pauseMenuItemRect(&ui, len(items)-1, len(items))
+	windowRect := image.Rect(first.Min.X-20, first.Min.Y-40, first.Max.X+20, last.Max.Y+20)
+	DrawWindow(screen, windowRect, ui.Colors.Background, ui.Colors.White, ui.Style.BorderWidth)
+	DrawTextCentered(screen, T("menu.title"), FontTitle, float64(ui.Screen.Width)/2, float64(first.Min.Y-16), ui.Colors.White)
+
+	for i, item := range items {
+		rect := pauseMenuItemRect(&ui, i, len(items))
+		DrawButton(screen, rect, item.Label, FontBody, ui.Colors.Background, ui.Colors.White, ui.Colors.Gray)
+		if i == gs.MenuIndex {
+			DrawFocusFrame(screen, rect, ui.Colors.Yellow)
+		}
+	}
+}
+
+// drawMedarotDetail はメダロットの詳細画面を描画します。
+func (sys *RenderSystem) drawMedarotDetail(screen *ebiten.Image, ecs *ecs.ECS, gs *GameStateComponent, config *Config) {
+	if !ecs.World.Valid(gs.DetailTarget) || Fonts == nil {
+		return
+	}
+	ui := config.UI
+	fillRect(screen, 0, 0, float32(ui.Screen.Width), float32(ui.Screen.Height), color.NRGBA{R: 0, G: 0, B: 0, A: 200}, false)
+
+	marginX, marginY := ui.Screen.Width/20, ui.Screen.Height/20
+	windowRect := image.Rect(marginX, marginY, ui.Screen.Width-marginX, ui.Screen.Height-marginY)
+	DrawWindow(screen, windowRect, ui.Colors.Background, ui.Colors.White, ui.Style.BorderWidth)
+
+	speed := gaugeSpeedMultiplier(ConfigComponentType.Get(ConfigComponentType.MustFirst(ecs.World)))
+	left, right := medarotDetailLines(ecs.World.Entry(gs.DetailTarget), config, speed)
+	lineHeight := int(math.Ceil(Fonts.LineHeight(FontBody))) + 2
+	columnX := []int{windowRect.Min.X + 20, windowRect.Min.X + windowRect.Dx()/2 + 10}
+	for col, lines := range [][]string{left, right} {
+		y := windowRect.Min.Y + 20 + lineHeight
+		for _, line := range lines {
+			if y > windowRect.Max.Y-30 {
+				break
+			}
+			DrawText(screen, line, FontBody, float64(columnX[col]), float64(y), ui.Colors.White)
+			y += lineHeight
+		}
+	}
+
+	prompt := T("detail.close")
+	DrawText(screen, prompt, FontSmall, float64(windowRect.Max.X-20)-Fonts.Measure(FontSmall, prompt), float64(windowRect.Max.Y-12), ui.Colors.Gray)
+}
+
+// drawHandOffScreen はホットシート対戦でプレイヤーが交代するときの画面を描画します。
+// 前のプレイヤーの選択や戦況が見えないよう、画面全体を覆います。
+func (sys *RenderSystem) drawHandOffScreen(screen *ebiten.Image, pasComp *PlayerActionSelectComponent, config *Config) {
+	ui := config.UI
+	fillRect(screen, 0, 0, float32(ui.Screen.Width), float32(ui.Screen.Height), ui.Colors.Background, false)
+	if Fonts == nil {
+		return
+	}
+	player := T("hotseat.player", "number", pasComp.ActivePlayer)
+	titleColor := ui.Colors.Team1
+	if pasComp.ActivePlayer == 2 {
+		titleColor = ui.Colors.Team2
 	}
+	centerX, centerY := float64(ui.Screen.Width)/2, float64(ui.Screen.Height)/2
+	DrawTextCentered(screen, T("hotseat.handoff_title", "player", player), FontTitle, centerX, centerY, titleColor)
+	DrawTextCentered(screen, T("hotseat.handoff_prompt", "player", player), FontSmall, centerX, centerY+Fonts.LineHeight(FontTitle), ui.Colors.Gray)
 }
 
 // drawActionSelectModal は行動選択モーダルを描画します。
@@ -180,41 +360,90 @@ func (sys *RenderSystem) drawActionSelectModal(screen *ebiten.Image, ecs *ecs.EC
 
 	// 背景オーバーレイ
 	overlayColor := color.NRGBA{R: 0, G: 0, B: 0, A: 180}
-	vector.DrawFilledRect(screen, 0, 0, float32(ui.Screen.Width), float32(ui.Screen.Height), overlayColor, false)
+	fillRect(screen, 0, 0, float32(ui.Screen.Width), float32(ui.Screen.Height), overlayColor, false)
+
+	// 選択中のターゲットを強調表示（オーバーレイの上に描く）
+	sys.drawTargetHighlight(screen, ecs, pasComp.CurrentTarget, config)
 
 	// ウィンドウ
-	boxW, boxH := 320, 200
-	boxX := (ui.Screen.Width - boxW) / 2
-	boxY := (ui.Screen.Height - boxH) / 2
-	windowRect := image.Rect(boxX, boxY, boxX+boxW, boxY+boxH)
-	DrawWindow(screen, windowRect, ui.Colors.Background, ui.Colors.Team1)
+	windowRect := actionModalRect(&ui)
+	boxY, boxH := windowRect.Min.Y, windowRect.Dy()
+	borderColor := ui.Colors.Team1
+	if identity.Team == Team2 {
+		borderColor = ui.Colors.Team2
+	}
+	DrawWindow(screen, windowRect, ui.Colors.Background, borderColor, ui.Style.BorderWidth)
 
-	// タイトル
-	titleStr := fmt.Sprintf("%s の行動を選択", identity.Name)
-	if MplusFont != nil {
-		bounds := text.BoundString(MplusFont, titleStr)
-		titleWidth := (bounds.Max.X - bounds.Min.X)
-		text.Draw(screen, titleStr, MplusFont, ui.Screen.Width/2-titleWidth/2, boxY+30, ui.Colors.White)
+	// タイトル（ホットシート対戦ではどちらのプレイヤーの番かも示す）
+	titleStr := T("action_select.title", "name", identity.Name)
+	if ConfigComponentType.Get(ConfigComponentType.MustFirst(ecs.World)).Match.Hotseat {
+		titleStr = T("action_select.title_player", "player", T("hotseat.player", "number", pasComp.ActivePlayer), "name", identity.Name)
 	}
+	DrawTextCentered(screen, titleStr, FontTitle, float64(ui.Screen.Width)/2, float64(boxY+30), ui.Colors.White)
 
 	// アクションボタン
 	for i, slotKey := range pasComp.AvailableActions {
 		partData := actingPartsComp.Parts[slotKey]
-		btnW, btnH, btnS := ui.ActionModal.ButtonWidth, ui.ActionModal.ButtonHeight, ui.ActionModal.ButtonSpacing
-		btnX := ui.Screen.Width/2 - int(btnW/2)
-		btnY := ui.Screen.Height/2 - 50 + (int(btnH)+int(btnS))*i
-		btnRect := image.Rect(btnX, btnY, btnX+int(btnW), btnY+int(btnH))
+		btnRect := actionButtonRect(&ui, i)
 
-		partStr := fmt.Sprintf("%s (%s)", partData.PartName, partData.Type)
+		partStr := fmt.Sprintf("%s (%s)", partData.DisplayName(), partData.Type)
 		if partData.Category == CategoryShoot || partData.Category == CategoryFight {
 			if ecs.World.Valid(pasComp.CurrentTarget) {
 				if targetEntry := ecs.World.Entry(pasComp.CurrentTarget); targetEntry.Valid() {
 					partStr += fmt.Sprintf(" -> %s", IdentityComponentType.Get(targetEntry).Name)
+					targetStatus := visibleStatus(ecs.World, targetEntry)
+					if preview, ok := previewActionWithStatus(actingMedarotEntry, partData, targetEntry, &targetStatus, config.Balance); ok {
+						partStr += "  " + formatActionPreview(preview)
+					}
 				}
 			}
 		}
-		DrawButton(screen, btnRect, partStr, MplusFont, ui.Colors.Background, ui.Colors.White, ui.Colors.White)
+		DrawButton(screen, btnRect, partStr, FontBody, ui.Colors.Background, ui.Colors.White, ui.Colors.White)
+		if i == pasComp.FocusIndex {
+			DrawFocusFrame(screen, btnRect, ui.Colors.Yellow)
+		}
+	}
+
+	// 操作ガイド
+	DrawTextCentered(screen, T("action_select.guide"), FontSmall, float64(ui.Screen.Width)/2, float64(boxY+boxH-10), ui.Colors.Gray)
+}
+
+// formatActionPreview は行動ボタンに添える命中率・クリティカル率・ダメージ幅の文字列を作ります。
+func formatActionPreview(p ActionPreview) string {
+	damage := fmt.Sprintf("%d", p.MinDamage)
+	if p.MaxDamage != p.MinDamage {
+		damage = fmt.Sprintf("%d-%d", p.MinDamage, p.MaxDamage)
+	}
+	str := T("action_select.preview", "hit", fmt.Sprintf("%.0f", p.HitProbability*100), "crit", fmt.Sprintf("%.0f", p.CritProbability*100), "damage", damage)
+	if p.CritProbability > 0 {
+		str += T("action_select.preview_crit", "damage", p.MaxCritDamage)
+	}
+	return str
+}
+
+// drawTargetHighlight は選択中のターゲットのアイコンと情報パネルに照準と枠を描画します。
+func (sys *RenderSystem) drawTargetHighlight(screen *ebiten.Image, ecs *ecs.ECS, target donburi.Entity, config *Config) {
+	if !ecs.World.Valid(target) {
+		return
+	}
+	targetEntry := ecs.World.Entry(target)
+	if !targetEntry.HasComponent(RenderComponentType) {
+		return
 	}
+	identity := IdentityComponentType.Get(targetEntry)
+	status := StatusComponentType.Get(targetEntry)
+	render := RenderComponentType.Get(targetEntry)
+	highlight := config.UI.Colors.Yellow
+
+	x, y := medarotIconPosition(config, identity, status, render)
+	r := config.UI.Battlefield.IconRadius + 6
+	strokeCircle(screen, x, y, r, 2, highlight, true)
+	strokeLine(screen, x-r-6, y, x-r+4, y, 2, highlight, true)
+	strokeLine(screen, x+r-4, y, x+r+6, y, 2, highlight, true)
+	strokeLine(screen, x, y-r-6, x, y-r+4, 2, highlight, true)
+	strokeLine(screen, x, y+r-4, x, y+r+6, 2, highlight, true)
+
+	DrawFocusFrame(screen, infoPanelRect(config, identity, render), highlight)
 }
 
 // drawGameMessagePanel はメッセージやゲームオーバー表示を描画します。
@@ -226,13 +455,75 @@ func (sys *RenderSystem) drawGameMessagePanel(screen *ebiten.Image, gs *GameStat
 	y := int(ui.Battlefield.Height) - height/2
 	rect := image.Rect(x, y, x+width, y+height)
 
-	prompt := ""
+	prompt, messageStyle := "", FontBody
 	if gs.CurrentState == GameStateMessage {
-		prompt = "クリックして続行..."
+		prompt = T("message.continue")
+		if remaining := len(gs.MessageQueue) - 1; remaining > 0 {
+			prompt = T("message.continue_remaining", "count", remaining)
+		}
 	} else if gs.CurrentState == GameStateOver {
-		prompt = "クリックでリスタート"
+		prompt, messageStyle = T("message.restart"), FontTitle
+	}
+	DrawMessagePanel(screen, rect, gs.CurrentMessage(), prompt, messageStyle, &ui)
+}
+
+// drawTooltip はカーソル位置にあるパーツ・メダロット・行動ボタンのツールチップを描画します。
+func (sys *RenderSystem) drawTooltip(screen *ebiten.Image, ecs *ecs.ECS, gs *GameStateComponent, pasComp *PlayerActionSelectComponent, config *Config) {
+	switch gs.CurrentState {
+	case GameStateOver, StatePaused, StateMedarotDetail:
+		return
+	}
+	if gs.CurrentState == StatePlayerActionSelect && pasComp.HandOff {
+		return
+	}
+	cursor := cursorPosition()
+	lines := hoverTooltipLines(ecs, sys.medarotQuery, gs, pasComp, config, cursor)
+	DrawTooltip(screen, cursor.X, cursor.Y, lines, &config.UI)
+}
+
+// drawReloadBanner はデータ・設定の再読み込みの結果を帯状に表示します。
+func (sys *RenderSystem) drawReloadBanner(screen *ebiten.Image, ecs *ecs.ECS, config *Config) {
+	entry, ok := ReloadBannerComponentType.First(ecs.World)
+	if !ok || Fonts == nil {
+		return
+	}
+	banner := ReloadBannerComponentType.Get(entry)
+	if banner.Text == "" {
+		return
+	}
+	ui := &config.UI
+	bgColor := color.NRGBA{R: 0x20, G: 0x60, B: 0x30, A: 230}
+	if banner.IsError {
+		bgColor = color.NRGBA{R: 0x90, G: 0x18, B: 0x18, A: 230}
+	}
+	// HUDと重ならないよう、戦場の下端に表示する
+	height := int(ui.InfoPanel.TextLineHeight) + 12
+	bottom := int(ui.Battlefield.Height)
+	rect := image.Rect(0, bottom-height, ui.Screen.Width, bottom)
+	DrawWindow(screen, rect, bgColor, ui.Colors.White, 0)
+	DrawText(screen, banner.Text, FontBody, 8, float64(bottom-8), ui.Colors.White)
+}
+
+// drawNetworkStatus はネット対戦の状態（相手待ち・切断・同期ずれ）を左上に表示します。
+func (sys *RenderSystem) drawNetworkStatus(screen *ebiten.Image, ecs *ecs.ECS, config *Config) {
+	entry, ok := NetworkStatusComponentType.First(ecs.World)
+	if !ok || Fonts == nil {
+		return
+	}
+	status := NetworkStatusComponentType.Get(entry)
+	if status.Text == "" {
+		return
+	}
+	ui := &config.UI
+	bgColor := ui.Colors.Panel
+	if status.IsError {
+		bgColor = color.NRGBA{R: 0x90, G: 0x18, B: 0x18, A: 230}
 	}
-	DrawMessagePanel(screen, rect, gs.Message, prompt, MplusFont, &ui)
+	margin := int(ui.HUD.Margin)
+	width := int(math.Ceil(Fonts.Measure(FontSmall, status.Text))) + 16
+	rect := image.Rect(margin, margin, margin+width, margin+int(ui.HUD.ButtonHeight))
+	DrawWindow(screen, rect, bgColor, ui.Colors.White, 0)
+	DrawText(screen, status.Text, FontSmall, float64(rect.Min.X+8), float64(rect.Max.Y)-(float64(rect.Dy())-Fonts.Ascent(FontSmall))/2, ui.Colors.White)
 }
 
 // drawDebugInfo はデバッグ情報を描画します。
@@ -244,7 +535,7 @@ func (sys *RenderSystem) drawDebugInfo(screen *ebiten.Image, ecs *ecs.ECS, gs *G
 	for _, e := range pasComp.ActionQueue {
 		queueIds = append(queueIds, int(e.Id()))
 	}
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Tick:%d St:%s Ent:%d Q:%v",
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Tick:%d St:%d Ent:%d Q:%v",
 		gs.TickCount, gs.CurrentState, ecs.World.Len(), queueIds),
-		10, config.UI.Screen.Height-15)
+		int(10*deviceScale), int(float64(config.UI.Screen.Height-15)*deviceScale))
 }
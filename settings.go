@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// MessageMode はメッセージの送り方を定義します。
+type MessageMode int
+
+const (
+	MessageModeManual  MessageMode = iota // クリックで送る
+	MessageModeAuto                       // 一定時間で自動的に送る
+	MessageModeInstant                    // 待たずに即座に送る
+)
+
+var messageModeNames = map[MessageMode]string{
+	MessageModeManual:  "manual",
+	MessageModeAuto:    "auto",
+	MessageModeInstant: "instant",
+}
+
+// String は設定ファイルなどで使うモード名を返します。
+func (m MessageMode) String() string {
+	if name, ok := messageModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("MessageMode(%d)", int(m))
+}
+
+// MarshalText はモードを文字列として保存するためのメソッドです。
+func (m MessageMode) MarshalText() ([]byte, error) {
+	if _, ok := messageModeNames[m]; !ok {
+		return nil, fmt.Errorf("unknown message mode %d", int(m))
+	}
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText は文字列からモードを復元します。
+func (m *MessageMode) UnmarshalText(b []byte) error {
+	for mode, name := range messageModeNames {
+		if name == string(b) {
+			*m = mode
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown message mode %q", string(b))
+}
+
+// GaugeSpeeds は選択可能なゲージ速度の倍率です。
+var GaugeSpeeds = []int{1, 2, 4, 8}
+
+// Settings はプレイヤーが実行中に変更でき、次回起動時にも引き継がれる設定です。
+type Settings struct {
+	GaugeSpeed    int         `json:"gauge_speed"`        // ゲージ速度の倍率 (GaugeSpeedsのいずれか)
+	MessageMode   MessageMode `json:"message_mode"`       // メッセージの送り方
+	MessageAutoMs int         `json:"message_auto_ms"`    // 自動送りモードでメッセージを表示しておく時間 (ミリ秒)
+	Fullscreen    bool        `json:"fullscreen"`         // フルスクリーンで表示する
+	Theme         string      `json:"theme,omitempty"`    // 配色テーマの名前（空ならdefault）
+	Language      string      `json:"language,omitempty"` // 表示言語（空なら日本語）
+
+	path string // 保存先。空の場合は保存しない
+}
+
+// DefaultSettings はデフォルトの設定を返します。
+func DefaultSettings() *Settings {
+	return &Settings{
+		GaugeSpeed:    1,
+		MessageMode:   MessageModeManual,
+		MessageAutoMs: 1200,
+	}
+}
+
+// DefaultSettingsPath はユーザー設定ディレクトリ内の設定ファイルのパスを返します。
+func DefaultSettingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "medarot-ebiten", "settings.json"), nil
+}
+
+// LoadSettings は設定ファイルを読み込みます。ファイルがなければデフォルト設定を返します。
+// 返された設定のSaveは同じパスに書き込みます。
+func LoadSettings(path string) (*Settings, error) {
+	settings := DefaultSettings()
+	settings.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, fmt.Errorf("failed to read settings %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, settings); err != nil {
+		return DefaultSettings(), fmt.Errorf("failed to parse settings %s: %w", path, err)
+	}
+	settings.normalize()
+	return settings, nil
+}
+
+// Save は設定をファイルに書き込みます。
+func (s *Settings) Save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create settings directory: %w", err)
+	}
+	return os.WriteFile(s.path, data, 0o644)
+}
+
+// normalize は不正な値をデフォルト値に戻します。
+func (s *Settings) normalize() {
+	valid := false
+	for _, speed := range GaugeSpeeds {
+		if s.GaugeSpeed == speed {
+			valid = true
+		}
+	}
+	if !valid {
+		s.GaugeSpeed = 1
+	}
+	if _, ok := messageModeNames[s.MessageMode]; !ok {
+		s.MessageMode = MessageModeManual
+	}
+	if s.MessageAutoMs <= 0 {
+		s.MessageAutoMs = DefaultSettings().MessageAutoMs
+	}
+}
+
+// CycleGaugeSpeed はゲージ速度を次の倍率に切り替えます (x1 -> x2 -> x4 -> x8 -> x1)。
+func (s *Settings) CycleGaugeSpeed() {
+	for i, speed := range GaugeSpeeds {
+		if s.GaugeSpeed == speed {
+			s.GaugeSpeed = GaugeSpeeds[(i+1)%len(GaugeSpeeds)]
+			return
+		}
+	}
+	s.GaugeSpeed = GaugeSpeeds[0]
+}
+
+// CycleMessageMode はメッセージの送り方を次のモードに切り替えます (手動 -> 自動 -> 即時 -> 手動)。
+func (s *Settings) CycleMessageMode() {
+	s.MessageMode = (s.MessageMode + 1) % MessageMode(len(messageModeNames))
+}
+
+// messageModeLabel はHUDに表示するモード名を返します。
+func messageModeLabel(mode MessageMode) string {
+	if _, ok := messageModeNames[mode]; !ok {
+		mode = MessageModeManual
+	}
+	return T("message_mode." + mode.String())
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/yohamta/donburi/ecs"
+)
+
+// 観戦配信: 戦闘の状態のスナップショットと戦闘イベントを、HTTPのServer-Sent Events（SSE）でJSONとして配信します。
+// 観戦者は何人でも接続でき、読み取り専用です。大会の実況や、外部のオーバーレイからの利用を想定しています。
+//
+//	GET /          参考用のビューア（spectator_viewer.html）
+//	GET /stream    SSEの配信。接続直後に最新のスナップショットを送り、以降は snapshot と combat のイベントを送る
+//	GET /snapshot  最新のスナップショットを1回だけ返す
+
+//go:embed spectator_viewer.html
+var spectatorViewerHTML []byte
+
+const (
+	spectatorClientBuffer = 64               // 観戦者ごとに溜めておける未送信のメッセージ数。あふれた観戦者は切断する
+	spectatorKeepAlive    = 15 * time.Second // 中継サーバーに切断されないよう、この間隔で空のコメントを送る
+)
+
+// SpectatorServer は観戦配信のHTTPサーバーです。CombatEventListenerとして戦闘イベントを受け取り、
+// Updateで一定のティックごとにスナップショットを作って、接続中の観戦者全員に送ります。
+// UpdateとOnCombatEventはゲームのループから、HTTPのハンドラは別のゴルーチンから呼ばれます。
+type SpectatorServer struct {
+	interval   int // スナップショットを作る間隔（ティック）
+	listener   net.Listener
+	server     *http.Server
+	lastWorld  *ecs.ECS // リスタートで作り直されたワールドを検出するため
+	battle     int
+	lastUpdate int
+
+	mu       sync.Mutex
+	clients  map[chan []byte]struct{}
+	snapshot []byte // 最新のスナップショット（JSON）
+}
+
+// StartSpectatorServer はaddrで観戦配信の待ち受けを始めます。intervalティックごとにスナップショットを配信します。
+func StartSpectatorServer(addr string, interval int) (*SpectatorServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for spectators on %s: %w", addr, err)
+	}
+	if interval < 1 {
+		interval = 1
+	}
+	s := &SpectatorServer{
+		interval: interval,
+		listener: listener,
+		clients:  make(map[chan []byte]struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.serveViewer)
+	mux.HandleFunc("/stream", s.serveStream)
+	mux.HandleFunc("/snapshot", s.serveSnapshot)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Spectator server stopped: %v", err)
+		}
+	}()
+	return s, nil
+}
+
+// Addr は待ち受けているアドレスを返します。
+func (s *SpectatorServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close はサーバーを止め、接続中の観戦者を切断します。
+func (s *SpectatorServer) Close() error {
+	return s.server.Close()
+}
+
+// Update は一定のティックごとにスナップショットを作り、前回から変わっていれば配信します。
+func (s *SpectatorServer) Update(ecs *ecs.ECS) {
+	gsEntry, ok := GameStateComponentType.First(ecs.World)
+	if !ok {
+		return
+	}
+	tick := GameStateComponentType.Get(gsEntry).TickCount
+	if ecs != s.lastWorld {
+		// 新しい戦闘はすぐに配信して、ビューアに前の戦闘の表示を片付けさせる
+		s.lastWorld = ecs
+		s.battle++
+	} else if tick-s.lastUpdate < s.interval {
+		return
+	}
+	s.lastUpdate = tick
+	snap := BuildBattleSnapshot(ecs.World)
+	snap.Battle = s.battle
+	data, err := json.Marshal(snap)
+	if err != nil {
+		log.Printf("Failed to encode spectator snapshot: %v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if string(data) == string(s.snapshot) {
+		return
+	}
+	s.snapshot = data
+	s.broadcastLocked(sseMessage("snapshot", data))
+}
+
+// OnCombatEvent は戦闘イベントをそのまま観戦者に配信します。
+func (s *SpectatorServer) OnCombatEvent(ev CombatEvent) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("Failed to encode combat event for spectators: %v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.broadcastLocked(sseMessage("combat", data))
+}
+
+// sseMessage はServer-Sent Eventsの1件分のメッセージを組み立てます。JSONは改行を含まないので1行のdataで送れます。
+func sseMessage(event string, data []byte) []byte {
+	return []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", event, data))
+}
+
+// broadcastLocked は接続中の観戦者全員にメッセージを送ります。呼び出し側でmuをロックしておきます。
+// 受信が追いつかない観戦者はゲームを止めないよう切断します（ビューアは再接続して最新の状態から再開します）。
+func (s *SpectatorServer) broadcastLocked(msg []byte) {
+	for ch := range s.clients {
+		select {
+		case ch <- msg:
+		default:
+			delete(s.clients, ch)
+			close(ch)
+		}
+	}
+}
+
+// serveViewer は参考用のビューアを返します。
+func (s *SpectatorServer) serveViewer(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(spectatorViewerHTML)
+}
+
+// serveSnapshot は最新のスナップショットを返します。まだ作られていなければ503を返します。
+func (s *SpectatorServer) serveSnapshot(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	data := s.snapshot
+	s.mu.Unlock()
+	if data == nil {
+		http.Error(w, "no snapshot yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(data)
+}
+
+// serveStream は観戦者を登録し、切断されるまでメッセージを送り続けます。
+func (s *SpectatorServer) serveStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan []byte, spectatorClientBuffer)
+	s.mu.Lock()
+	if s.snapshot != nil {
+		ch <- sseMessage("snapshot", s.snapshot)
+	}
+	s.clients[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		if _, ok := s.clients[ch]; ok {
+			delete(s.clients, ch)
+			close(ch)
+		}
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*") // 別のオリジンのオーバーレイからも購読できるようにする
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(spectatorKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return // 受信が遅れたため切断された
+			}
+			if _, err := w.Write(msg); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := w.Write([]byte(": keep-alive\n\n")); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/png" // PNG形式のスプライトを読み込むため
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// embeddedAssets はバイナリに埋め込まれた既定のアセットです。
+//
+//go:embed assets
+var embeddedAssets embed.FS
+
+// assetManifestFile はアセットのルートに置くマニフェストのファイル名です。
+const assetManifestFile = "manifest.json"
+
+// spriteScale はアイコンの半径に対するスプライトの表示サイズ（一辺）の倍率です。
+const spriteScale = 2.4
+
+// AssetManifest はパーツIDと画像ファイルの対応表です。パスはアセットのルートからの相対パスです。
+type AssetManifest struct {
+	Parts map[string]string `json:"parts"`
+}
+
+// SpriteAtlas は読み込み済みのスプライト画像を保持します。
+type SpriteAtlas struct {
+	images map[string]*ebiten.Image // 画像パスをキーにする
+}
+
+// OpenAssetFS はアセットを読み込むファイルシステムを返します。
+// dirが空の場合は埋め込みのアセットを、指定された場合は外部ディレクトリを使います。
+func OpenAssetFS(dir string) (fs.FS, error) {
+	if dir != "" {
+		return os.DirFS(dir), nil
+	}
+	return fs.Sub(embeddedAssets, "assets")
+}
+
+// LoadSpriteAtlas はマニフェストを読み込み、参照されている画像をすべて読み込みます。
+// 読み込めなかった画像はログに出して飛ばします（その部位は円の表示に戻ります）。
+func LoadSpriteAtlas(fsys fs.FS) (*SpriteAtlas, *AssetManifest, error) {
+	data, err := fs.ReadFile(fsys, assetManifestFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read asset manifest: %w", err)
+	}
+	var manifest AssetManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse asset manifest: %w", err)
+	}
+
+	atlas := &SpriteAtlas{images: make(map[string]*ebiten.Image)}
+	for partID, path := range manifest.Parts {
+		if _, loaded := atlas.images[path]; loaded {
+			continue
+		}
+		img, err := loadSpriteImage(fsys, path)
+		if err != nil {
+			log.Printf("Sprite for part %s is unavailable: %v", partID, err)
+			continue
+		}
+		atlas.images[path] = img
+	}
+	return atlas, &manifest, nil
+}
+
+// loadSpriteImage は画像ファイルを1つ読み込みます。
+func loadSpriteImage(fsys fs.FS, path string) (*ebiten.Image, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return ebiten.NewImageFromImage(img), nil
+}
+
+// ApplyManifest はマニフェストに従って各パーツのSpriteを設定します。
+func ApplyManifest(allParts map[string]*Part, manifest *AssetManifest) {
+	if manifest == nil {
+		return
+	}
+	for id, part := range allParts {
+		part.Sprite = manifest.Parts[id]
+	}
+}
+
+// Sprite はパスに対応する画像を返します。読み込まれていなければnilです。
+func (a *SpriteAtlas) Sprite(path string) *ebiten.Image {
+	if a == nil || path == "" {
+		return nil
+	}
+	return a.images[path]
+}
+
+// medarotSpriteLayers は脚部・腕・頭部の順に重ねるスロットの並びです。
+var medarotSpriteLayers = []PartSlotKey{PartSlotLegs, PartSlotLeftArm, PartSlotRightArm, PartSlotHead}
+
+// drawMedarotSprite はパーツのスプライトを重ねてメダロットを描画します。
+// いずれかのパーツの画像がない場合は何も描かずにfalseを返し、呼び出し側で円を描きます。
+func drawMedarotSprite(screen *ebiten.Image, atlas *SpriteAtlas, identity *IdentityComponent, status *StatusComponent, parts *PartsComponent, cx, cy float32, config *Config) bool {
+	if atlas == nil || parts == nil {
+		return false
+	}
+	images := make([]*ebiten.Image, len(medarotSpriteLayers))
+	for i, slotKey := range medarotSpriteLayers {
+		part, ok := parts.Parts[slotKey]
+		if !ok || part == nil {
+			return false
+		}
+		if images[i] = atlas.Sprite(part.Sprite); images[i] == nil {
+			return false
+		}
+	}
+
+	teamColor := config.UI.Colors.Team1
+	if identity.Team == Team2 {
+		teamColor = config.UI.Colors.Team2
+	}
+	size := float64(config.UI.Battlefield.IconRadius) * spriteScale
+	for i, slotKey := range medarotSpriteLayers {
+		img := images[i]
+		w, h := img.Bounds().Dx(), img.Bounds().Dy()
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(-float64(w)/2, -float64(h)/2)
+		if identity.Team == Team2 {
+			op.GeoM.Scale(-1, 1) // チーム2は左向きにする
+		}
+		op.GeoM.Scale(size/float64(w), size/float64(h))
+		op.GeoM.Translate(float64(cx), float64(cy))
+		op.GeoM.Scale(deviceScale, deviceScale)
+		op.Filter = ebiten.FilterLinear
+		// 破壊されたパーツ（または機能停止した機体）は灰色で描く
+		if status.IsBroken() || parts.Parts[slotKey].IsBroken {
+			op.ColorScale.ScaleWithColor(config.UI.Colors.Broken)
+		} else {
+			op.ColorScale.ScaleWithColor(teamColor)
+		}
+		screen.DrawImage(img, op)
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// TelemetryRecorder は戦闘イベントを1行1オブジェクトのJSON Lines形式でファイルへ書き出します。
+// CombatEventListenerを実装しているため、GUI・ヘッドレスのどちらのゲームにも登録できます。
+type TelemetryRecorder struct {
+	mu     sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+	enc    *json.Encoder
+}
+
+// NewTelemetryRecorder は指定されたパスに.jsonlファイルを作成し、レコーダーを返します。
+func NewTelemetryRecorder(path string) (*TelemetryRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create telemetry file %s: %w", path, err)
+	}
+	w := bufio.NewWriter(file)
+	return &TelemetryRecorder{file: file, writer: w, enc: json.NewEncoder(w)}, nil
+}
+
+// OnCombatEvent はイベントを1行のJSONとして書き出します。
+func (r *TelemetryRecorder) OnCombatEvent(ev CombatEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.enc == nil {
+		return
+	}
+	// json.Encoderは1オブジェクトごとに改行を出力するため、そのままJSON Linesになる
+	if err := r.enc.Encode(ev); err != nil {
+		log.Printf("Failed to write telemetry event: %v", err)
+	}
+}
+
+// Close はバッファをフラッシュしてファイルを閉じます。
+func (r *TelemetryRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	flushErr := r.writer.Flush()
+	closeErr := r.file.Close()
+	r.file, r.writer, r.enc = nil, nil, nil
+	if flushErr != nil {
+		return flushErr
+	}
+	return closeErr
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultThemeName は設定ファイルに書かれたそのままの配色（テーマ未適用）の名前です。
+const defaultThemeName = "default"
+
+// Theme は配色・パネルの不透明度・枠の太さ・フォントサイズをまとめたものです。
+// JSONで書かれ、指定されなかった項目はデフォルトの値のままになります。
+type Theme struct {
+	Name        string            `json:"name"`
+	Colors      map[string]string `json:"colors"`       // キーは themeColorKeys のいずれか、値は "#RRGGBB" または "#RRGGBBAA"
+	PanelAlpha  *int              `json:"panel_alpha"`  // 0-255
+	BorderWidth *float32          `json:"border_width"` // ピクセル
+	FontSize    *float64          `json:"font_size"`    // ポイント
+}
+
+// themeColorKeys はテーマファイルで指定できる色の名前と、UIConfig.Colorsの対応するフィールドです。
+var themeColorKeys = map[string]func(ui *UIConfig) *color.Color{
+	"white":      func(ui *UIConfig) *color.Color { return &ui.Colors.White },
+	"red":        func(ui *UIConfig) *color.Color { return &ui.Colors.Red },
+	"blue":       func(ui *UIConfig) *color.Color { return &ui.Colors.Blue },
+	"yellow":     func(ui *UIConfig) *color.Color { return &ui.Colors.Yellow },
+	"gray":       func(ui *UIConfig) *color.Color { return &ui.Colors.Gray },
+	"orange":     func(ui *UIConfig) *color.Color { return &ui.Colors.Orange },
+	"team1":      func(ui *UIConfig) *color.Color { return &ui.Colors.Team1 },
+	"team2":      func(ui *UIConfig) *color.Color { return &ui.Colors.Team2 },
+	"leader":     func(ui *UIConfig) *color.Color { return &ui.Colors.Leader },
+	"broken":     func(ui *UIConfig) *color.Color { return &ui.Colors.Broken },
+	"hp":         func(ui *UIConfig) *color.Color { return &ui.Colors.HP },
+	"hp_low":     func(ui *UIConfig) *color.Color { return &ui.Colors.HPLow },
+	"panel":      func(ui *UIConfig) *color.Color { return &ui.Colors.Panel },
+	"tooltip":    func(ui *UIConfig) *color.Color { return &ui.Colors.Tooltip },
+	"background": func(ui *UIConfig) *color.Color { return &ui.Colors.Background },
+}
+
+// validate はテーマの内容を検査し、読み取れない値があればエラーを返します。
+func (t *Theme) validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("theme has no name")
+	}
+	for key, value := range t.Colors {
+		if _, ok := themeColorKeys[key]; !ok {
+			return fmt.Errorf("theme %s: unknown color %q", t.Name, key)
+		}
+		if _, err := parseHexColor(value); err != nil {
+			return fmt.Errorf("theme %s: color %s: %w", t.Name, key, err)
+		}
+	}
+	if t.PanelAlpha != nil && (*t.PanelAlpha < 0 || *t.PanelAlpha > 255) {
+		return fmt.Errorf("theme %s: panel_alpha must be between 0 and 255, got %d", t.Name, *t.PanelAlpha)
+	}
+	if t.BorderWidth != nil && *t.BorderWidth < 0 {
+		return fmt.Errorf("theme %s: border_width must not be negative, got %g", t.Name, *t.BorderWidth)
+	}
+	if t.FontSize != nil && (*t.FontSize < 6 || *t.FontSize > 32) {
+		return fmt.Errorf("theme %s: font_size must be between 6 and 32, got %g", t.Name, *t.FontSize)
+	}
+	return nil
+}
+
+// parseHexColor は "#RRGGBB" または "#RRGGBBAA" 形式の色を読み取ります。
+func parseHexColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) != 6 && len(hex) != 8 {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q (want #RRGGBB or #RRGGBBAA)", s)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q (want #RRGGBB or #RRGGBBAA)", s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// ThemeSet は選択可能なテーマの一覧と、テーマを当てる前の配色を保持します。
+type ThemeSet struct {
+	base   UIConfig // テーマ未適用の配色とスタイル
+	themes map[string]*Theme
+}
+
+// NewThemeSet はuiの現在の配色をデフォルトとしてテーマの一覧を作ります。
+func NewThemeSet(ui UIConfig) *ThemeSet {
+	return &ThemeSet{base: ui, themes: make(map[string]*Theme)}
+}
+
+// SetBase はテーマを当てる前の配色とスタイルをuiのものに置き換えます。設定ファイルを読み直したときに使います。
+func (ts *ThemeSet) SetBase(ui UIConfig) {
+	ts.base = ui
+}
+
+// Add はテーマを追加します。同じ名前のテーマがあれば置き換えます。
+func (ts *ThemeSet) Add(t *Theme) error {
+	if err := t.validate(); err != nil {
+		return err
+	}
+	if t.Name == defaultThemeName {
+		return fmt.Errorf("theme name %q is reserved", defaultThemeName)
+	}
+	ts.themes[t.Name] = t
+	return nil
+}
+
+// LoadDir はfsysのdir直下にある*.jsonをテーマとして読み込みます。
+// 読み込めないファイルはログに出して飛ばします。dirがなければ何もしません。
+func (ts *ThemeSet) LoadDir(fsys fs.FS, dir string) {
+	matches, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return
+	}
+	for _, file := range matches {
+		if err := ts.loadFile(fsys, file); err != nil {
+			log.Printf("Skipping theme %s: %v", file, err)
+		}
+	}
+}
+
+func (ts *ThemeSet) loadFile(fsys fs.FS, file string) error {
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return err
+	}
+	var theme Theme
+	if err := json.Unmarshal(data, &theme); err != nil {
+		return fmt.Errorf("failed to parse: %w", err)
+	}
+	return ts.Add(&theme)
+}
+
+// Names は選択可能なテーマの名前を、defaultを先頭にして返します。
+func (ts *ThemeSet) Names() []string {
+	names := make([]string, 0, len(ts.themes))
+	for name := range ts.themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{defaultThemeName}, names...)
+}
+
+// Apply は名前で指定したテーマをuiに適用します。フォントサイズが変わった場合はフォントも作り直します。
+func (ts *ThemeSet) Apply(ui *UIConfig, name string) error {
+	theme, ok := ts.themes[name]
+	if !ok && name != defaultThemeName && name != "" {
+		return fmt.Errorf("unknown theme %q", name)
+	}
+	ui.Colors = ts.base.Colors
+	ui.Style = ts.base.Style
+	if theme != nil {
+		for key, value := range theme.Colors {
+			c, _ := parseHexColor(value) // validateで検査済み
+			*themeColorKeys[key](ui) = c
+		}
+		if theme.PanelAlpha != nil {
+			ui.Style.PanelAlpha = uint8(*theme.PanelAlpha)
+		}
+		if theme.BorderWidth != nil {
+			ui.Style.BorderWidth = *theme.BorderWidth
+		}
+		if theme.FontSize != nil {
+			ui.Style.FontSize = *theme.FontSize
+		}
+	}
+	ui.InfoPanel.TextLineHeight = float32(ui.Style.FontSize * 1.2)
+	if Fonts != nil {
+		Fonts.SetBaseSize(ui.Style.FontSize)
+	}
+	return nil
+}
+
+// NextThemeName は一覧の中でcurrentの次のテーマ名を返します。空のcurrentはdefaultとして扱います。
+func (ts *ThemeSet) NextThemeName(current string) string {
+	if current == "" {
+		current = defaultThemeName
+	}
+	names := ts.Names()
+	for i, name := range names {
+		if name == current {
+			return names[(i+1)%len(names)]
+		}
+	}
+	return names[0]
+}
+
+// LoadThemes は組み込みのテーマ（アセットのthemesディレクトリ）と、ユーザー設定ディレクトリのテーマを読み込みます。
+func LoadThemes(ui UIConfig, assetFS fs.FS) *ThemeSet {
+	themes := NewThemeSet(ui)
+	if assetFS != nil {
+		themes.LoadDir(assetFS, "themes")
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		themes.LoadDir(os.DirFS(filepath.Join(dir, "medarot-ebiten")), "themes")
+	}
+	return themes
+}
+
+// panelColor はメッセージパネルの背景色を、テーマの不透明度を反映して返します。
+func panelColor(ui *UIConfig) color.Color {
+	r, g, b, _ := ui.Colors.Panel.RGBA()
+	return color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: ui.Style.PanelAlpha}
+}
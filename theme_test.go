@@ -0,0 +1,78 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"testing"
+)
+
+func TestNextThemeNameCyclesFromDefault(t *testing.T) {
+	themes := NewThemeSet(LoadConfig().UI)
+	themes.LoadDir(os.DirFS("assets"), "themes")
+
+	// 設定に保存されたテーマが空ならdefaultの次へ進む
+	if got := themes.NextThemeName(""); got != "deuteranopia" {
+		t.Errorf("NextThemeName(\"\") = %q, want deuteranopia", got)
+	}
+	if got := themes.NextThemeName("protanopia"); got != defaultThemeName {
+		t.Errorf("NextThemeName(protanopia) = %q, want %s", got, defaultThemeName)
+	}
+	if got := themes.NextThemeName("removed"); got != defaultThemeName {
+		t.Errorf("NextThemeName(removed) = %q, want %s", got, defaultThemeName)
+	}
+}
+
+func TestThemeApplyKeepsTooltipBackground(t *testing.T) {
+	ui := LoadConfig().UI
+	themes := NewThemeSet(ui)
+	themes.LoadDir(os.DirFS("assets"), "themes")
+
+	want := color.NRGBA{R: 0x10, G: 0x14, B: 0x1c, A: 235}
+	if err := themes.Apply(&ui, "deuteranopia"); err != nil {
+		t.Fatal(err)
+	}
+	if ui.Colors.Tooltip != want {
+		t.Errorf("deuteranopia tooltip = %v, want the default %v", ui.Colors.Tooltip, want)
+	}
+	if err := themes.Apply(&ui, "high-contrast"); err != nil {
+		t.Fatal(err)
+	}
+	if ui.Colors.Tooltip != (color.NRGBA{A: 255}) {
+		t.Errorf("high-contrast tooltip = %v, want opaque black", ui.Colors.Tooltip)
+	}
+	if err := themes.Apply(&ui, "missing"); err == nil {
+		t.Error("Apply accepted an unknown theme")
+	}
+}
+
+func TestApplyConfigReappliesThemeOnNewColors(t *testing.T) {
+	config := LoadConfig()
+	themes := NewThemeSet(config.UI)
+	themes.LoadDir(os.DirFS("assets"), "themes")
+	configComp := &ConfigComponent{GameConfig: &config, Settings: &Settings{Theme: "deuteranopia"}, Themes: themes}
+	if err := themes.Apply(&config.UI, "deuteranopia"); err != nil {
+		t.Fatal(err)
+	}
+	themed := config.UI.Colors
+
+	// テーマが変えない色と、テーマが上書きする色の両方を書き換えて読み直す
+	newConfig := LoadConfig()
+	newConfig.UI.Colors.Tooltip = color.NRGBA{R: 1, G: 2, B: 3, A: 255}
+	newConfig.UI.Colors.Team1 = color.NRGBA{R: 4, G: 5, B: 6, A: 255}
+	newConfig.UI.Style.BorderWidth = 7
+	applyConfig(configComp, &newConfig)
+
+	if config.UI.Colors.Tooltip != newConfig.UI.Colors.Tooltip || config.UI.Style.BorderWidth != 7 {
+		t.Errorf("tooltip %v, border %g after reload; want the edited values", config.UI.Colors.Tooltip, config.UI.Style.BorderWidth)
+	}
+	if config.UI.Colors.Team1 != themed.Team1 {
+		t.Errorf("team1 = %v after reload, want the theme's %v", config.UI.Colors.Team1, themed.Team1)
+	}
+	// defaultに戻すと新しい設定の色になる
+	if err := themes.Apply(&config.UI, defaultThemeName); err != nil {
+		t.Fatal(err)
+	}
+	if config.UI.Colors.Team1 != newConfig.UI.Colors.Team1 {
+		t.Errorf("default team1 = %v, want the reloaded %v", config.UI.Colors.Team1, newConfig.UI.Colors.Team1)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/yohamta/donburi"
+	"github.com/yohamta/donburi/ecs"
+)
+
+// categoryMessageKeys は行動の大区分の表示名のカタログキーです。
+var categoryMessageKeys = map[ActionCategory]string{
+	CategoryShoot: "category.shoot",
+	CategoryFight: "category.fight",
+	CategoryNone:  "category.none",
+}
+
+// traitMessageKeys は行動の特性の表示名のカタログキーです。
+var traitMessageKeys = map[ActionTrait]string{
+	TraitNormal:  "trait.normal",
+	TraitAim:     "trait.aim",
+	TraitStrike:  "trait.strike",
+	TraitBerserk: "trait.berserk",
+	TraitNone:    "trait.none",
+}
+
+// displayNameOr はマップにカタログキーがあればその文言を、なければ元の値を返します。
+func displayNameOr[K ~string](keys map[K]string, key K) string {
+	if messageKey, ok := keys[key]; ok {
+		return T(messageKey)
+	}
+	return string(key)
+}
+
+// partTooltipLines はパーツの全ステータスをツールチップ用の行に整形します。
+func partTooltipLines(part *Part) []string {
+	lines := []string{
+		fmt.Sprintf("%s [%s]", part.DisplayName(), part.ID),
+		T("tooltip.part_kind", "type", part.Type, "category", displayNameOr(categoryMessageKeys, part.Category), "trait", displayNameOr(traitMessageKeys, part.Trait)),
+		T("tooltip.weapon", "weapon", part.WeaponTypeText.Or(part.WeaponType), "armor", part.Armor, "max", part.MaxArmor),
+		T("tooltip.power", "power", part.Power, "charge", part.Charge, "cooldown", part.Cooldown),
+		T("tooltip.defense", "defense", part.Defense, "accuracy", part.Accuracy),
+		T("tooltip.mobility", "mobility", part.Mobility, "propulsion", part.Propulsion),
+	}
+	if part.IsBroken {
+		lines = append(lines, T("tooltip.broken"))
+	}
+	return lines
+}
+
+// medarotTooltipLines はメダロットとメダルの情報をツールチップ用の行に整形します。
+func medarotTooltipLines(identity *IdentityComponent, medal *Medal) []string {
+	lines := []string{identity.Name}
+	if identity.IsLeader {
+		lines[0] = T("tooltip.leader", "name", identity.Name)
+	}
+	if medal == nil {
+		return lines
+	}
+	return append(lines,
+		T("tooltip.medal", "medal", medal.DisplayName(), "attribute", medal.AttributeText.Or(medal.Attribute)),
+		T("tooltip.medaforce", "medaforce", medal.MedaforceText.Or(medal.Medaforce), "personality", medal.PersonalityText.Or(medal.Personality)),
+		T("tooltip.skills", "shoot", medal.SkillShoot, "fight", medal.SkillFight, "scan", medal.SkillScan, "support", medal.SkillSupport),
+	)
+}
+
+// hoverTooltipLines はカーソル位置にあるUI要素のツールチップの内容を返します。
+// 行動選択中はボタン、それ以外ではメダロットのアイコンと情報パネルのパーツ行が対象です。
+func hoverTooltipLines(ecs *ecs.ECS, medarotQuery *donburi.Query, gs *GameStateComponent, pasComp *PlayerActionSelectComponent, config *Config, cursor image.Point) []string {
+	if gs.CurrentState == StatePlayerActionSelect {
+		if len(pasComp.ActionQueue) == 0 || !ecs.World.Valid(pasComp.ActionQueue[0]) {
+			return nil
+		}
+		parts := PartsComponentType.Get(ecs.World.Entry(pasComp.ActionQueue[0]))
+		for i, slotKey := range pasComp.AvailableActions {
+			if cursor.In(actionButtonRect(&config.UI, i)) {
+				if part := parts.Parts[slotKey]; part != nil {
+					return partTooltipLines(part)
+				}
+			}
+		}
+		return nil
+	}
+
+	var lines []string
+	radius := float64(config.UI.Battlefield.IconRadius)
+	medarotQuery.Each(ecs.World, func(entry *donburi.Entry) {
+		if lines != nil {
+			return
+		}
+		identity := IdentityComponentType.Get(entry)
+		render := RenderComponentType.Get(entry)
+
+		x, y := medarotIconPosition(config, identity, StatusComponentType.Get(entry), render)
+		dx, dy := float64(cursor.X)-float64(x), float64(cursor.Y)-float64(y)
+		if dx*dx+dy*dy <= radius*radius {
+			var medal *Medal
+			if entry.HasComponent(CMedal) {
+				medal = CMedal.Get(entry).Medal
+			}
+			lines = medarotTooltipLines(identity, medal)
+			return
+		}
+
+		panelRect := infoPanelRect(config, identity, render)
+		if !cursor.In(panelRect) {
+			return
+		}
+		parts := PartsComponentType.Get(entry)
+		for i, slotKey := range infoPanelSlots {
+			if part := parts.Parts[slotKey]; part != nil && cursor.In(partLineRect(config, panelRect, i)) {
+				lines = partTooltipLines(part)
+				return
+			}
+		}
+	})
+	return lines
+}
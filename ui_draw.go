@@ -4,66 +4,233 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/text"
 	"github.com/hajimehoshi/ebiten/v2/vector"
-	"golang.org/x/image/font"
 )
 
+// deviceScale は論理座標1あたりの描画先のピクセル数です（HiDPIの画面では2など）。LayoutFで更新します。
+// UIの配置とカーソル座標は論理座標のまま扱い、描画するときにこの倍率で拡大します。
+var deviceScale = 1.0
+
+// setDeviceScale は描画の倍率を設定し、フォントもその倍率の解像度で作り直させます。
+func setDeviceScale(scale float64) {
+	if scale <= 0 {
+		scale = 1
+	}
+	deviceScale = scale
+	if Fonts != nil {
+		Fonts.SetScale(scale)
+	}
+}
+
+// cursorPosition はカーソルの位置を論理座標で返します。
+func cursorPosition() image.Point {
+	x, y := ebiten.CursorPosition()
+	return image.Pt(int(float64(x)/deviceScale), int(float64(y)/deviceScale))
+}
+
+// 以下はvectorパッケージの関数を論理座標で呼ぶためのものです。座標と線の太さをdeviceScale倍して描きます。
+
+func fillRect(dst *ebiten.Image, x, y, width, height float32, clr color.Color, antialias bool) {
+	s := float32(deviceScale)
+	vector.DrawFilledRect(dst, x*s, y*s, width*s, height*s, clr, antialias)
+}
+
+func strokeRect(dst *ebiten.Image, x, y, width, height, strokeWidth float32, clr color.Color, antialias bool) {
+	s := float32(deviceScale)
+	vector.StrokeRect(dst, x*s, y*s, width*s, height*s, strokeWidth*s, clr, antialias)
+}
+
+func strokeLine(dst *ebiten.Image, x0, y0, x1, y1, strokeWidth float32, clr color.Color, antialias bool) {
+	s := float32(deviceScale)
+	vector.StrokeLine(dst, x0*s, y0*s, x1*s, y1*s, strokeWidth*s, clr, antialias)
+}
+
+func fillCircle(dst *ebiten.Image, cx, cy, r float32, clr color.Color, antialias bool) {
+	s := float32(deviceScale)
+	vector.DrawFilledCircle(dst, cx*s, cy*s, r*s, clr, antialias)
+}
+
+func strokeCircle(dst *ebiten.Image, cx, cy, r, strokeWidth float32, clr color.Color, antialias bool) {
+	s := float32(deviceScale)
+	vector.StrokeCircle(dst, cx*s, cy*s, r*s, strokeWidth*s, clr, antialias)
+}
+
+// medarotIconPosition は、状態とゲージから求めたメダロットのアイコンの中心座標を返します。
+func medarotIconPosition(config *Config, identity *IdentityComponent, status *StatusComponent, render *RenderComponent) (float32, float32) {
+	bf := config.UI.Battlefield
+	baseYPos := bf.MedarotVerticalSpacing * float32(render.DrawIndex+1)
+	progress := status.Gauge / 100.0
+	homeX, execX := bf.Team1HomeX, bf.Team1ExecutionLineX
+	if identity.Team == Team2 {
+		homeX, execX = bf.Team2HomeX, bf.Team2ExecutionLineX
+	}
+
+	var currentX float32
+	switch status.State {
+	case StateActionCharging:
+		currentX = homeX + float32(progress)*(execX-homeX)
+	case StateReadyToExecuteAction:
+		currentX = execX
+	case StateActionCooldown:
+		currentX = execX - float32(progress)*(execX-homeX)
+	default:
+		currentX = homeX
+	}
+	return currentX, baseYPos
+}
+
+// infoPanelRect は、メダロットの情報パネルの矩形を返します。
+// 画面の左半分にチーム1、右半分にチーム2のパネルを、InfoPanel.Columns列で並べます。
+func infoPanelRect(config *Config, identity *IdentityComponent, render *RenderComponent) image.Rectangle {
+	ip := config.UI.InfoPanel
+	columns := ip.Columns
+	if columns < 1 {
+		columns = 1
+	}
+	column, row := render.DrawIndex%columns, render.DrawIndex/columns
+	if identity.Team == Team2 {
+		column += columns
+	}
+	panelX := ip.Padding + float32(column)*(ip.BlockWidth+ip.Padding)
+	panelY := ip.StartY + ip.Padding + float32(row)*(ip.BlockHeight+ip.Padding)
+	return image.Rect(int(panelX), int(panelY), int(panelX+ip.BlockWidth), int(panelY+ip.BlockHeight))
+}
+
+// infoPanelSlots は情報パネルにパーツを並べる順番です。
+var infoPanelSlots = []PartSlotKey{PartSlotHead, PartSlotRightArm, PartSlotLeftArm, PartSlotLegs}
+
+// partLineBaselineY は、情報パネル内のi番目のパーツ行のベースラインのY座標を返します。
+func partLineBaselineY(config *Config, panelY float32, index int) float32 {
+	ip := config.UI.InfoPanel
+	return panelY + ip.TextLineHeight*2 + float32(index)*(ip.TextLineHeight+4)
+}
+
+// partLineRect は、情報パネル内のi番目のパーツ行の矩形を返します。ツールチップの判定に使います。
+func partLineRect(config *Config, panelRect image.Rectangle, index int) image.Rectangle {
+	baseline := int(partLineBaselineY(config, float32(panelRect.Min.Y), index))
+	top := baseline - int(config.UI.InfoPanel.TextLineHeight)
+	return image.Rect(panelRect.Min.X, top, panelRect.Max.X, baseline+4)
+}
+
+// actionModalRect は行動選択モーダルのウィンドウの矩形を返します。
+func actionModalRect(ui *UIConfig) image.Rectangle {
+	boxW, boxH := int(ui.ActionModal.ButtonWidth)+40, 200
+	boxX := (ui.Screen.Width - boxW) / 2
+	boxY := (ui.Screen.Height - boxH) / 2
+	return image.Rect(boxX, boxY, boxX+boxW, boxY+boxH)
+}
+
+// pauseMenuItemRect は、count個の項目を持つポーズメニューのi番目の項目の矩形を返します。
+func pauseMenuItemRect(ui *UIConfig, index, count int) image.Rectangle {
+	const itemW, itemH, spacing = 240, 26, 4
+	totalH := count*(itemH+spacing) - spacing
+	x := (ui.Screen.Width - itemW) / 2
+	y := (ui.Screen.Height-totalH)/2 + index*(itemH+spacing)
+	return image.Rect(x, y, x+itemW, y+itemH)
+}
+
+// actionButtonRect は行動選択モーダルのi番目のボタンの矩形を返します。
+// 入力判定と描画の両方がこの関数を使い、座標計算を一か所にまとめます。
+func actionButtonRect(ui *UIConfig, index int) image.Rectangle {
+	btnW, btnH, btnS := ui.ActionModal.ButtonWidth, ui.ActionModal.ButtonHeight, ui.ActionModal.ButtonSpacing
+	btnX := ui.Screen.Width/2 - int(btnW/2)
+	btnY := ui.Screen.Height/2 - 50 + (int(btnH)+int(btnS))*index
+	return image.Rect(btnX, btnY, btnX+int(btnW), btnY+int(btnH))
+}
+
 // DrawWindow は、指定された位置とサイズで背景と枠線を持つウィンドウを描画します。
-func DrawWindow(screen *ebiten.Image, rect image.Rectangle, bgColor, borderColor color.Color) {
-	vector.DrawFilledRect(screen, float32(rect.Min.X), float32(rect.Min.Y), float32(rect.Dx()), float32(rect.Dy()), bgColor, true)
-	vector.StrokeRect(screen, float32(rect.Min.X), float32(rect.Min.Y), float32(rect.Dx()), float32(rect.Dy()), 2, borderColor, false)
+func DrawWindow(screen *ebiten.Image, rect image.Rectangle, bgColor, borderColor color.Color, borderWidth float32) {
+	fillRect(screen, float32(rect.Min.X), float32(rect.Min.Y), float32(rect.Dx()), float32(rect.Dy()), bgColor, true)
+	if borderWidth > 0 {
+		strokeRect(screen, float32(rect.Min.X), float32(rect.Min.Y), float32(rect.Dx()), float32(rect.Dy()), borderWidth, borderColor, false)
+	}
 }
 
 // DrawButton は、テキスト付きのボタンを描画します。テキストはボタンの中央に配置されます。
-func DrawButton(screen *ebiten.Image, rect image.Rectangle, label string, face font.Face, bgColor, textColor, borderColor color.Color) {
-	vector.DrawFilledRect(screen, float32(rect.Min.X), float32(rect.Min.Y), float32(rect.Dx()), float32(rect.Dy()), bgColor, true)
-	vector.StrokeRect(screen, float32(rect.Min.X), float32(rect.Min.Y), float32(rect.Dx()), float32(rect.Dy()), 1, borderColor, true)
+func DrawButton(screen *ebiten.Image, rect image.Rectangle, label string, style FontStyle, bgColor, textColor, borderColor color.Color) {
+	fillRect(screen, float32(rect.Min.X), float32(rect.Min.Y), float32(rect.Dx()), float32(rect.Dy()), bgColor, true)
+	strokeRect(screen, float32(rect.Min.X), float32(rect.Min.Y), float32(rect.Dx()), float32(rect.Dy()), 1, borderColor, true)
+
+	if Fonts != nil && label != "" {
+		// DrawTextはベースラインを基準にするため、縦の中央揃えには高さの計算が必要です。
+		ascent := Fonts.Ascent(style)
+		textY := float64(rect.Min.Y) + (float64(rect.Dy())+ascent)/2
+		DrawTextCentered(screen, label, style, float64(rect.Min.X)+float64(rect.Dx())/2, textY, textColor)
+	}
+}
+
+// DrawFocusFrame は、キーボード・ゲームパッドでフォーカスされている要素を示す枠を描画します。
+func DrawFocusFrame(screen *ebiten.Image, rect image.Rectangle, frameColor color.Color) {
+	strokeRect(screen, float32(rect.Min.X)-2, float32(rect.Min.Y)-2, float32(rect.Dx())+4, float32(rect.Dy())+4, 2, frameColor, true)
+}
+
+// DrawTooltip は、カーソル付近に複数行のツールチップを描画します。画面外にはみ出さないよう位置を調整します。
+func DrawTooltip(screen *ebiten.Image, cursorX, cursorY int, lines []string, uiConfig *UIConfig) {
+	if Fonts == nil || len(lines) == 0 {
+		return
+	}
+	const padding = 6
+	const style = FontSmall
+	lineHeight := int(math.Ceil(Fonts.LineHeight(style)))
+	width := 0
+	for _, line := range lines {
+		if w := int(math.Ceil(Fonts.Measure(style, line))); w > width {
+			width = w
+		}
+	}
+	width += padding * 2
+	height := lineHeight*len(lines) + padding*2
+
+	x, y := cursorX+16, cursorY+16
+	if x+width > uiConfig.Screen.Width {
+		x = cursorX - width - 8
+	}
+	if y+height > uiConfig.Screen.Height {
+		y = uiConfig.Screen.Height - height
+	}
+	if x < 0 {
+		x = 0
+	}
+	if y < 0 {
+		y = 0
+	}
 
-	if face != nil && label != "" {
-		bounds, _ := font.BoundString(face, label)
-		textWidth := (bounds.Max.X - bounds.Min.X).Ceil()
-		// Ebitenのテキスト描画はベースラインを基準にするため、中央揃えには高さの計算が必要です。
-		ascent := face.Metrics().Ascent.Ceil()
-		textX := rect.Min.X + (rect.Dx()-textWidth)/2
-		textY := rect.Min.Y + (rect.Dy()+ascent)/2
-		text.Draw(screen, label, face, textX, textY, textColor)
+	DrawWindow(screen, image.Rect(x, y, x+width, y+height), uiConfig.Colors.Tooltip, uiConfig.Colors.Gray, uiConfig.Style.BorderWidth)
+	ascent := Fonts.Ascent(style)
+	for i, line := range lines {
+		DrawText(screen, line, style, float64(x+padding), float64(y+padding+lineHeight*i)+ascent, uiConfig.Colors.White)
 	}
 }
 
 // DrawMessagePanel は、メッセージとオプションのプロンプトテキストを持つパネルを描画します。
-func DrawMessagePanel(screen *ebiten.Image, rect image.Rectangle, message, prompt string, face font.Face, uiConfig *UIConfig) {
-	DrawWindow(screen, rect, color.NRGBA{0, 0, 0, 200}, uiConfig.Colors.White)
+// メッセージはmessageStyle、プロンプトは補足用の小さい文字で描きます。
+func DrawMessagePanel(screen *ebiten.Image, rect image.Rectangle, message, prompt string, messageStyle FontStyle, uiConfig *UIConfig) {
+	DrawWindow(screen, rect, panelColor(uiConfig), uiConfig.Colors.White, uiConfig.Style.BorderWidth)
 
-	if face == nil {
+	if Fonts == nil {
 		return
 	}
 	// メインメッセージ (中央揃え)
 	if message != "" {
-		bounds, _ := font.BoundString(face, message)
-		msgW := (bounds.Max.X - bounds.Min.X).Ceil()
-		ascent := face.Metrics().Ascent.Ceil()
-		msgX := rect.Min.X + (rect.Dx()-msgW)/2
-		msgY := rect.Min.Y + (rect.Dy()+ascent)/2
-		text.Draw(screen, message, face, msgX, msgY, uiConfig.Colors.White)
+		msgY := float64(rect.Min.Y) + (float64(rect.Dy())+Fonts.Ascent(messageStyle))/2
+		DrawTextCentered(screen, message, messageStyle, float64(rect.Min.X)+float64(rect.Dx())/2, msgY, uiConfig.Colors.White)
 	}
 	// プロンプトメッセージ (右下)
 	if prompt != "" {
-		bounds, _ := font.BoundString(face, prompt)
-		promptW := (bounds.Max.X - bounds.Min.X).Ceil()
-		ascent := face.Metrics().Ascent.Ceil()
-		promptX := rect.Max.X - promptW - 20
-		promptY := rect.Max.Y - 20 - ascent + face.Metrics().Height.Ceil()
-		text.Draw(screen, prompt, face, promptX, promptY, uiConfig.Colors.White)
+		promptX := float64(rect.Max.X) - Fonts.Measure(FontSmall, prompt) - 20
+		promptY := float64(rect.Max.Y) - 20 + Fonts.LineHeight(FontSmall) - Fonts.Ascent(FontSmall)
+		DrawText(screen, prompt, FontSmall, promptX, promptY, uiConfig.Colors.White)
 	}
 }
 
 // drawMedarotInfoPanel は個々のメダロットの情報パネルを描画します。
 // render_system.goから移動し、このファイルに集約しました。
-func drawMedarotInfoPanel(screen *ebiten.Image, identity *IdentityComponent, status *StatusComponent, parts *PartsComponent, startX, startY float32, config *Config, debugMode bool) {
-	if MplusFont == nil {
+// armorDisplayがあれば、減った装甲の分を遅れて縮むバーとして重ねて描画します。
+func drawMedarotInfoPanel(screen *ebiten.Image, identity *IdentityComponent, status *StatusComponent, parts *PartsComponent, armorDisplay *ArmorDisplayComponent, startX, startY float32, config *Config, debugMode bool) {
+	if Fonts == nil {
 		return
 	}
 
@@ -72,24 +239,21 @@ func drawMedarotInfoPanel(screen *ebiten.Image, identity *IdentityComponent, sta
 	if status.IsBroken() {
 		nameColor = config.UI.Colors.Broken
 	}
-	text.Draw(screen, identity.Name, MplusFont, int(startX), int(startY)+int(config.UI.InfoPanel.TextLineHeight), nameColor)
+	DrawText(screen, identity.Name, FontBody, float64(startX), float64(startY+config.UI.InfoPanel.TextLineHeight), nameColor)
 	if debugMode {
 		stateStr := fmt.Sprintf("St:%s(G:%.0f)", status.State, status.Gauge)
-		text.Draw(screen, stateStr, MplusFont, int(startX+70), int(startY)+int(config.UI.InfoPanel.TextLineHeight), config.UI.Colors.Yellow)
+		DrawText(screen, stateStr, FontSmall, float64(startX+70), float64(startY+config.UI.InfoPanel.TextLineHeight), config.UI.Colors.Yellow)
 	}
 
-	partSlots := []PartSlotKey{PartSlotHead, PartSlotRightArm, PartSlotLeftArm, PartSlotLegs}
-	partSlotDisplayNames := map[PartSlotKey]string{PartSlotHead: "頭", PartSlotRightArm: "右", PartSlotLeftArm: "左", PartSlotLegs: "脚"}
-	currentInfoY := startY + config.UI.InfoPanel.TextLineHeight*2
-
 	// 各パーツの情報
-	for _, slotKey := range partSlots {
+	for i, slotKey := range infoPanelSlots {
 		part, exists := parts.Parts[slotKey]
 		if !exists || part == nil {
 			continue
 		}
+		currentInfoY := partLineBaselineY(config, startY, i)
 
-		hpText := fmt.Sprintf("%s:%d/%d", partSlotDisplayNames[slotKey], part.Armor, part.MaxArmor)
+		hpText := fmt.Sprintf("%s:%d/%d", partSlotLabel(slotKey, true), part.Armor, part.MaxArmor)
 		textColor := config.UI.Colors.White
 		if part.IsBroken {
 			textColor = config.UI.Colors.Broken
@@ -100,23 +264,27 @@ func drawMedarotInfoPanel(screen *ebiten.Image, identity *IdentityComponent, sta
 			hpPercentage := float64(part.Armor) / float64(part.MaxArmor)
 			gaugeX := startX + config.UI.InfoPanel.PartHPGaugeOffsetX
 			gaugeY := currentInfoY - config.UI.InfoPanel.TextLineHeight/2 - config.UI.InfoPanel.PartHPGaugeHeight/2
-			vector.DrawFilledRect(screen, gaugeX, gaugeY, config.UI.InfoPanel.PartHPGaugeWidth, config.UI.InfoPanel.PartHPGaugeHeight, color.NRGBA{50, 50, 50, 255}, true)
+			fillRect(screen, gaugeX, gaugeY, config.UI.InfoPanel.PartHPGaugeWidth, config.UI.InfoPanel.PartHPGaugeHeight, color.NRGBA{50, 50, 50, 255}, true)
+			if armorDisplay != nil {
+				if displayed, ok := armorDisplay.Values[slotKey]; ok && displayed > float64(part.Armor) {
+					drainPercentage := displayed / float64(part.MaxArmor)
+					fillRect(screen, gaugeX, gaugeY, float32(float64(config.UI.InfoPanel.PartHPGaugeWidth)*drainPercentage), config.UI.InfoPanel.PartHPGaugeHeight, config.UI.Colors.Orange, true)
+				}
+			}
 
 			barFillColor := config.UI.Colors.HP
 			if part.IsBroken {
 				barFillColor = config.UI.Colors.Broken
 			} else if hpPercentage < 0.3 {
-				barFillColor = config.UI.Colors.Red
+				barFillColor = config.UI.Colors.HPLow
 			}
-			vector.DrawFilledRect(screen, gaugeX, gaugeY, float32(float64(config.UI.InfoPanel.PartHPGaugeWidth)*hpPercentage), config.UI.InfoPanel.PartHPGaugeHeight, barFillColor, true)
+			fillRect(screen, gaugeX, gaugeY, float32(float64(config.UI.InfoPanel.PartHPGaugeWidth)*hpPercentage), config.UI.InfoPanel.PartHPGaugeHeight, barFillColor, true)
 		}
 
-		text.Draw(screen, hpText, MplusFont, int(startX), int(currentInfoY), textColor)
+		DrawText(screen, hpText, FontBody, float64(startX), float64(currentInfoY), textColor)
 
 		// パーツ名
 		partNameX := startX + config.UI.InfoPanel.PartHPGaugeOffsetX + config.UI.InfoPanel.PartHPGaugeWidth + 5
-		text.Draw(screen, part.PartName, MplusFont, int(partNameX), int(currentInfoY), textColor)
-
-		currentInfoY += config.UI.InfoPanel.TextLineHeight + 4
+		DrawText(screen, part.DisplayName(), FontBody, float64(partNameX), float64(currentInfoY), textColor)
 	}
 }